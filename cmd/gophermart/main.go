@@ -4,14 +4,22 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"log"
+	"time"
 
 	"github.com/korovindenis/go-market/internal/adapters/accrual"
 	"github.com/korovindenis/go-market/internal/adapters/auth"
 	"github.com/korovindenis/go-market/internal/adapters/config"
 	"github.com/korovindenis/go-market/internal/adapters/ctxinfo"
+	"github.com/korovindenis/go-market/internal/adapters/expirer"
+	"github.com/korovindenis/go-market/internal/adapters/hasher"
 	"github.com/korovindenis/go-market/internal/adapters/logger"
+	"github.com/korovindenis/go-market/internal/adapters/notifier"
+	"github.com/korovindenis/go-market/internal/adapters/storage/memory"
 	bd "github.com/korovindenis/go-market/internal/adapters/storage/postgresql"
+	"github.com/korovindenis/go-market/internal/adapters/totp"
+	"github.com/korovindenis/go-market/internal/domain/entity"
 	"github.com/korovindenis/go-market/internal/domain/usecases"
 	"github.com/korovindenis/go-market/internal/port/http/handler"
 	"github.com/korovindenis/go-market/internal/port/http/middleware"
@@ -28,6 +36,71 @@ const (
 	ExitWithError
 )
 
+// methods used by usecases, accrual and expirer
+type storage interface {
+	UserRegister(ctx context.Context, user entity.User) (int64, error)
+	GetUserCredentials(ctx context.Context, userFromReq entity.User) (entity.User, error)
+	SetUserPassword(ctx context.Context, user entity.User, passwordHash string) error
+	GetUser(ctx context.Context, userFromReq entity.User) (entity.User, error)
+	GetAllOrders(ctx context.Context, user entity.User, filter entity.OrderFilter) ([]entity.Order, error)
+	AddOrder(ctx context.Context, order entity.Order, user entity.User) error
+	GetBalance(ctx context.Context, user entity.User) (entity.Balance, error)
+	GetPendingAccrual(ctx context.Context, user entity.User) (entity.Money, error)
+	WithdrawBalance(ctx context.Context, balance entity.BalanceUpdate, user entity.User) error
+	Withdrawals(ctx context.Context, user entity.User, filter entity.OrderFilter) ([]entity.BalanceUpdate, error)
+	ReverseWithdrawal(ctx context.Context, number string) (entity.BalanceUpdate, error)
+
+	GetTransactions(ctx context.Context, user entity.User, filter entity.TransactionFilter) ([]entity.Transaction, error)
+
+	CreateSession(ctx context.Context, session entity.Session) (int64, error)
+	RotateSession(ctx context.Context, oldHash string, next entity.Session) (entity.Session, error)
+	GetSession(ctx context.Context, sessionID int64) (entity.Session, error)
+	GetSessions(ctx context.Context, user entity.User) ([]entity.Session, error)
+	TouchSession(ctx context.Context, sessionID int64, seenAt time.Time) error
+	RevokeSession(ctx context.Context, user entity.User, sessionID int64) error
+	RevokeOtherSessions(ctx context.Context, user entity.User, keepSessionID int64) error
+
+	GetLoginAttempts(ctx context.Context, key string) (entity.LoginAttempts, error)
+	AddFailedLogin(ctx context.Context, key string, at, forgetBefore time.Time) (entity.LoginAttempts, error)
+	ResetLoginAttempts(ctx context.Context, key string) error
+
+	CreatePasswordReset(ctx context.Context, token entity.PasswordResetToken) error
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string, at time.Time) (entity.User, error)
+
+	GetTwoFactor(ctx context.Context, user entity.User) (entity.TwoFactor, error)
+	SetTwoFactorSecret(ctx context.Context, user entity.User, secret string) error
+	EnableTwoFactor(ctx context.Context, user entity.User, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, user entity.User, step int64) error
+	UseRecoveryCode(ctx context.Context, user entity.User, codeHash string) error
+	CreateLoginChallenge(ctx context.Context, challenge entity.LoginChallenge) error
+	GetLoginChallenge(ctx context.Context, tokenHash string) (entity.LoginChallenge, error)
+	UseLoginChallenge(ctx context.Context, challengeID int64, at time.Time) error
+
+	GetUserDetails(ctx context.Context, user entity.User) (entity.UserDetails, error)
+	SetUserBlocked(ctx context.Context, user entity.User, blocked bool) (entity.User, error)
+	AdjustBalance(ctx context.Context, user entity.User, adjustment entity.BalanceAdjustment) (entity.Balance, error)
+	GetOrder(ctx context.Context, number string) (entity.OrderDetails, error)
+	RequeueOrder(ctx context.Context, number string) error
+
+	GetAuditEvents(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEvent, error)
+	GetAuditChain(ctx context.Context, afterID int64, limit int) ([]entity.AuditEvent, error)
+
+	ReserveIdempotencyKey(ctx context.Context, key entity.IdempotencyKey, expiredBefore time.Time) (entity.IdempotencyKey, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error
+	ReleaseIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error
+
+	GetPointLots(ctx context.Context, user entity.User) ([]entity.PointLot, error)
+	ExpirePoints(ctx context.Context, earnedBefore time.Time) (entity.Money, error)
+
+	ClaimNotProcessedOrders(ctx context.Context, claim entity.OrderClaim) ([]entity.Order, error)
+	SetOrderStatusAndAccrual(ctx context.Context, order entity.Order) error
+}
+
+// notifications used by usecases
+type userNotifier interface {
+	SendPasswordReset(ctx context.Context, user entity.User, token string, expiresAt time.Time) error
+}
+
 func main() {
 	// init config
 	config, err := config.New()
@@ -41,20 +114,32 @@ func main() {
 		log.Fatal(err)
 	}
 
-	// init bd
-	sqlBd, err := bd.Init(config)
+	// init storage
+	storage, err := newStorage(config)
 	if err != nil {
-		logger.Fatal("init bd", zap.Error(err))
+		logger.Fatal("init storage", zap.Error(err))
 	}
 
-	// init storage
-	storage, err := bd.New(sqlBd)
+	// init password hasher
+	hasher, err := hasher.New(config)
 	if err != nil {
-		logger.Fatal("init storage", zap.Error(err))
+		logger.Fatal("init hasher", zap.Error(err))
+	}
+
+	// init notifier
+	notifier, err := newNotifier(config, logger.Logger)
+	if err != nil {
+		logger.Fatal("init notifier", zap.Error(err))
+	}
+
+	// init totp for two-factor auth
+	totp, err := totp.New(config)
+	if err != nil {
+		logger.Fatal("init totp", zap.Error(err))
 	}
 
 	// init usecases
-	usecases, err := usecases.New(config, storage)
+	usecases, err := usecases.New(config, storage, hasher, notifier, totp, logger.Logger)
 	if err != nil {
 		logger.Fatal("init usecases", zap.Error(err))
 	}
@@ -78,7 +163,7 @@ func main() {
 	}
 
 	// init middleware
-	middleware, err := middleware.New(config, auth)
+	middleware, err := middleware.New(config, auth, usecases, usecases)
 	if err != nil {
 		logger.Fatal("init middleware", zap.Error(err))
 	}
@@ -88,13 +173,68 @@ func main() {
 	defer cancel()
 
 	// accrual
-	accrual, err := accrual.New(config, storage)
+	accrual, err := accrual.New(config, storage, logger.Logger)
 	if err != nil {
 		logger.Fatal("init accrual", zap.Error(err))
 	}
-	go accrual.Run(ctx)
+	// queue depth and in-flight orders, served to admins at /api/admin/debug/vars
+	expvar.Publish("accrual", expvar.Func(func() any { return accrual.Stats() }))
+	accrualDone := make(chan struct{})
+	go func() {
+		defer close(accrualDone)
+		accrual.Run(ctx)
+	}()
+
+	// expiration of loyalty points
+	expirer, err := expirer.New(config, storage, logger.Logger)
+	if err != nil {
+		logger.Fatal("init expirer", zap.Error(err))
+	}
+	go expirer.Run(ctx)
 
 	if err := server.Run(ctx, config, handler, middleware); err != nil {
 		logger.Fatal("run web server", zap.Error(err))
 	}
+
+	// let the accrual workers finish the orders they hold
+	cancel()
+	<-accrualDone
+}
+
+// pick the storage adapter from config
+func newStorage(cfg interface {
+	GetStorageDriver() (string, error)
+	GetStorageConnectionString() string
+}) (storage, error) {
+	driver, err := cfg.GetStorageDriver()
+	if err != nil {
+		return nil, err
+	}
+	if driver == config.StorageDriverMemory {
+		return memory.New()
+	}
+
+	// init bd
+	sqlBd, err := bd.Init(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return bd.New(sqlBd)
+}
+
+// pick the notifier from config, the log one is for development
+func newNotifier(cfg interface {
+	GetNotifierDriver() string
+	GetPasswordResetURL() string
+	GetSMTPAddress() string
+	GetSMTPUsername() string
+	GetSMTPPassword() string
+	GetSMTPFrom() string
+}, logger *zap.Logger) (userNotifier, error) {
+	if cfg.GetNotifierDriver() == config.NotifierDriverSMTP {
+		return notifier.NewSMTP(cfg)
+	}
+
+	return notifier.NewLog(cfg, logger)
 }
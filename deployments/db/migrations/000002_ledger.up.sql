@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE TABLE ledger_transactions (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('ACCRUAL', 'WITHDRAWAL', 'REVERSAL', 'ADJUSTMENT')),
+    user_id BIGINT NOT NULL,
+    order_number VARCHAR(50),
+    reason TEXT,
+    created_at TIMESTAMP DEFAULT current_timestamp,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT NOT NULL,
+    account VARCHAR(50) NOT NULL,
+    user_id BIGINT,
+    debit DECIMAL(12, 2) NOT NULL DEFAULT 0 CHECK (debit >= 0),
+    credit DECIMAL(12, 2) NOT NULL DEFAULT 0 CHECK (credit >= 0),
+    FOREIGN KEY (transaction_id) REFERENCES ledger_transactions(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX ledger_transactions_user_id_idx ON ledger_transactions (user_id, id);
+CREATE INDEX ledger_entries_transaction_id_idx ON ledger_entries (transaction_id);
+CREATE INDEX ledger_entries_user_account_idx ON ledger_entries (user_id, account);
+
+-- existing balances become opening adjustments so the ledger explains them
+WITH opening AS (
+    INSERT INTO ledger_transactions (kind, user_id, reason)
+    SELECT 'ADJUSTMENT', user_id, 'opening balance'
+    FROM balances
+    WHERE current <> 0 OR withdrawn <> 0
+    RETURNING id, user_id
+)
+INSERT INTO ledger_entries (transaction_id, account, user_id, debit, credit)
+SELECT o.id, 'loyalty_expense', NULL, b.current + b.withdrawn, 0 FROM opening o JOIN balances b ON b.user_id = o.user_id WHERE b.current + b.withdrawn <> 0
+UNION ALL
+SELECT o.id, 'current', o.user_id, 0, b.current FROM opening o JOIN balances b ON b.user_id = o.user_id WHERE b.current <> 0
+UNION ALL
+SELECT o.id, 'withdrawn', o.user_id, 0, b.withdrawn FROM opening o JOIN balances b ON b.user_id = o.user_id WHERE b.withdrawn <> 0;
+
+-- +goose Down
+DROP TABLE ledger_entries;
+DROP TABLE ledger_transactions;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- keyset pagination of order and withdrawal lists
+CREATE INDEX orders_user_id_idx ON orders (user_id, id DESC);
+
+-- +goose Down
+DROP INDEX orders_user_id_idx;
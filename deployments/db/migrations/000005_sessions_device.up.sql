@@ -0,0 +1,12 @@
+-- +goose Up
+-- device info shown in the list of active sessions
+ALTER TABLE sessions
+    ADD COLUMN ip VARCHAR(45) NOT NULL DEFAULT '',
+    ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+    ADD COLUMN last_seen_at TIMESTAMP DEFAULT current_timestamp;
+
+-- +goose Down
+ALTER TABLE sessions
+    DROP COLUMN ip,
+    DROP COLUMN user_agent,
+    DROP COLUMN last_seen_at;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- failed logins by login and client ip, shared by all replicas
+CREATE TABLE login_attempts (
+    key TEXT PRIMARY KEY,
+    failures INT NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE login_attempts;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+ALTER TABLE users ADD COLUMN blocked_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN blocked_at;
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- results of requests sent with an Idempotency-Key header
+CREATE TABLE idempotency_keys (
+    user_id BIGINT NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    -- zero while the first request is in progress
+    status_code INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, key),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- +goose Down
+DROP TABLE idempotency_keys;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- set once, when a processed withdrawal is cancelled and its sum credited back
+ALTER TABLE orders ADD COLUMN reversed_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE orders DROP COLUMN reversed_at;
@@ -0,0 +1,37 @@
+-- +goose Up
+-- withdrawals used to be processed orders with a sum,
+-- their numbers collided with the accrual orders
+CREATE TABLE withdrawals (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    number VARCHAR(50) NOT NULL UNIQUE,
+    sum DECIMAL(12, 2) NOT NULL CHECK (sum > 0),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('PROCESSED', 'REVERSED')),
+    processed_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    reversed_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- keyset pagination of withdrawal lists
+CREATE INDEX withdrawals_user_id_idx ON withdrawals (user_id, id DESC);
+
+INSERT INTO withdrawals (user_id, number, sum, status, processed_at, reversed_at)
+SELECT user_id, number::TEXT, sum, CASE WHEN reversed_at IS NULL THEN 'PROCESSED' ELSE 'REVERSED' END, COALESCE(uploaded_at, current_timestamp), reversed_at
+FROM orders
+WHERE sum > 0 AND status = 'PROCESSED'
+ORDER BY id;
+
+DELETE FROM orders WHERE sum > 0 AND status = 'PROCESSED';
+ALTER TABLE orders DROP COLUMN reversed_at;
+ALTER TABLE orders DROP COLUMN sum;
+
+-- +goose Down
+ALTER TABLE orders ADD COLUMN sum DECIMAL(10, 2) DEFAULT 0;
+ALTER TABLE orders ADD COLUMN reversed_at TIMESTAMP;
+
+INSERT INTO orders (number, user_id, sum, uploaded_at, status, reversed_at)
+SELECT number::BIGINT, user_id, sum, processed_at, 'PROCESSED', reversed_at
+FROM withdrawals
+ORDER BY id;
+
+DROP TABLE withdrawals;
@@ -0,0 +1,29 @@
+-- +goose Up
+ALTER TABLE ledger_transactions DROP CONSTRAINT ledger_transactions_kind_check;
+ALTER TABLE ledger_transactions ADD CONSTRAINT ledger_transactions_kind_check CHECK (kind IN ('ACCRUAL', 'WITHDRAWAL', 'REVERSAL', 'ADJUSTMENT', 'EXPIRY'));
+
+-- points credited at once, debits of the current account use the oldest lots first,
+-- the expiry date follows from earned_at and the configured lifetime
+CREATE TABLE point_lots (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    order_number VARCHAR(50),
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    remaining DECIMAL(12, 2) NOT NULL CHECK (remaining >= 0 AND remaining <= amount),
+    earned_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX point_lots_user_id_idx ON point_lots (user_id, earned_at, id) WHERE remaining > 0;
+CREATE INDEX point_lots_earned_at_idx ON point_lots (earned_at) WHERE remaining > 0;
+
+-- existing points count as earned now
+INSERT INTO point_lots (user_id, amount, remaining)
+SELECT user_id, current, current FROM balances WHERE current > 0;
+
+-- +goose Down
+DROP TABLE point_lots;
+ALTER TABLE ledger_transactions DROP CONSTRAINT ledger_transactions_kind_check;
+-- keep the history balanced, expiries become adjustments
+UPDATE ledger_transactions SET kind = 'ADJUSTMENT', reason = 'points expired' WHERE kind = 'EXPIRY';
+ALTER TABLE ledger_transactions ADD CONSTRAINT ledger_transactions_kind_check CHECK (kind IN ('ACCRUAL', 'WITHDRAWAL', 'REVERSAL', 'ADJUSTMENT'));
@@ -0,0 +1,6 @@
+-- +goose Up
+-- accrual estimate while the accrual system processes the order, zero once it is final
+ALTER TABLE orders ADD COLUMN pending DECIMAL(12, 2) NOT NULL DEFAULT 0 CHECK (pending >= 0);
+
+-- +goose Down
+ALTER TABLE orders DROP COLUMN pending;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- replica polling the order in the accrual system, others skip it until the lease ends
+ALTER TABLE orders ADD COLUMN claimed_by VARCHAR(255);
+ALTER TABLE orders ADD COLUMN lease_until TIMESTAMP;
+
+CREATE INDEX orders_not_processed_idx ON orders (uploaded_at) WHERE status NOT IN ('INVALID', 'PROCESSED');
+
+-- +goose Down
+DROP INDEX orders_not_processed_idx;
+ALTER TABLE orders DROP COLUMN lease_until;
+ALTER TABLE orders DROP COLUMN claimed_by;
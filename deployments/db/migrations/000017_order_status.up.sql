@@ -0,0 +1,9 @@
+-- +goose Up
+-- the statuses of entity.OrderStatus, the accrual system reports REGISTERED before it starts processing
+ALTER TABLE orders DROP CONSTRAINT orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check CHECK (status IN ('NEW', 'REGISTERED', 'PROCESSING', 'INVALID', 'PROCESSED'));
+
+-- +goose Down
+UPDATE orders SET status = 'PROCESSING' WHERE status = 'REGISTERED';
+ALTER TABLE orders DROP CONSTRAINT orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check CHECK (status IN ('NEW', 'PROCESSING', 'INVALID', 'PROCESSED'));
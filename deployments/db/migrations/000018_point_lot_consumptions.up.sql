@@ -0,0 +1,15 @@
+-- +goose Up
+-- points a debit took from each lot, a reversal gives them back to the same lots
+CREATE TABLE point_lot_consumptions (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT NOT NULL,
+    lot_id BIGINT NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    FOREIGN KEY (transaction_id) REFERENCES ledger_transactions(id),
+    FOREIGN KEY (lot_id) REFERENCES point_lots(id)
+);
+
+CREATE INDEX point_lot_consumptions_transaction_id_idx ON point_lot_consumptions (transaction_id);
+
+-- +goose Down
+DROP TABLE point_lot_consumptions;
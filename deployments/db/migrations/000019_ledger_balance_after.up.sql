@@ -0,0 +1,22 @@
+-- +goose Up
+-- current balance of the user right after the transaction, the history reads it instead of a running sum
+ALTER TABLE ledger_transactions ADD COLUMN balance_after DECIMAL(12, 2);
+
+WITH feed AS (
+    SELECT t.id, t.user_id,
+        COALESCE(SUM(e.credit - e.debit) FILTER (WHERE e.account = 'current' AND e.user_id = t.user_id), 0) AS amount
+    FROM ledger_transactions t
+    JOIN ledger_entries e ON e.transaction_id = t.id
+    GROUP BY t.id
+), running AS (
+    SELECT id, SUM(amount) OVER (PARTITION BY user_id ORDER BY id) AS balance FROM feed
+)
+UPDATE ledger_transactions t SET balance_after = running.balance
+FROM running
+WHERE running.id = t.id;
+
+UPDATE ledger_transactions SET balance_after = 0 WHERE balance_after IS NULL;
+ALTER TABLE ledger_transactions ALTER COLUMN balance_after SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE ledger_transactions DROP COLUMN balance_after;
@@ -3,6 +3,7 @@ module github.com/korovindenis/go-market
 go 1.20
 
 require (
+	github.com/gin-contrib/pprof v1.4.0
 	github.com/knadh/koanf v1.5.0
 	golang.org/x/crypto v0.14.0
 )
@@ -12,7 +13,6 @@ require (
 	github.com/chenzhuoyu/base64x v0.0.0-20221115062448-fe3a3abad311 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/gabriel-vasile/mimetype v1.4.2 // indirect
-	github.com/gin-contrib/pprof v1.4.0 // indirect
 	github.com/gin-contrib/sse v0.1.0 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
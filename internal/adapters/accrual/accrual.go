@@ -2,21 +2,22 @@ package accrual
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/korovindenis/go-market/internal/domain/entity"
+	"go.uber.org/zap"
 )
 
-// max goroutine
-const maxWorker = 10
-
 // get/set for BD
 type storage interface {
-	GetAllNotProcessedOrders(ctx context.Context) ([]entity.Order, error)
+	ClaimNotProcessedOrders(ctx context.Context, claim entity.OrderClaim) ([]entity.Order, error)
 
 	SetOrderStatusAndAccrual(ctx context.Context, order entity.Order) error
 }
@@ -24,83 +25,242 @@ type storage interface {
 // configuration
 type config interface {
 	GetAccrualAddress() string
+	GetAccrualWorkers() int
+	GetAccrualQueueSize() int
+	GetAccrualPollInterval() time.Duration
+	GetAccrualRateLimit() int
+	GetAccrualLease() time.Duration
 }
 
+// Poller of the accrual system.
+// A dispatcher polls the storage and feeds a fixed pool of workers
 type Accrual struct {
 	storage
 	config
+	logger *zap.Logger
+
+	// replica name on the order leases
+	owner    string
+	client   *resty.Client
+	governor *governor
+	workers  int
+	queue    chan entity.Order
+
+	// numbers of the orders queued or processed, they are not dispatched again
+	mu       sync.Mutex
+	inFlight map[string]struct{}
+	// orders the workers are processing right now
+	processing atomic.Int64
+	// failed claims, requests and updates
+	errors atomic.Int64
+	// replies that would move an order to a status it can not reach
+	illegalTransitions atomic.Int64
 }
 
-// response data
+// sizes of the pool, exported to tune the config
+type Stats struct {
+	Workers    int   `json:"workers"`
+	QueueSize  int   `json:"queue_size"`
+	QueueDepth int   `json:"queue_depth"`
+	InFlight   int64 `json:"in_flight"`
+	Errors     int64 `json:"errors"`
+	// replies not stored because the order can not move to their status
+	IllegalTransitions int64 `json:"illegal_transitions"`
+}
+
+// response data, the accrual of a REGISTERED or PROCESSING order is an estimate
 type accrualRespose struct {
-	Number  string  `json:"order"`
-	Status  string  `json:"status"`
-	Accrual float64 `json:"accrual,omitempty"`
+	Number  string             `json:"order"`
+	Status  entity.OrderStatus `json:"status"`
+	Accrual entity.Money       `json:"accrual,omitempty"`
 }
 
-func New(config config, storage storage) (*Accrual, error) {
+// order update for the storage, estimates are held as pending
+func (r *accrualRespose) order(number string) entity.Order {
+	order := entity.Order{
+		Number: number,
+		Status: r.Status,
+	}
+	if r.Status.IsPending() {
+		order.Pending = r.Accrual
+	} else {
+		order.Accrual = r.Accrual
+	}
+
+	return order
+}
+
+func New(config config, storage storage, logger *zap.Logger) (*Accrual, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Accrual{
-		storage: storage,
-		config:  config,
+		storage:  storage,
+		config:   config,
+		logger:   logger,
+		owner:    fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		client:   resty.New(),
+		governor: newGovernor(config.GetAccrualRateLimit()),
+		workers:  config.GetAccrualWorkers(),
+		queue:    make(chan entity.Order, config.GetAccrualQueueSize()),
+		inFlight: make(map[string]struct{}),
 	}, nil
 }
 
-func (a *Accrual) Run(ctx context.Context) {
-	accrualAddress := a.config.GetAccrualAddress()
-	restClient := resty.New()
+func (a *Accrual) Stats() Stats {
+	return Stats{
+		Workers:    a.workers,
+		QueueSize:  cap(a.queue),
+		QueueDepth: len(a.queue),
+		InFlight:   a.processing.Load(),
+		Errors:     a.errors.Load(),
 
-	notProcessedOrdersCH := make(chan entity.Order, maxWorker)
-	defer close(notProcessedOrdersCH)
+		IllegalTransitions: a.illegalTransitions.Load(),
+	}
+}
+
+// Poll until ctx is done, then wait for the workers.
+// Orders left in the queue are dispatched again on the next start
+func (a *Accrual) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < a.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.worker(ctx)
+		}()
+	}
 
-	updateTicker := time.NewTicker(1 * time.Second)
-	defer updateTicker.Stop()
+	pollTicker := time.NewTicker(a.GetAccrualPollInterval())
+	defer pollTicker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
+			// only the dispatcher sends, so the queue is closed here
+			close(a.queue)
+			wg.Wait()
 			return
-		case <-updateTicker.C:
-			orders, _ := a.GetAllNotProcessedOrders(ctx)
-			for _, order := range orders {
-				notProcessedOrdersCH <- order
-
-				go a.worker(ctx, restClient, accrualAddress, notProcessedOrdersCH)
-			}
+		case <-pollTicker.C:
+			a.dispatch(ctx)
 		}
 	}
 }
 
-func (a *Accrual) worker(ctx context.Context, restClient *resty.Client, accrualAddress string, orderCh <-chan entity.Order) {
-	for {
+// Claim orders and queue the ones that are not in flight, never blocks the ticker.
+// The claim renews the leases of the orders this replica holds
+func (a *Accrual) dispatch(ctx context.Context) {
+	now := time.Now()
+	orders, err := a.ClaimNotProcessedOrders(ctx, entity.OrderClaim{
+		Owner:      a.owner,
+		ClaimedAt:  now,
+		LeaseUntil: now.Add(a.GetAccrualLease()),
+		// room for the orders in flight and a full queue
+		Limit: a.workers + cap(a.queue),
+	})
+	if err != nil {
+		if !errors.Is(err, entity.ErrNoContent) {
+			a.fail("claim orders", err)
+		}
+		return
+	}
+
+	for _, order := range orders {
+		if !a.acquire(order.Number) {
+			continue
+		}
 		select {
-		case <-ctx.Done():
+		case a.queue <- order:
+		default:
+			// the queue is full, the rest waits for the next poll
+			a.release(order.Number)
 			return
-		case order := <-orderCh:
-			accrualResp := accrualRespose{}
-			resp, _ := restClient.R().
-				EnableTrace().
-				SetResult(&accrualResp).
-				Get(fmt.Sprintf("%s/api/orders/%s", accrualAddress, order.Number))
-
-			if resp.StatusCode() == http.StatusTooManyRequests {
-				retryAfterHeader := resp.Header().Get("Retry-After")
-				if retryAfterHeader != "" {
-					secondsToWait, _ := strconv.Atoi(retryAfterHeader)
-					time.Sleep(time.Duration(secondsToWait) * time.Second)
-				}
-
-				continue
-			}
-
-			if resp.StatusCode() == http.StatusOK {
-				newOrder := entity.Order{
-					Number:  order.Number,
-					Status:  accrualResp.Status,
-					Accrual: accrualResp.Accrual,
-				}
-				_ = a.SetOrderStatusAndAccrual(ctx, newOrder)
-			}
+		}
+	}
+}
+
+func (a *Accrual) acquire(number string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
+	if _, ok := a.inFlight[number]; ok {
+		return false
+	}
+	a.inFlight[number] = struct{}{}
+
+	return true
+}
+
+func (a *Accrual) release(number string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.inFlight, number)
+}
+
+// drains the queue, skipping the orders once ctx is done
+func (a *Accrual) worker(ctx context.Context) {
+	for order := range a.queue {
+		if ctx.Err() == nil {
+			a.processing.Add(1)
+			a.process(ctx, order)
+			a.processing.Add(-1)
+		}
+		a.release(order.Number)
+	}
+}
+
+func (a *Accrual) process(ctx context.Context, order entity.Order) {
+	if err := a.governor.Wait(ctx); err != nil {
+		return
+	}
+
+	accrualResp := accrualRespose{}
+	resp, err := a.client.R().
+		SetContext(ctx).
+		SetResult(&accrualResp).
+		Get(fmt.Sprintf("%s/api/orders/%s", a.GetAccrualAddress(), order.Number))
+	if err != nil {
+		// shutting down is not a failure
+		if ctx.Err() == nil {
+			a.fail("request accrual", err, zap.String("order", order.Number))
+		}
+		return
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusTooManyRequests:
+		// pause all workers, the order is dispatched again after the pause
+		a.governor.Pause(parseRetryAfter(resp.Header().Get("Retry-After")), parseRateLimit(resp.String()))
+	case http.StatusOK:
+		// unknown statuses and moves back are not stored, the storage checks again under its lock
+		if !order.Status.CanTransitionTo(accrualResp.Status) {
+			a.illegalTransition(order, accrualResp.Status)
 			return
 		}
+		err := a.SetOrderStatusAndAccrual(ctx, accrualResp.order(order.Number))
+		switch {
+		case errors.Is(err, entity.ErrOrderStatusTransition):
+			a.illegalTransition(order, accrualResp.Status)
+		case err != nil:
+			a.fail("set order status", err, zap.String("order", order.Number), zap.String("status", string(accrualResp.Status)))
+		}
 	}
 }
+
+// the order changed since it was claimed or the accrual system sent an unknown status
+func (a *Accrual) illegalTransition(order entity.Order, status entity.OrderStatus) {
+	a.illegalTransitions.Add(1)
+	a.logger.Warn("accrual illegal order status transition",
+		zap.String("order", order.Number),
+		zap.String("from", string(order.Status)),
+		zap.String("to", string(status)),
+	)
+}
+
+// log the error and count it in the stats
+func (a *Accrual) fail(msg string, err error, fields ...zap.Field) {
+	a.errors.Add(1)
+	a.logger.Error("accrual "+msg, append(fields, zap.Error(err))...)
+}
@@ -0,0 +1,347 @@
+package accrual
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/adapters/storage/memory"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type mockConfig struct {
+	address   string
+	workers   int
+	queueSize int
+	rateLimit int
+}
+
+func (m mockConfig) GetAccrualAddress() string {
+	return m.address
+}
+func (m mockConfig) GetAccrualWorkers() int {
+	return m.workers
+}
+func (m mockConfig) GetAccrualQueueSize() int {
+	return m.queueSize
+}
+func (m mockConfig) GetAccrualPollInterval() time.Duration {
+	return 10 * time.Millisecond
+}
+func (m mockConfig) GetAccrualRateLimit() int {
+	return m.rateLimit
+}
+func (m mockConfig) GetAccrualLease() time.Duration {
+	return time.Minute
+}
+
+type mockStorage struct {
+	mu      sync.Mutex
+	orders  []entity.Order
+	updated []entity.Order
+	// returned by every update when set
+	setErr error
+}
+
+func (m *mockStorage) ClaimNotProcessedOrders(ctx context.Context, claim entity.OrderClaim) ([]entity.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]entity.Order(nil), m.orders...), nil
+}
+
+func (m *mockStorage) SetOrderStatusAndAccrual(ctx context.Context, order entity.Order) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.setErr != nil {
+		return m.setErr
+	}
+	m.updated = append(m.updated, order)
+	for i := range m.orders {
+		if m.orders[i].Number == order.Number {
+			m.orders = append(m.orders[:i], m.orders[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (m *mockStorage) updatedCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.updated)
+}
+
+// accrual system that answers once unblocked, counting the requests per order
+type mockAccrualServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests map[string]int
+	unblock  chan struct{}
+}
+
+func newMockAccrualServer() *mockAccrualServer {
+	m := &mockAccrualServer{
+		requests: make(map[string]int),
+		unblock:  make(chan struct{}),
+	}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		number := strings.TrimPrefix(r.URL.Path, "/api/orders/")
+		m.mu.Lock()
+		m.requests[number]++
+		m.mu.Unlock()
+
+		select {
+		case <-m.unblock:
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"order":"` + number + `","status":"PROCESSED","accrual":10}`))
+	}))
+
+	return m
+}
+
+func (m *mockAccrualServer) requestsPerOrder() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	requests := make(map[string]int, len(m.requests))
+	for number, count := range m.requests {
+		requests[number] = count
+	}
+
+	return requests
+}
+
+func TestAccrual_Run(t *testing.T) {
+	// Arrange
+	server := newMockAccrualServer()
+	defer server.Close()
+	storage := &mockStorage{orders: []entity.Order{{Number: "12345678903", Status: entity.StatusNew}, {Number: "79927398713", Status: entity.StatusNew}, {Number: "9278923470", Status: entity.StatusNew}}}
+	accrual, _ := New(mockConfig{address: server.URL, workers: 2, queueSize: 5}, storage, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	// Act
+	go func() {
+		defer close(done)
+		accrual.Run(ctx)
+	}()
+
+	// Assert
+	// both workers hold an order, the third one waits in the queue
+	assert.Eventually(t, func() bool {
+		return accrual.Stats() == Stats{Workers: 2, QueueSize: 5, QueueDepth: 1, InFlight: 2}
+	}, time.Second, 5*time.Millisecond)
+	// later polls do not dispatch the same orders again
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, Stats{Workers: 2, QueueSize: 5, QueueDepth: 1, InFlight: 2}, accrual.Stats())
+	assert.Len(t, server.requestsPerOrder(), 2)
+
+	close(server.unblock)
+	assert.Eventually(t, func() bool { return storage.updatedCount() == 3 }, time.Second, 5*time.Millisecond)
+	for number, count := range server.requestsPerOrder() {
+		assert.Equal(t, 1, count, number)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("accrual did not stop")
+	}
+	assert.Equal(t, Stats{Workers: 2, QueueSize: 5}, accrual.Stats())
+}
+
+func TestAccrual_RunDrainsOnCancel(t *testing.T) {
+	// Arrange
+	server := newMockAccrualServer()
+	defer server.Close()
+	defer close(server.unblock)
+	storage := &mockStorage{orders: []entity.Order{{Number: "12345678903", Status: entity.StatusNew}, {Number: "79927398713", Status: entity.StatusNew}, {Number: "9278923470", Status: entity.StatusNew}}}
+	accrual, _ := New(mockConfig{address: server.URL, workers: 1, queueSize: 1}, storage, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		accrual.Run(ctx)
+	}()
+	// the worker waits for the accrual system
+	assert.Eventually(t, func() bool { return len(server.requestsPerOrder()) == 1 }, time.Second, 5*time.Millisecond)
+
+	// Act
+	cancel()
+
+	// Assert
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("accrual did not stop")
+	}
+	assert.Equal(t, Stats{Workers: 1, QueueSize: 1}, accrual.Stats())
+	assert.Equal(t, 0, storage.updatedCount())
+}
+
+func TestAccrualRespose_Order(t *testing.T) {
+	tests := []struct {
+		name string
+		resp accrualRespose
+		want entity.Order
+	}{
+		{
+			name: "estimate of a registered order",
+			resp: accrualRespose{Status: entity.StatusRegistered, Accrual: entity.NewMoney(100, 0)},
+			want: entity.Order{Number: "9278923470", Status: entity.StatusRegistered, Pending: entity.NewMoney(100, 0)},
+		},
+		{
+			name: "estimate of a processing order",
+			resp: accrualRespose{Status: entity.StatusProcessing, Accrual: entity.NewMoney(50, 50)},
+			want: entity.Order{Number: "9278923470", Status: entity.StatusProcessing, Pending: entity.NewMoney(50, 50)},
+		},
+		{
+			name: "processed order",
+			resp: accrualRespose{Status: entity.StatusProcessed, Accrual: entity.NewMoney(90, 0)},
+			want: entity.Order{Number: "9278923470", Status: entity.StatusProcessed, Accrual: entity.NewMoney(90, 0)},
+		},
+		{
+			name: "invalid order",
+			resp: accrualRespose{Status: entity.StatusInvalid},
+			want: entity.Order{Number: "9278923470", Status: entity.StatusInvalid},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			order := tt.resp.order("9278923470")
+
+			// Assert
+			assert.Equal(t, tt.want, order)
+		})
+	}
+}
+
+func TestAccrual_RunReplicasCreditOnce(t *testing.T) {
+	// Arrange
+	server := newMockAccrualServer()
+	defer server.Close()
+	close(server.unblock)
+	storage, _ := memory.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	id, _ := storage.UserRegister(ctx, entity.User{Login: "user1", Password: "hash"})
+	user := entity.User{ID: id}
+	numbers := []string{"12345678903", "79927398713", "9278923470"}
+	for _, number := range numbers {
+		storage.AddOrder(ctx, entity.Order{Number: number}, user)
+	}
+
+	var wg sync.WaitGroup
+	for _, owner := range []string{"replica1", "replica2", "replica3"} {
+		accrual, _ := New(mockConfig{address: server.URL, workers: 2, queueSize: 2}, storage, zap.NewNop())
+		accrual.owner = owner
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			accrual.Run(ctx)
+		}()
+	}
+
+	// Act
+	assert.Eventually(t, func() bool {
+		balance, _ := storage.GetBalance(ctx, user)
+		return balance.Current == entity.NewMoney(30, 0)
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	// Assert
+	balance, _ := storage.GetBalance(ctx, user)
+	assert.Equal(t, entity.Balance{Current: entity.NewMoney(30, 0)}, balance)
+	for number, count := range server.requestsPerOrder() {
+		assert.Equal(t, 1, count, number)
+	}
+}
+
+func TestAccrual_RunLogsErrors(t *testing.T) {
+	// Arrange
+	server := newMockAccrualServer()
+	defer server.Close()
+	close(server.unblock)
+	storage := &mockStorage{orders: []entity.Order{{Number: "12345678903", Status: entity.StatusNew}}, setErr: errors.New("db is down")}
+	core, logs := observer.New(zap.ErrorLevel)
+	accrual, _ := New(mockConfig{address: server.URL, workers: 1, queueSize: 1}, storage, zap.New(core))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	// Act
+	go func() {
+		defer close(done)
+		accrual.Run(ctx)
+	}()
+
+	// Assert
+	// the order keeps failing on every poll and every failure is seen
+	assert.Eventually(t, func() bool { return accrual.Stats().Errors >= 2 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+	assert.Equal(t, accrual.Stats().Errors, int64(logs.FilterMessage("accrual set order status").Len()))
+	assert.Equal(t, "12345678903", logs.All()[0].ContextMap()["order"])
+}
+
+func TestAccrual_RunCountsIllegalTransitions(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  entity.Order
+		setErr error
+	}{
+		{
+			name:  "order is final already",
+			order: entity.Order{Number: "12345678903", Status: entity.StatusInvalid},
+		},
+		{
+			name:   "order changed since the claim",
+			order:  entity.Order{Number: "12345678903", Status: entity.StatusNew},
+			setErr: entity.ErrOrderStatusTransition,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			server := newMockAccrualServer()
+			defer server.Close()
+			close(server.unblock)
+			storage := &mockStorage{orders: []entity.Order{tt.order}, setErr: tt.setErr}
+			core, logs := observer.New(zap.WarnLevel)
+			accrual, _ := New(mockConfig{address: server.URL, workers: 1, queueSize: 1}, storage, zap.New(core))
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+
+			// Act
+			go func() {
+				defer close(done)
+				accrual.Run(ctx)
+			}()
+
+			// Assert
+			assert.Eventually(t, func() bool { return accrual.Stats().IllegalTransitions >= 1 }, time.Second, 5*time.Millisecond)
+			cancel()
+			<-done
+			assert.Equal(t, int64(0), accrual.Stats().Errors)
+			assert.Equal(t, 0, storage.updatedCount())
+			assert.Equal(t, "accrual illegal order status transition", logs.All()[0].Message)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package accrual
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// wait when the accrual system answers 429 without Retry-After
+const defaultRetryAfter = time.Minute
+
+// limit the accrual system sends with 429
+var rateLimitRe = regexp.MustCompile(`No more than (\d+) requests per minute`)
+
+// Rate of the calls to the accrual system, shared by all workers.
+// A 429 pauses every call, after the pause the calls are spaced
+// by the slower of the configured rate and the one the system sent
+type governor struct {
+	mu sync.Mutex
+	// gap between two calls, zero for no limit
+	interval time.Duration
+	// configured gap, the system can only make it longer
+	minInterval time.Duration
+	// no call before this time
+	next time.Time
+}
+
+// rate in requests per minute, zero for no limit
+func newGovernor(rate int) *governor {
+	return &governor{
+		interval:    rateInterval(rate),
+		minInterval: rateInterval(rate),
+	}
+}
+
+func rateInterval(rate int) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	return time.Minute / time.Duration(rate)
+}
+
+// block until a call is allowed or ctx is done
+func (g *governor) Wait(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		now := time.Now()
+		if !g.next.After(now) {
+			g.next = now.Add(g.interval)
+			g.mu.Unlock()
+			return nil
+		}
+		wait := g.next.Sub(now)
+		g.mu.Unlock()
+
+		// the pause can grow while waiting, so check again
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// stop all calls for retryAfter, a positive rate is the limit sent by the system
+func (g *governor) Pause(retryAfter time.Duration, rate int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if until := time.Now().Add(retryAfter); until.After(g.next) {
+		g.next = until
+	}
+	if interval := rateInterval(rate); interval > g.minInterval {
+		g.interval = interval
+	} else {
+		g.interval = g.minInterval
+	}
+}
+
+// Retry-After in seconds
+func parseRetryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return defaultRetryAfter
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// requests per minute from the 429 body, zero when it is not there
+func parseRateLimit(body string) int {
+	match := rateLimitRe.FindStringSubmatch(body)
+	if match == nil {
+		return 0
+	}
+	rate, _ := strconv.Atoi(match[1])
+	return rate
+}
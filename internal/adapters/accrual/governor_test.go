@@ -0,0 +1,173 @@
+package accrual
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+	}{
+		{
+			name:   "seconds",
+			header: "60",
+			want:   time.Minute,
+		},
+		{
+			name: "no header",
+			want: defaultRetryAfter,
+		},
+		{
+			name:   "http date",
+			header: "Wed, 21 Oct 2026 07:28:00 GMT",
+			want:   defaultRetryAfter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			retryAfter := parseRetryAfter(tt.header)
+
+			// Assert
+			assert.Equal(t, tt.want, retryAfter)
+		})
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{
+			name: "limit",
+			body: "No more than 10 requests per minute allowed",
+			want: 10,
+		},
+		{
+			name: "no limit",
+			body: "Too Many Requests",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			rate := parseRateLimit(tt.body)
+
+			// Assert
+			assert.Equal(t, tt.want, rate)
+		})
+	}
+}
+
+func TestGovernor_Pause(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     int
+		limit    int
+		interval time.Duration
+	}{
+		{
+			name:     "limit of the system",
+			rate:     600,
+			limit:    300,
+			interval: 200 * time.Millisecond,
+		},
+		{
+			name:     "configured rate is slower",
+			rate:     300,
+			limit:    600,
+			interval: 200 * time.Millisecond,
+		},
+		{
+			name:     "no limit",
+			interval: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			governor := newGovernor(tt.rate)
+			ctx := context.Background()
+
+			// Act
+			governor.Pause(50*time.Millisecond, tt.limit)
+			start := time.Now()
+			governor.Wait(ctx)
+			paused := time.Since(start)
+			governor.Wait(ctx)
+			spaced := time.Since(start) - paused
+
+			// Assert
+			assert.GreaterOrEqual(t, paused, 50*time.Millisecond)
+			assert.GreaterOrEqual(t, spaced, tt.interval)
+			assert.Equal(t, tt.interval, governor.interval)
+		})
+	}
+}
+
+func TestGovernor_WaitCanceled(t *testing.T) {
+	// Arrange
+	governor := newGovernor(0)
+	governor.Pause(time.Minute, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// Act
+	err := governor.Wait(ctx)
+
+	// Assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestAccrual_RunBacksOffOnTooManyRequests(t *testing.T) {
+	// Arrange
+	var mu sync.Mutex
+	var calls []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, time.Now())
+		first := len(calls) == 1
+		mu.Unlock()
+
+		if first {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte("No more than 600 requests per minute allowed"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"order":"` + r.URL.Path[len("/api/orders/"):] + `","status":"PROCESSED","accrual":10}`))
+	}))
+	defer server.Close()
+	storage := &mockStorage{orders: []entity.Order{{Number: "12345678903", Status: entity.StatusNew}, {Number: "79927398713", Status: entity.StatusNew}, {Number: "9278923470", Status: entity.StatusNew}}}
+	accrual, _ := New(mockConfig{address: server.URL, workers: 3, queueSize: 3, rateLimit: 6000}, storage, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Act
+	go accrual.Run(ctx)
+
+	// Assert
+	assert.Eventually(t, func() bool { return storage.updatedCount() == 3 }, 3*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	// the other workers wait with the one that got 429
+	assert.GreaterOrEqual(t, calls[1].Sub(calls[0]), 900*time.Millisecond)
+	// then the calls follow the limit of the system
+	for i := 2; i < len(calls); i++ {
+		assert.GreaterOrEqual(t, calls[i].Sub(calls[i-1]), 90*time.Millisecond)
+	}
+}
@@ -1,6 +1,10 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -12,43 +16,76 @@ import (
 //go:generate mockery --name config --exported
 type config interface {
 	GetAppSecretKey() string
-	GetTokenLifeTime() time.Duration
+	GetAccessTokenLifeTime() time.Duration
+	GetSigningKeys() ([]entity.SigningKey, error)
 }
 
 type Auth struct {
 	config
+	keys []signingKey
 }
 
-// info in jwt token
+// size of the random part of a refresh token
+const refreshTokenBytes = 32
+
+// info in jwt token, the token is readable by anyone so it holds no credentials
 type claims struct {
-	entity.User
+	ID        int64
+	IP        string
+	UserAgent string
+	SessionID int64
+	Role      entity.Role `json:"Role,omitempty"`
 	jwt.RegisteredClaims
 }
 
 func New(config config) (*Auth, error) {
+	keysConfig, err := config.GetSigningKeys()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := loadSigningKeys(keysConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Auth{
 		config,
+		keys,
 	}, nil
 }
 
 func (a *Auth) GenerateToken(userFromBd entity.User) (string, error) {
 	claims := claims{
-		userFromBd,
-		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(a.GetTokenLifeTime() * time.Hour)),
+		ID:        userFromBd.ID,
+		IP:        userFromBd.IP,
+		UserAgent: userFromBd.UserAgent,
+		SessionID: userFromBd.SessionID,
+		Role:      userFromBd.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(a.GetAccessTokenLifeTime())),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	return token.SignedString([]byte(a.GetAppSecretKey()))
+	// without signing keys the shared secret is used
+	if len(a.keys) == 0 {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(a.GetAppSecretKey()))
+	}
+
+	key, err := a.currentKey(time.Now())
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+
+	return token.SignedString(key.private)
 }
 
 func (a *Auth) CheckToken(user entity.User, tokenString string) error {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(a.GetAppSecretKey()), nil
-	})
+	token, err := jwt.Parse(tokenString, a.keyFunc)
 
 	if !token.Valid {
 		if errors.Is(err, jwt.ErrTokenMalformed) {
@@ -82,9 +119,7 @@ func (a *Auth) CheckToken(user entity.User, tokenString string) error {
 func (a *Auth) GetUserFromToken(tokenString string) (entity.User, error) {
 	var user entity.User
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(a.GetAppSecretKey()), nil
-	})
+	token, err := jwt.Parse(tokenString, a.keyFunc)
 
 	if token.Valid && err == nil {
 		claims, ok := token.Claims.(jwt.MapClaims)
@@ -97,7 +132,37 @@ func (a *Auth) GetUserFromToken(tokenString string) (entity.User, error) {
 		}
 
 		user.ID = int64(userIDFloat)
+
+		// tokens issued before sessions existed have no session,
+		// they are rejected by the session check
+		if sessionIDFloat, found := claims["SessionID"].(float64); found {
+			user.SessionID = int64(sessionIDFloat)
+		}
+
+		// tokens issued before roles existed belong to users
+		roleName, _ := claims["Role"].(string)
+		role, err := entity.ParseRole(roleName)
+		if err != nil {
+			return user, err
+		}
+		user.Role = role
 	}
 
 	return user, err
 }
+
+// opaque refresh token, only its hash is stored
+func (a *Auth) GenerateRefreshToken() (string, string, error) {
+	raw := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("auth GenerateRefreshToken: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	return token, a.HashRefreshToken(token), nil
+}
+
+func (a *Auth) HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/korovindenis/go-market/internal/adapters/auth/mocks"
 	"github.com/korovindenis/go-market/internal/domain/entity"
 	"github.com/stretchr/testify/assert"
@@ -13,10 +14,11 @@ import (
 
 func TestAuth_GenerateToken(t *testing.T) {
 	config := mocks.NewConfig(t)
+	config.On("GetSigningKeys").Return(nil, nil)
 	auth, _ := New(config)
 	config.On("GetAppSecretKey").Return("xxxxxxxx", nil).Maybe()
 	config.On("GetTokenName").Return("gomarket_auth", nil).Maybe()
-	config.On("GetTokenLifeTime").Return(time.Duration(6), nil).Maybe()
+	config.On("GetAccessTokenLifeTime").Return(15*time.Minute, nil).Maybe()
 
 	type args struct {
 		userFromBd entity.User
@@ -51,12 +53,41 @@ func TestAuth_GenerateToken(t *testing.T) {
 	}
 }
 
+func TestAuth_GenerateTokenWithoutCredentials(t *testing.T) {
+	// Arrange
+	config := mocks.NewConfig(t)
+	config.On("GetSigningKeys").Return(nil, nil)
+	auth, _ := New(config)
+	config.On("GetAppSecretKey").Return("xxxxxxxx", nil).Maybe()
+	config.On("GetAccessTokenLifeTime").Return(15*time.Minute, nil).Maybe()
+	user := entity.User{Login: "root", Password: "root", Email: "root@example.com", ID: 1, IP: "127.0.0.1", UserAgent: "curl", SessionID: 2, Role: entity.RoleAdmin}
+
+	// Act
+	token, err := auth.GenerateToken(user)
+
+	// Assert
+	assert.NoError(t, err)
+	claims := jwt.MapClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(token, claims)
+	assert.NoError(t, err)
+	assert.NotContains(t, claims, "Login")
+	assert.NotContains(t, claims, "login")
+	assert.NotContains(t, claims, "password")
+	assert.NotContains(t, claims, "email")
+	assert.Equal(t, "admin", claims["Role"])
+	assert.Equal(t, float64(2), claims["SessionID"])
+	fromToken, err := auth.GetUserFromToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.User{ID: 1, SessionID: 2, Role: entity.RoleAdmin}, fromToken)
+}
+
 func TestAuth_CheckToken(t *testing.T) {
 	config := mocks.NewConfig(t)
+	config.On("GetSigningKeys").Return(nil, nil)
 	auth, _ := New(config)
 	config.On("GetAppSecretKey").Return("xxxxxxxx", nil).Maybe()
 	config.On("GetTokenName").Return("gomarket_auth", nil).Maybe()
-	config.On("GetTokenLifeTime").Return(time.Duration(6), nil).Maybe()
+	config.On("GetAccessTokenLifeTime").Return(15*time.Minute, nil).Maybe()
 
 	type args struct {
 		user        entity.User
@@ -119,10 +150,11 @@ func TestAuth_CheckToken(t *testing.T) {
 
 func TestAuth_GetUserFromToken(t *testing.T) {
 	config := mocks.NewConfig(t)
+	config.On("GetSigningKeys").Return(nil, nil)
 	auth, _ := New(config)
 	config.On("GetAppSecretKey").Return("xxxxxxxx", nil).Maybe()
 	config.On("GetTokenName").Return("gomarket_auth", nil).Maybe()
-	config.On("GetTokenLifeTime").Return(time.Duration(6), nil).Maybe()
+	config.On("GetAccessTokenLifeTime").Return(15*time.Minute, nil).Maybe()
 
 	type args struct {
 		tokenString string
@@ -164,3 +196,55 @@ func TestAuth_GetUserFromToken(t *testing.T) {
 		})
 	}
 }
+
+func TestAuth_SessionInToken(t *testing.T) {
+	config := mocks.NewConfig(t)
+	config.On("GetSigningKeys").Return(nil, nil)
+	auth, _ := New(config)
+	config.On("GetAppSecretKey").Return("xxxxxxxx", nil).Maybe()
+	config.On("GetAccessTokenLifeTime").Return(15*time.Minute, nil).Maybe()
+
+	// Act
+	token, err := auth.GenerateToken(entity.User{ID: 7, SessionID: 3})
+	assert.NoError(t, err)
+	user, err := auth.GetUserFromToken(token)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), user.ID)
+	assert.Equal(t, int64(3), user.SessionID)
+	assert.Equal(t, entity.RoleUser, user.Role, "tokens without a role belong to users")
+}
+
+func TestAuth_RoleInToken(t *testing.T) {
+	config := mocks.NewConfig(t)
+	config.On("GetSigningKeys").Return(nil, nil)
+	auth, _ := New(config)
+	config.On("GetAppSecretKey").Return("xxxxxxxx", nil).Maybe()
+	config.On("GetAccessTokenLifeTime").Return(15*time.Minute, nil).Maybe()
+
+	// Act
+	token, err := auth.GenerateToken(entity.User{ID: 7, SessionID: 3, Role: entity.RoleAdmin})
+	assert.NoError(t, err)
+	user, err := auth.GetUserFromToken(token)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, entity.RoleAdmin, user.Role)
+}
+
+func TestAuth_GenerateRefreshToken(t *testing.T) {
+	config := mocks.NewConfig(t)
+	config.On("GetSigningKeys").Return(nil, nil)
+	auth, _ := New(config)
+
+	// Act
+	token, hash, err := auth.GenerateRefreshToken()
+	other, _, _ := auth.GenerateRefreshToken()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other)
+	assert.Equal(t, hash, auth.HashRefreshToken(token))
+	assert.Len(t, hash, 64)
+}
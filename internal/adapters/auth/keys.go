@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+var (
+	ErrUnknownKID        = errors.New("unknown kid")
+	ErrKeyExpired        = errors.New("signing key expired")
+	ErrNoActiveKey       = errors.New("no active signing key")
+	ErrUnexpectedAlg     = errors.New("unexpected signing method")
+	ErrUnsupportedKey    = errors.New("unsupported private key")
+	ErrUnsupportedMethod = errors.New("unsupported signing algorithm")
+)
+
+// loaded signing key
+type signingKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	private   crypto.Signer
+	signUntil time.Time
+}
+
+func (k *signingKey) canSign(now time.Time) bool {
+	return k.signUntil.IsZero() || now.Before(k.signUntil)
+}
+
+// tokens signed right before the cutoff stay valid for their whole lifetime
+func (k *signingKey) canVerify(now time.Time, tokenLifeTime time.Duration) bool {
+	return k.signUntil.IsZero() || now.Before(k.signUntil.Add(tokenLifeTime))
+}
+
+func loadSigningKeys(keys []entity.SigningKey) ([]signingKey, error) {
+	loaded := make([]signingKey, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key.KID == "" || seen[key.KID] {
+			return nil, fmt.Errorf("signing key %q: kid must be unique and not empty", key.KID)
+		}
+		seen[key.KID] = true
+
+		private, err := readPrivateKey(key.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", key.KID, err)
+		}
+
+		var method jwt.SigningMethod
+		switch key.Algorithm {
+		case entity.SigningAlgorithmRS256:
+			if _, ok := private.(*rsa.PrivateKey); !ok {
+				return nil, fmt.Errorf("signing key %s: %w for %s", key.KID, ErrUnsupportedKey, key.Algorithm)
+			}
+			method = jwt.SigningMethodRS256
+		case entity.SigningAlgorithmEdDSA:
+			if _, ok := private.(ed25519.PrivateKey); !ok {
+				return nil, fmt.Errorf("signing key %s: %w for %s", key.KID, ErrUnsupportedKey, key.Algorithm)
+			}
+			method = jwt.SigningMethodEdDSA
+		default:
+			return nil, fmt.Errorf("signing key %s: %w %q", key.KID, ErrUnsupportedMethod, key.Algorithm)
+		}
+
+		loaded = append(loaded, signingKey{
+			kid:       key.KID,
+			method:    method,
+			private:   private,
+			signUntil: key.SignUntil,
+		})
+	}
+
+	return loaded, nil
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+
+	return signer, nil
+}
+
+// newest key that still signs, keys are configured oldest first
+func (a *Auth) currentKey(now time.Time) (*signingKey, error) {
+	for i := len(a.keys) - 1; i >= 0; i-- {
+		if a.keys[i].canSign(now) {
+			return &a.keys[i], nil
+		}
+	}
+
+	return nil, ErrNoActiveKey
+}
+
+// key to verify a token with, tokens are looked up by kid
+func (a *Auth) keyFunc(token *jwt.Token) (interface{}, error) {
+	// without signing keys the shared secret is used
+	if len(a.keys) == 0 {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("%w %s", ErrUnexpectedAlg, token.Method.Alg())
+		}
+		return []byte(a.GetAppSecretKey()), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	for i := range a.keys {
+		key := &a.keys[i]
+		if key.kid != kid {
+			continue
+		}
+		if !key.canVerify(time.Now(), a.GetAccessTokenLifeTime()) {
+			return nil, fmt.Errorf("%w %s", ErrKeyExpired, kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("%w %s", ErrUnexpectedAlg, token.Method.Alg())
+		}
+		return key.private.Public(), nil
+	}
+
+	return nil, fmt.Errorf("%w %q", ErrUnknownKID, kid)
+}
+
+// public parts of the keys tokens can still be verified with, for other services
+func (a *Auth) JWKS() entity.JWKS {
+	jwks := entity.JWKS{Keys: []entity.JWK{}}
+	now := time.Now()
+	for _, key := range a.keys {
+		if !key.canVerify(now, a.GetAccessTokenLifeTime()) {
+			continue
+		}
+
+		jwk := entity.JWK{
+			KID:       key.kid,
+			Use:       "sig",
+			Algorithm: key.method.Alg(),
+		}
+		switch public := key.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
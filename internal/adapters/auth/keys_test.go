@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/korovindenis/go-market/internal/adapters/auth/mocks"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeKey(t *testing.T, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func rsaKeyFile(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeKey(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+}
+
+func ed25519KeyFile(t *testing.T) string {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeKey(t, "PRIVATE KEY", der)
+}
+
+func newAuthWithKeys(t *testing.T, keys []entity.SigningKey) *Auth {
+	config := mocks.NewConfig(t)
+	config.On("GetSigningKeys").Return(keys, nil)
+	config.On("GetAccessTokenLifeTime").Return(15*time.Minute, nil).Maybe()
+	config.On("GetAppSecretKey").Return("xxxxxxxx", nil).Maybe()
+	auth, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth
+}
+
+func TestAuth_SigningKeyRotation(t *testing.T) {
+	oldKey := entity.SigningKey{KID: "2026-09", Algorithm: entity.SigningAlgorithmRS256, PrivateKeyFile: rsaKeyFile(t)}
+	newKey := entity.SigningKey{KID: "2026-10", Algorithm: entity.SigningAlgorithmEdDSA, PrivateKeyFile: ed25519KeyFile(t)}
+	user := entity.User{ID: 7, SessionID: 3}
+
+	// Arrange
+	before := newAuthWithKeys(t, []entity.SigningKey{oldKey})
+	oldToken, err := before.GenerateToken(user)
+	assert.NoError(t, err)
+
+	// Act
+	after := newAuthWithKeys(t, []entity.SigningKey{oldKey, newKey})
+	newToken, err := after.GenerateToken(user)
+	assert.NoError(t, err)
+
+	// Assert
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-10", parsed.Header["kid"])
+	assert.Equal(t, "EdDSA", parsed.Method.Alg())
+
+	// tokens of the old key stay valid during rotation
+	for _, token := range []string{oldToken, newToken} {
+		got, err := after.GetUserFromToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, got.ID)
+	}
+
+	// the retired key no longer signs but verifies for one more token lifetime
+	oldKey.SignUntil = time.Now().Add(-time.Minute)
+	retired := newAuthWithKeys(t, []entity.SigningKey{oldKey})
+	_, err = retired.GenerateToken(user)
+	assert.ErrorIs(t, err, ErrNoActiveKey)
+	_, err = retired.GetUserFromToken(oldToken)
+	assert.NoError(t, err)
+	assert.Len(t, retired.JWKS().Keys, 1)
+
+	// and its tokens stop being valid after that
+	oldKey.SignUntil = time.Now().Add(-16 * time.Minute)
+	expired := newAuthWithKeys(t, []entity.SigningKey{oldKey, newKey})
+	_, err = expired.GetUserFromToken(oldToken)
+	assert.ErrorIs(t, err, ErrKeyExpired)
+	assert.Len(t, expired.JWKS().Keys, 1)
+}
+
+func TestAuth_RejectsSharedSecretWithKeys(t *testing.T) {
+	hmac := newAuthWithKeys(t, nil)
+	token, err := hmac.GenerateToken(entity.User{ID: 7})
+	assert.NoError(t, err)
+
+	// Act
+	auth := newAuthWithKeys(t, []entity.SigningKey{{KID: "1", Algorithm: entity.SigningAlgorithmEdDSA, PrivateKeyFile: ed25519KeyFile(t)}})
+	_, err = auth.GetUserFromToken(token)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrUnknownKID)
+}
+
+func TestAuth_JWKS(t *testing.T) {
+	auth := newAuthWithKeys(t, []entity.SigningKey{
+		{KID: "rsa", Algorithm: entity.SigningAlgorithmRS256, PrivateKeyFile: rsaKeyFile(t)},
+		{KID: "ed", Algorithm: entity.SigningAlgorithmEdDSA, PrivateKeyFile: ed25519KeyFile(t)},
+	})
+
+	// Act
+	jwks := auth.JWKS()
+
+	// Assert
+	if assert.Len(t, jwks.Keys, 2) {
+		assert.Equal(t, "RSA", jwks.Keys[0].KeyType)
+		assert.Equal(t, "AQAB", jwks.Keys[0].E)
+		assert.NotEmpty(t, jwks.Keys[0].N)
+		assert.Equal(t, "OKP", jwks.Keys[1].KeyType)
+		assert.Equal(t, "Ed25519", jwks.Keys[1].Curve)
+		assert.Len(t, jwks.Keys[1].X, 43)
+	}
+}
+
+func TestLoadSigningKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []entity.SigningKey
+	}{
+		{
+			name: "algorithm does not match key",
+			keys: []entity.SigningKey{{KID: "1", Algorithm: entity.SigningAlgorithmRS256, PrivateKeyFile: ed25519KeyFile(t)}},
+		},
+		{
+			name: "unsupported algorithm",
+			keys: []entity.SigningKey{{KID: "1", Algorithm: entity.SigningAlgorithmHS256, PrivateKeyFile: ed25519KeyFile(t)}},
+		},
+		{
+			name: "duplicate kid",
+			keys: []entity.SigningKey{
+				{KID: "1", Algorithm: entity.SigningAlgorithmEdDSA, PrivateKeyFile: ed25519KeyFile(t)},
+				{KID: "1", Algorithm: entity.SigningAlgorithmEdDSA, PrivateKeyFile: ed25519KeyFile(t)},
+			},
+		},
+		{
+			name: "missing file",
+			keys: []entity.SigningKey{{KID: "1", Algorithm: entity.SigningAlgorithmEdDSA, PrivateKeyFile: "/nonexistent.pem"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadSigningKeys(tt.keys)
+
+			assert.Error(t, err)
+		})
+	}
+}
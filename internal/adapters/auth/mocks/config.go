@@ -3,9 +3,10 @@
 package mocks
 
 import (
-	time "time"
-
+	entity "github.com/korovindenis/go-market/internal/domain/entity"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // Config is an autogenerated mock type for the config type
@@ -13,6 +14,24 @@ type Config struct {
 	mock.Mock
 }
 
+// GetAccessTokenLifeTime provides a mock function with given fields:
+func (_m *Config) GetAccessTokenLifeTime() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccessTokenLifeTime")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
 // GetAppSecretKey provides a mock function with given fields:
 func (_m *Config) GetAppSecretKey() string {
 	ret := _m.Called()
@@ -31,22 +50,34 @@ func (_m *Config) GetAppSecretKey() string {
 	return r0
 }
 
-// GetTokenLifeTime provides a mock function with given fields:
-func (_m *Config) GetTokenLifeTime() time.Duration {
+// GetSigningKeys provides a mock function with given fields:
+func (_m *Config) GetSigningKeys() ([]entity.SigningKey, error) {
 	ret := _m.Called()
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetTokenLifeTime")
+		panic("no return value specified for GetSigningKeys")
 	}
 
-	var r0 time.Duration
-	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+	var r0 []entity.SigningKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]entity.SigningKey, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []entity.SigningKey); ok {
 		r0 = rf()
 	} else {
-		r0 = ret.Get(0).(time.Duration)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.SigningKey)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // NewConfig creates a new instance of Config. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
@@ -15,19 +15,121 @@ import (
 
 const configDefaultPath = "./configs/config.dev.yaml"
 
+// storage drivers
+const (
+	StorageDriverPostgreSQL = "postgresql"
+	StorageDriverMemory     = "memory"
+)
+
+// notifier drivers
+const (
+	NotifierDriverLog  = "log"
+	NotifierDriverSMTP = "smtp"
+)
+
+const (
+	defaultAccessTokenLifeTime   = 15 * time.Minute
+	defaultPasswordResetLifeTime = 30 * time.Minute
+	defaultTOTPIssuer            = "gomarket"
+)
+
+// accrual poller defaults
+const (
+	defaultAccrualWorkers      = 10
+	defaultAccrualQueueSize    = 100
+	defaultAccrualPollInterval = time.Second
+	defaultAccrualLease        = time.Minute
+)
+
+const (
+	defaultPointsExpiringSoon   = 30 * 24 * time.Hour
+	defaultPointsExpireInterval = time.Hour
+)
+
+// login brute-force protection defaults
+const (
+	defaultLoginMaxFailures = 5
+	defaultLoginBaseDelay   = time.Second
+	defaultLoginLockout     = 15 * time.Minute
+)
+
 // data in configDefaultPath
 type config struct {
 	App        `koanf:"app"`
 	Httpserver `koanf:"http_server"`
 	Storage    `koanf:"storage"`
 	Accrual    `koanf:"accrual"`
+	Notifier   `koanf:"notifier"`
 }
 
 type App struct {
-	LogsLevel     string `koanf:"logs_level"`
-	SecretKey     string `koanf:"secret_key"`
-	TokenName     string `koanf:"token_name"`
-	TokenLifeTime int    `koanf:"token_lifetime"`
+	LogsLevel           string `koanf:"logs_level"`
+	SecretKey           string `koanf:"secret_key"`
+	TokenName           string `koanf:"token_name"`
+	TokenLifeTime       int    `koanf:"token_lifetime"`
+	AccessTokenLifeTime int    `koanf:"access_token_lifetime"`
+	RefreshTokenName    string `koanf:"refresh_token_name"`
+	// where the access token is looked up, in order of precedence
+	TokenSources []string `koanf:"token_sources"`
+	// asymmetric signing keys, oldest first, the newest active one signs
+	SigningKeys []SigningKey `koanf:"signing_keys"`
+	// brute-force protection of login
+	LoginAttempts struct {
+		MaxFailures int `koanf:"max_failures"`
+		BaseDelay   int `koanf:"base_delay"`
+		Lockout     int `koanf:"lockout"`
+	} `koanf:"login_attempts"`
+	Password Password `koanf:"password"`
+	// self-service password reset
+	PasswordReset struct {
+		// minutes
+		TokenLifeTime int `koanf:"token_lifetime"`
+		// the token is added as the token query parameter
+		URL string `koanf:"url"`
+	} `koanf:"password_reset"`
+	// totp two-factor auth
+	TwoFactor struct {
+		Issuer string `koanf:"issuer"`
+		// points, withdrawals above it need a totp code, 0 turns the check off
+		WithdrawalThreshold int64 `koanf:"withdrawal_threshold"`
+	} `koanf:"two_factor"`
+	// expiration of loyalty points
+	Points struct {
+		// months after accrual, 0 keeps points forever
+		LifeTime int `koanf:"lifetime"`
+		// days
+		ExpiringSoon int `koanf:"expiring_soon"`
+		// minutes
+		ExpireInterval int `koanf:"expire_interval"`
+	} `koanf:"points"`
+}
+
+type Password struct {
+	// argon2id or bcrypt, other hashes are upgraded on login
+	Hasher   string `koanf:"hasher"`
+	Argon2id struct {
+		Time uint32 `koanf:"time"`
+		// KiB
+		Memory  uint32 `koanf:"memory"`
+		Threads uint8  `koanf:"threads"`
+	} `koanf:"argon2id"`
+	BcryptCost int `koanf:"bcrypt_cost"`
+	Policy     struct {
+		MinLength     int  `koanf:"min_length"`
+		MaxLength     int  `koanf:"max_length"`
+		RequireUpper  bool `koanf:"require_upper"`
+		RequireLower  bool `koanf:"require_lower"`
+		RequireDigit  bool `koanf:"require_digit"`
+		RequireSymbol bool `koanf:"require_symbol"`
+	} `koanf:"policy"`
+}
+
+type SigningKey struct {
+	KID            string `koanf:"kid"`
+	Algorithm      string `koanf:"algorithm"`
+	PrivateKeyFile string `koanf:"private_key_file"`
+	// RFC3339, when the key stops signing, empty means never
+	SignUntil string `koanf:"sign_until"`
 }
 
 type Httpserver struct {
@@ -43,12 +145,33 @@ type Httpserver struct {
 }
 
 type Storage struct {
+	Driver           string `koanf:"driver"`
 	ConnectionString string `koanf:"connection_string"`
 	Salt             string `koanf:"salt"`
 }
 
 type Accrual struct {
 	Address string `koanf:"address"`
+	// size of the worker pool and of the queue that feeds it
+	Workers   int `koanf:"workers"`
+	QueueSize int `koanf:"queue_size"`
+	// seconds between polls for unprocessed orders
+	PollInterval int `koanf:"poll_interval"`
+	// requests per minute to the accrual system, zero until it sends its limit
+	RateLimit int `koanf:"rate_limit"`
+	// seconds a replica holds the orders it polls
+	Lease int `koanf:"lease"`
+}
+
+type Notifier struct {
+	// log or smtp
+	Driver string `koanf:"driver"`
+	SMTP   struct {
+		Address  string `koanf:"address"`
+		Username string `koanf:"username"`
+		Password string `koanf:"password"`
+		From     string `koanf:"from"`
+	} `koanf:"smtp"`
 }
 
 func New() (*config, error) {
@@ -92,13 +215,104 @@ func (c *config) GetTokenLifeTime() time.Duration {
 	return time.Duration(c.App.TokenLifeTime)
 }
 
+// access token lives minutes, the session behind it lives GetTokenLifeTime hours,
+// unset value falls back to the default
+func (c *config) GetAccessTokenLifeTime() time.Duration {
+	if c.App.AccessTokenLifeTime <= 0 {
+		return defaultAccessTokenLifeTime
+	}
+	return time.Duration(c.App.AccessTokenLifeTime) * time.Minute
+}
+
+func (c *config) GetRefreshTokenName() string {
+	return c.App.RefreshTokenName
+}
+
+func (c *config) GetTokenSources() []string {
+	return c.App.TokenSources
+}
+
+// without signing keys tokens are signed with HS256 and the secret key
+func (c *config) GetSigningKeys() ([]entity.SigningKey, error) {
+	keys := make([]entity.SigningKey, 0, len(c.App.SigningKeys))
+	for _, key := range c.App.SigningKeys {
+		signingKey := entity.SigningKey{
+			KID:            key.KID,
+			Algorithm:      key.Algorithm,
+			PrivateKeyFile: key.PrivateKeyFile,
+		}
+		if key.SignUntil != "" {
+			signUntil, err := time.Parse(time.RFC3339, key.SignUntil)
+			if err != nil {
+				return nil, fmt.Errorf("signing key %s sign_until: %w", key.KID, err)
+			}
+			signingKey.SignUntil = signUntil
+		}
+		keys = append(keys, signingKey)
+	}
+
+	return keys, nil
+}
+
+// base delay is in seconds and lockout in minutes, unset values fall back to defaults
+func (c *config) GetLoginPolicy() entity.LoginPolicy {
+	policy := entity.LoginPolicy{
+		MaxFailures: c.App.LoginAttempts.MaxFailures,
+		BaseDelay:   time.Duration(c.App.LoginAttempts.BaseDelay) * time.Second,
+		Lockout:     time.Duration(c.App.LoginAttempts.Lockout) * time.Minute,
+	}
+	if policy.MaxFailures <= 0 {
+		policy.MaxFailures = defaultLoginMaxFailures
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = defaultLoginBaseDelay
+	}
+	if policy.Lockout <= 0 {
+		policy.Lockout = defaultLoginLockout
+	}
+
+	return policy
+}
+
+func (c *config) GetPasswordHasher() string {
+	return c.App.Password.Hasher
+}
+
+func (c *config) GetArgon2idTime() uint32 {
+	return c.App.Password.Argon2id.Time
+}
+
+func (c *config) GetArgon2idMemory() uint32 {
+	return c.App.Password.Argon2id.Memory
+}
+
+func (c *config) GetArgon2idThreads() uint8 {
+	return c.App.Password.Argon2id.Threads
+}
+
+func (c *config) GetBcryptCost() int {
+	return c.App.Password.BcryptCost
+}
+
+func (c *config) GetPasswordPolicy() entity.PasswordPolicy {
+	policy := c.App.Password.Policy
+	return entity.PasswordPolicy{
+		MinLength:     policy.MinLength,
+		MaxLength:     policy.MaxLength,
+		RequireUpper:  policy.RequireUpper,
+		RequireLower:  policy.RequireLower,
+		RequireDigit:  policy.RequireDigit,
+		RequireSymbol: policy.RequireSymbol,
+	}
+}
+
 func (c *config) GetServerMode() string {
 	return c.Httpserver.Mode
 }
 
 func (c *config) parseFlags() {
 	flag.StringVar(&c.Httpserver.Address, "a", "localhost:8080", "Address and port to run the service")
-	flag.StringVar(&c.Storage.ConnectionString, "d", "host=127.0.0.1 user=go password=go dbname=go sslmode=disable", "Database connection string")
+	flag.StringVar(&c.Storage.ConnectionString, "d", c.Storage.ConnectionString, "Database connection string")
 	flag.StringVar(&c.Accrual.Address, "r", "http://localhost:8082", "Accural service address")
 	flag.Parse()
 
@@ -107,6 +321,9 @@ func (c *config) parseFlags() {
 	}
 	if envKey, err := getEnvVariable("DATABASE_URI"); err == nil {
 		c.Storage.ConnectionString = envKey
+	} else if _, exists := os.LookupEnv("DATABASE_URI"); exists {
+		// DATABASE_URI is set but empty - run without a database
+		c.Storage.ConnectionString = ""
 	}
 	if envKey, err := getEnvVariable("ACCRUAL_SYSTEM_ADDRESS"); err == nil {
 		c.Accrual.Address = envKey
@@ -141,6 +358,27 @@ func (c *config) GetStorageConnectionString() string {
 	return c.Storage.ConnectionString
 }
 
+// Memory is used when it is set explicitly or when no driver is set and
+// there is nothing to connect to. A chosen postgresql needs a connection string
+func (c *config) GetStorageDriver() (string, error) {
+	switch c.Storage.Driver {
+	case StorageDriverMemory:
+		return StorageDriverMemory, nil
+	case StorageDriverPostgreSQL:
+		if c.Storage.ConnectionString == "" {
+			return "", fmt.Errorf("storage driver %s needs a connection string", StorageDriverPostgreSQL)
+		}
+		return StorageDriverPostgreSQL, nil
+	case "":
+		if c.Storage.ConnectionString == "" {
+			return StorageDriverMemory, nil
+		}
+		return StorageDriverPostgreSQL, nil
+	}
+
+	return "", fmt.Errorf("unknown storage driver: %s", c.Storage.Driver)
+}
+
 func (c *config) GetStorageSalt() string {
 	return c.Storage.Salt
 }
@@ -149,9 +387,103 @@ func (c *config) GetAccrualAddress() string {
 	return c.Accrual.Address
 }
 
+func (c *config) GetAccrualWorkers() int {
+	if c.Accrual.Workers <= 0 {
+		return defaultAccrualWorkers
+	}
+	return c.Accrual.Workers
+}
+
+func (c *config) GetAccrualQueueSize() int {
+	if c.Accrual.QueueSize <= 0 {
+		return defaultAccrualQueueSize
+	}
+	return c.Accrual.QueueSize
+}
+
+func (c *config) GetAccrualRateLimit() int {
+	return c.Accrual.RateLimit
+}
+
+func (c *config) GetAccrualLease() time.Duration {
+	if c.Accrual.Lease <= 0 {
+		return defaultAccrualLease
+	}
+	return time.Duration(c.Accrual.Lease) * time.Second
+}
+
+func (c *config) GetAccrualPollInterval() time.Duration {
+	if c.Accrual.PollInterval <= 0 {
+		return defaultAccrualPollInterval
+	}
+	return time.Duration(c.Accrual.PollInterval) * time.Second
+}
+
 func getEnvVariable(varName string) (string, error) {
 	if envVarValue, exists := os.LookupEnv(varName); exists && envVarValue != "" {
 		return envVarValue, nil
 	}
 	return "", entity.ErrEnvVarNotFound
 }
+
+// password reset token lives minutes, unset value falls back to the default
+func (c *config) GetPasswordResetLifeTime() time.Duration {
+	if c.App.PasswordReset.TokenLifeTime <= 0 {
+		return defaultPasswordResetLifeTime
+	}
+	return time.Duration(c.App.PasswordReset.TokenLifeTime) * time.Minute
+}
+
+func (c *config) GetPasswordResetURL() string {
+	return c.App.PasswordReset.URL
+}
+
+// shown in authenticator apps, defaults to the app name
+func (c *config) GetTOTPIssuer() string {
+	if c.App.TwoFactor.Issuer == "" {
+		return defaultTOTPIssuer
+	}
+	return c.App.TwoFactor.Issuer
+}
+
+func (c *config) GetTOTPWithdrawalThreshold() entity.Money {
+	return entity.NewMoney(c.App.TwoFactor.WithdrawalThreshold, 0)
+}
+
+// lifetime is in months, expiring soon in days and the interval in minutes,
+// unset values but the lifetime fall back to defaults
+func (c *config) GetPointsPolicy() entity.PointsPolicy {
+	policy := entity.PointsPolicy{
+		LifetimeMonths: c.App.Points.LifeTime,
+		ExpiringSoon:   time.Duration(c.App.Points.ExpiringSoon) * 24 * time.Hour,
+		ExpireInterval: time.Duration(c.App.Points.ExpireInterval) * time.Minute,
+	}
+	if policy.ExpiringSoon <= 0 {
+		policy.ExpiringSoon = defaultPointsExpiringSoon
+	}
+	if policy.ExpireInterval <= 0 {
+		policy.ExpireInterval = defaultPointsExpireInterval
+	}
+
+	return policy
+}
+
+func (c *config) GetNotifierDriver() string {
+	return c.Notifier.Driver
+}
+
+func (c *config) GetSMTPAddress() string {
+	return c.Notifier.SMTP.Address
+}
+
+func (c *config) GetSMTPUsername() string {
+	return c.Notifier.SMTP.Username
+}
+
+func (c *config) GetSMTPPassword() string {
+	return c.Notifier.SMTP.Password
+}
+
+func (c *config) GetSMTPFrom() string {
+	return c.Notifier.SMTP.From
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_GetStorageDriver(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage Storage
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "positive - memory",
+			storage: Storage{Driver: StorageDriverMemory},
+			want:    StorageDriverMemory,
+		},
+		{
+			name:    "positive - postgresql",
+			storage: Storage{Driver: StorageDriverPostgreSQL, ConnectionString: "host=127.0.0.1"},
+			want:    StorageDriverPostgreSQL,
+		},
+		{
+			name:    "positive - unset driver with connection string",
+			storage: Storage{ConnectionString: "host=127.0.0.1"},
+			want:    StorageDriverPostgreSQL,
+		},
+		{
+			name:    "positive - unset driver without connection string",
+			storage: Storage{},
+			want:    StorageDriverMemory,
+		},
+		{
+			name:    "negative - postgresql without connection string",
+			storage: Storage{Driver: StorageDriverPostgreSQL},
+			wantErr: true,
+		},
+		{
+			name:    "negative - unknown driver",
+			storage: Storage{Driver: "sqlite"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			c := config{Storage: tt.storage}
+
+			// Act
+			got, err := c.GetStorageDriver()
+
+			// Assert
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfig_ParseFlagsKeepsEmptyConnectionString(t *testing.T) {
+	// Arrange
+	t.Setenv("DATABASE_URI", "")
+	os.Unsetenv("DATABASE_URI")
+	c := config{}
+
+	// Act
+	c.parseFlags()
+
+	// Assert
+	assert.Empty(t, c.GetStorageConnectionString())
+	driver, err := c.GetStorageDriver()
+	assert.NoError(t, err)
+	assert.Equal(t, StorageDriverMemory, driver)
+}
@@ -26,3 +26,16 @@ func (u *Ctxinfo) GetUserIDFromCtx(ctx *gin.Context) (int64, error) {
 
 	return userID, nil
 }
+
+func (u *Ctxinfo) GetSessionIDFromCtx(ctx *gin.Context) (int64, error) {
+	sessionIDRaw, ok := ctx.Get("sessionId")
+	if !ok {
+		return 0, fmt.Errorf("%s", "GetSessionIDFromCtx Get SessionId")
+	}
+	sessionID, ok := sessionIDRaw.(int64)
+	if !ok {
+		return 0, fmt.Errorf("%s", "GetSessionIDFromCtx sessionIDRaw")
+	}
+
+	return sessionID, nil
+}
@@ -39,3 +39,25 @@ func TestGetUserIDFromCtxInvalidType(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, int64(0), resultUserID)
 }
+
+func TestGetSessionIDFromCtx(t *testing.T) {
+	handler := &Ctxinfo{}
+	ctx := &gin.Context{}
+	sessionID := int64(7)
+	ctx.Set("sessionId", sessionID)
+
+	resultSessionID, err := handler.GetSessionIDFromCtx(ctx)
+
+	assert.Nil(t, err)
+	assert.Equal(t, sessionID, resultSessionID)
+}
+
+func TestGetSessionIDFromCtxMissingKey(t *testing.T) {
+	handler := &Ctxinfo{}
+	ctx := &gin.Context{}
+
+	resultSessionID, err := handler.GetSessionIDFromCtx(ctx)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, int64(0), resultSessionID)
+}
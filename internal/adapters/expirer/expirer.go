@@ -0,0 +1,70 @@
+package expirer
+
+import (
+	"context"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"go.uber.org/zap"
+)
+
+// write-off in BD
+type storage interface {
+	ExpirePoints(ctx context.Context, earnedBefore time.Time) (entity.Money, error)
+}
+
+// configuration
+type config interface {
+	GetPointsPolicy() entity.PointsPolicy
+}
+
+// writes off points that were not spent within their lifetime
+type Expirer struct {
+	storage
+	config
+	logger *zap.Logger
+}
+
+func New(config config, storage storage, logger *zap.Logger) (*Expirer, error) {
+	return &Expirer{
+		storage: storage,
+		config:  config,
+		logger:  logger,
+	}, nil
+}
+
+func (e *Expirer) Run(ctx context.Context) {
+	policy := e.config.GetPointsPolicy()
+	if policy.LifetimeMonths <= 0 {
+		return
+	}
+
+	expireTicker := time.NewTicker(policy.ExpireInterval)
+	defer expireTicker.Stop()
+	for {
+		expired, err := e.Expire(ctx, time.Now())
+		switch {
+		case err != nil && ctx.Err() == nil:
+			// the points are written off on the next tick
+			e.logger.Error("expirer expire points", zap.Error(err))
+		case err == nil:
+			e.logger.Debug("expirer expired points", zap.String("amount", expired.String()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-expireTicker.C:
+		}
+	}
+}
+
+// write off the points that have expired by now
+func (e *Expirer) Expire(ctx context.Context, now time.Time) (entity.Money, error) {
+	earnedBefore := e.config.GetPointsPolicy().EarnedBefore(now)
+	if earnedBefore.IsZero() {
+		return 0, nil
+	}
+
+	return e.ExpirePoints(ctx, earnedBefore)
+}
@@ -0,0 +1,116 @@
+package expirer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type mockConfig struct {
+	policy entity.PointsPolicy
+}
+
+func (m mockConfig) GetPointsPolicy() entity.PointsPolicy {
+	return m.policy
+}
+
+type mockStorage struct {
+	earnedBefore []time.Time
+	// returned by every write-off when set
+	err error
+}
+
+func (m *mockStorage) ExpirePoints(ctx context.Context, earnedBefore time.Time) (entity.Money, error) {
+	m.earnedBefore = append(m.earnedBefore, earnedBefore)
+	if m.err != nil {
+		return 0, m.err
+	}
+	return entity.NewMoney(10, 0), nil
+}
+
+func TestExpirer_Expire(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		policy       entity.PointsPolicy
+		expired      entity.Money
+		earnedBefore []time.Time
+	}{
+		{
+			name:         "points expire",
+			policy:       entity.PointsPolicy{LifetimeMonths: 12},
+			expired:      entity.NewMoney(10, 0),
+			earnedBefore: []time.Time{time.Date(2025, 10, 18, 12, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "points live forever",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			storage := &mockStorage{}
+			expirer, _ := New(mockConfig{policy: tt.policy}, storage, zap.NewNop())
+
+			// Act
+			expired, err := expirer.Expire(context.Background(), now)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expired, expired)
+			assert.Equal(t, tt.earnedBefore, storage.earnedBefore)
+		})
+	}
+}
+
+func TestExpirer_RunLogs(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		level zapcore.Level
+		msg   string
+	}{
+		{
+			name:  "expired amount",
+			level: zap.DebugLevel,
+			msg:   "expirer expired points",
+		},
+		{
+			name:  "failed write-off",
+			err:   errors.New("db is down"),
+			level: zap.ErrorLevel,
+			msg:   "expirer expire points",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			core, logs := observer.New(zap.DebugLevel)
+			policy := entity.PointsPolicy{LifetimeMonths: 12, ExpireInterval: time.Hour}
+			expirer, _ := New(mockConfig{policy: policy}, &mockStorage{err: tt.err}, zap.New(core))
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+
+			// Act
+			go func() {
+				defer close(done)
+				expirer.Run(ctx)
+			}()
+
+			// Assert
+			assert.Eventually(t, func() bool { return logs.Len() == 1 }, time.Second, 5*time.Millisecond)
+			cancel()
+			<-done
+			entry := logs.All()[0]
+			assert.Equal(t, tt.level, entry.Level)
+			assert.Equal(t, tt.msg, entry.Message)
+		})
+	}
+}
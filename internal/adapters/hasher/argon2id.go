@@ -0,0 +1,117 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// sizes recommended by RFC 9106
+const (
+	argon2idSaltLength = 16
+	argon2idKeyLength  = 32
+)
+
+// defaults of RFC 9106 second recommended option
+const (
+	argon2idDefaultTime    = 3
+	argon2idDefaultMemory  = 64 * 1024
+	argon2idDefaultThreads = 4
+)
+
+type argon2id struct {
+	time uint32
+	// KiB
+	memory  uint32
+	threads uint8
+}
+
+type argon2idHash struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func (a *argon2id) params() (uint32, uint32, uint8) {
+	time, memory, threads := a.time, a.memory, a.threads
+	if time == 0 {
+		time = argon2idDefaultTime
+	}
+	if memory == 0 {
+		memory = argon2idDefaultMemory
+	}
+	if threads == 0 {
+		threads = argon2idDefaultThreads
+	}
+	return time, memory, threads
+}
+
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+func (a *argon2id) hash(password string) (string, error) {
+	salt := make([]byte, argon2idSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	time, memory, threads := a.params()
+	key := argon2.IDKey([]byte(password), salt, time, memory, threads, argon2idKeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, memory, time, threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a *argon2id) verify(hash, password string) (bool, error) {
+	parsed, err := parseArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), parsed.salt, parsed.time, parsed.memory, parsed.threads, uint32(len(parsed.key)))
+
+	return subtle.ConstantTimeCompare(key, parsed.key) == 1, nil
+}
+
+func (a *argon2id) owns(hash string) bool {
+	return hashID(hash) == AlgorithmArgon2id
+}
+
+func (a *argon2id) outdated(hash string) bool {
+	parsed, err := parseArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	time, memory, threads := a.params()
+	return parsed.time != time || parsed.memory != memory || parsed.threads != threads
+}
+
+func parseArgon2id(hash string) (argon2idHash, error) {
+	var parsed argon2idHash
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return parsed, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return parsed, ErrMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &parsed.memory, &parsed.time, &parsed.threads); err != nil {
+		return parsed, ErrMalformedHash
+	}
+
+	var err error
+	if parsed.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return parsed, ErrMalformedHash
+	}
+	if parsed.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(parsed.key) == 0 {
+		return parsed, ErrMalformedHash
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,50 @@
+package hasher
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type bcryptHasher struct {
+	cost int
+}
+
+func (b *bcryptHasher) getCost() int {
+	if b.cost == 0 {
+		return bcrypt.DefaultCost
+	}
+	return b.cost
+}
+
+func (b *bcryptHasher) hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.getCost())
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (b *bcryptHasher) verify(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *bcryptHasher) owns(hash string) bool {
+	switch hashID(hash) {
+	case "2a", "2b", "2y":
+		return true
+	}
+	return false
+}
+
+func (b *bcryptHasher) outdated(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != b.getCost()
+}
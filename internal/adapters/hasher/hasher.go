@@ -0,0 +1,96 @@
+// Password hashing, new hashes use the configured algorithm,
+// hashes of any supported algorithm are verified
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// supported algorithms
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrMalformedHash    = errors.New("malformed password hash")
+)
+
+//go:generate mockery --name config --exported
+type config interface {
+	GetPasswordHasher() string
+	GetArgon2idTime() uint32
+	GetArgon2idMemory() uint32
+	GetArgon2idThreads() uint8
+	GetBcryptCost() int
+}
+
+// one hashing algorithm
+type algorithm interface {
+	hash(password string) (string, error)
+	verify(hash, password string) (bool, error)
+	// the hash was made by this algorithm
+	owns(hash string) bool
+	// the hash was made with other parameters
+	outdated(hash string) bool
+}
+
+type Hasher struct {
+	preferred  algorithm
+	algorithms []algorithm
+}
+
+func New(config config) (*Hasher, error) {
+	argon := &argon2id{
+		time:    config.GetArgon2idTime(),
+		memory:  config.GetArgon2idMemory(),
+		threads: config.GetArgon2idThreads(),
+	}
+	bcryptAlg := &bcryptHasher{cost: config.GetBcryptCost()}
+
+	h := &Hasher{
+		algorithms: []algorithm{argon, bcryptAlg},
+	}
+	switch config.GetPasswordHasher() {
+	case AlgorithmArgon2id, "":
+		h.preferred = argon
+	case AlgorithmBcrypt:
+		h.preferred = bcryptAlg
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, config.GetPasswordHasher())
+	}
+
+	return h, nil
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.preferred.hash(password)
+}
+
+// false without error means a wrong password
+func (h *Hasher) Verify(hash, password string) (bool, error) {
+	for _, alg := range h.algorithms {
+		if alg.owns(hash) {
+			return alg.verify(hash, password)
+		}
+	}
+
+	return false, ErrUnknownAlgorithm
+}
+
+// the hash should be replaced by a hash of the preferred algorithm
+func (h *Hasher) NeedsRehash(hash string) bool {
+	return !h.preferred.owns(hash) || h.preferred.outdated(hash)
+}
+
+// $<id>$... as in the PHC string format
+func hashID(hash string) string {
+	parts := strings.SplitN(hash, "$", 3)
+	if len(parts) < 3 || parts[0] != "" {
+		return ""
+	}
+	return parts[1]
+}
@@ -0,0 +1,96 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/korovindenis/go-market/internal/adapters/hasher/mocks"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap parameters keep the tests fast
+func newHasher(t *testing.T, algorithm string, memory uint32) *Hasher {
+	config := mocks.NewConfig(t)
+	config.On("GetPasswordHasher").Return(algorithm, nil)
+	config.On("GetArgon2idTime").Return(uint32(1), nil)
+	config.On("GetArgon2idMemory").Return(memory, nil)
+	config.On("GetArgon2idThreads").Return(uint8(1), nil)
+	config.On("GetBcryptCost").Return(bcrypt.MinCost, nil)
+
+	h, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestHasher_Argon2id(t *testing.T) {
+	h := newHasher(t, AlgorithmArgon2id, 1024)
+
+	// Act
+	hash, err := h.Hash("rootgomarket")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	ok, err := h.Verify(hash, "rootgomarket")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = h.Verify(hash, "wrong")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.False(t, h.NeedsRehash(hash))
+	assert.True(t, newHasher(t, AlgorithmArgon2id, 2048).NeedsRehash(hash), "memory cost changed")
+}
+
+func TestHasher_UpgradeBcrypt(t *testing.T) {
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("rootgomarket"), bcrypt.MinCost)
+	h := newHasher(t, AlgorithmArgon2id, 1024)
+
+	// Act
+	ok, err := h.Verify(string(legacy), "rootgomarket")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, h.NeedsRehash(string(legacy)))
+	assert.False(t, newHasher(t, AlgorithmBcrypt, 1024).NeedsRehash(string(legacy)))
+}
+
+func TestHasher_Verify_Malformed(t *testing.T) {
+	h := newHasher(t, AlgorithmArgon2id, 1024)
+
+	tests := []struct {
+		name string
+		hash string
+		err  error
+	}{
+		{
+			name: "plain text",
+			hash: "root",
+			err:  ErrUnknownAlgorithm,
+		},
+		{
+			name: "broken argon2id",
+			hash: "$argon2id$v=19$m=1024$salt$key",
+			err:  ErrMalformedHash,
+		},
+		{
+			name: "other version",
+			hash: "$argon2id$v=16$m=1024,t=1,p=1$c2FsdHNhbHQ$a2V5",
+			err:  ErrMalformedHash,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := h.Verify(tt.hash, "root")
+
+			assert.False(t, ok)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
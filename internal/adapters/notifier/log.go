@@ -0,0 +1,38 @@
+package notifier
+
+import (
+	"context"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"go.uber.org/zap"
+)
+
+// writes notifications to the log instead of sending them, for development only
+type Log struct {
+	logger  *zap.Logger
+	baseURL string
+}
+
+func NewLog(config interface{ GetPasswordResetURL() string }, logger *zap.Logger) (*Log, error) {
+	return &Log{
+		logger:  logger,
+		baseURL: config.GetPasswordResetURL(),
+	}, nil
+}
+
+func (l *Log) SendPasswordReset(ctx context.Context, user entity.User, token string, expiresAt time.Time) error {
+	link, err := resetLink(l.baseURL, token)
+	if err != nil {
+		return err
+	}
+
+	l.logger.Info("password reset",
+		zap.String("login", user.Login),
+		zap.String("email", user.Email),
+		zap.String("link", link),
+		zap.Time("expires_at", expiresAt),
+	)
+
+	return nil
+}
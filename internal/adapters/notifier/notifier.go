@@ -0,0 +1,32 @@
+// Delivery of notifications to users, SMTP for production
+// and a log-only notifier for development
+package notifier
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// reset link with the token as the token query parameter,
+// without a base url only the token is sent
+func resetLink(baseURL, token string) (string, error) {
+	if baseURL == "" {
+		return token, nil
+	}
+
+	link, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("password reset url: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
+
+func resetMessage(login, link string, expiresAt time.Time) string {
+	return fmt.Sprintf("Hello, %s!\r\n\r\nTo set a new password follow the link below, it is valid until %s:\r\n\r\n%s\r\n\r\nIf you did not ask for a password reset, ignore this message.\r\n",
+		login, expiresAt.UTC().Format(time.RFC1123), link)
+}
@@ -0,0 +1,178 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/adapters/notifier/mocks"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// received message of the smtp stand-in
+type mail struct {
+	from string
+	to   []string
+	data string
+}
+
+// minimal smtp server, accepts one message per connection
+func startSMTPServer(t *testing.T) (string, <-chan mail) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	mails := make(chan mail, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, mails)
+		}
+	}()
+
+	return ln.Addr().String(), mails
+}
+
+func serveSMTP(conn net.Conn, mails chan<- mail) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	var m mail
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		switch upper := strings.ToUpper(cmd); {
+		case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(upper, "MAIL FROM:"):
+			m.from = strings.Trim(cmd[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(upper, "RCPT TO:"):
+			m.to = append(m.to, strings.Trim(cmd[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case upper == "DATA":
+			reply("354 end with .")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			m.data = data.String()
+			mails <- m
+			reply("250 OK")
+		case upper == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func newSMTPConfig(t *testing.T, address, from string) *mocks.Config {
+	config := mocks.NewConfig(t)
+	config.On("GetSMTPAddress").Return(address).Maybe()
+	config.On("GetSMTPFrom").Return(from).Maybe()
+	config.On("GetSMTPUsername").Return("").Maybe()
+	config.On("GetSMTPPassword").Return("").Maybe()
+	config.On("GetPasswordResetURL").Return("http://127.0.0.1:8080/reset-password").Maybe()
+	return config
+}
+
+func TestSMTP_SendPasswordReset(t *testing.T) {
+	// Arrange
+	address, mails := startSMTPServer(t)
+	smtpNotifier, err := NewSMTP(newSMTPConfig(t, address, "gomarket@localhost"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := entity.User{Login: "root", Email: "root@example.com"}
+
+	// Act
+	err = smtpNotifier.SendPasswordReset(context.Background(), user, "reset-token", time.Now().Add(time.Hour))
+
+	// Assert
+	assert.NoError(t, err)
+	select {
+	case m := <-mails:
+		assert.Equal(t, "gomarket@localhost", m.from)
+		assert.Equal(t, []string{"root@example.com"}, m.to)
+		assert.Contains(t, m.data, "To: root@example.com\r\n")
+		assert.Contains(t, m.data, "Subject: Password reset\r\n")
+		assert.Contains(t, m.data, "http://127.0.0.1:8080/reset-password?token=reset-token")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+}
+
+func TestSMTP_InvalidAddress(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+	}{
+		{name: "empty", email: ""},
+		{name: "not an address", email: "root"},
+		{name: "header injection", email: "root@example.com\r\nBcc: evil@example.com"},
+	}
+	address, _ := startSMTPServer(t)
+	smtpNotifier, err := NewSMTP(newSMTPConfig(t, address, "gomarket@localhost"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := smtpNotifier.SendPasswordReset(context.Background(), entity.User{Email: tt.email}, "reset-token", time.Now())
+
+			// Assert
+			assert.ErrorIs(t, err, ErrInvalidAddress)
+		})
+	}
+}
+
+func TestNewSMTP_WrongConfig(t *testing.T) {
+	_, err := NewSMTP(newSMTPConfig(t, "no-port", "gomarket@localhost"))
+	assert.Error(t, err)
+
+	_, err = NewSMTP(newSMTPConfig(t, "127.0.0.1:25", "gomarket"))
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+}
+
+func TestLog_SendPasswordReset(t *testing.T) {
+	// Arrange
+	core, logs := observer.New(zap.InfoLevel)
+	config := mocks.NewConfig(t)
+	config.On("GetPasswordResetURL").Return("")
+	logNotifier, _ := NewLog(config, zap.New(core))
+
+	// Act
+	err := logNotifier.SendPasswordReset(context.Background(), entity.User{Login: "root"}, "reset-token", time.Now())
+
+	// Assert
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, logs.Len()) {
+		assert.Equal(t, "reset-token", logs.All()[0].ContextMap()["link"])
+	}
+}
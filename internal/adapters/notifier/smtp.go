@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+var ErrInvalidAddress = errors.New("invalid email address")
+
+const resetSubject = "Password reset"
+
+//go:generate mockery --name config --exported
+type config interface {
+	GetPasswordResetURL() string
+	GetSMTPAddress() string
+	GetSMTPUsername() string
+	GetSMTPPassword() string
+	GetSMTPFrom() string
+}
+
+// sends notifications by email
+type SMTP struct {
+	address string
+	from    string
+	baseURL string
+	// nil when the server needs no authentication
+	auth smtp.Auth
+}
+
+func NewSMTP(config config) (*SMTP, error) {
+	host, _, err := net.SplitHostPort(config.GetSMTPAddress())
+	if err != nil {
+		return nil, fmt.Errorf("smtp address: %w", err)
+	}
+	if err := checkAddress(config.GetSMTPFrom()); err != nil {
+		return nil, fmt.Errorf("smtp from: %w", err)
+	}
+
+	s := &SMTP{
+		address: config.GetSMTPAddress(),
+		from:    config.GetSMTPFrom(),
+		baseURL: config.GetPasswordResetURL(),
+	}
+	// PlainAuth only sends credentials over TLS or to localhost
+	if config.GetSMTPUsername() != "" {
+		s.auth = smtp.PlainAuth("", config.GetSMTPUsername(), config.GetSMTPPassword(), host)
+	}
+
+	return s, nil
+}
+
+func (s *SMTP) SendPasswordReset(ctx context.Context, user entity.User, token string, expiresAt time.Time) error {
+	if err := checkAddress(user.Email); err != nil {
+		return err
+	}
+	link, err := resetLink(s.baseURL, token)
+	if err != nil {
+		return err
+	}
+
+	return s.send(ctx, user.Email, resetSubject, resetMessage(user.Login, link, expiresAt))
+}
+
+func (s *SMTP) send(ctx context.Context, to, subject, body string) error {
+	msg := strings.Join([]string{
+		"From: " + s.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	// smtp.SendMail has no context, run it aside to honour cancellation
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.address, s.auth, s.from, []string{to}, []byte(msg))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// header injection guard, addresses go into the message headers as is
+func checkAddress(address string) error {
+	if address == "" || strings.ContainsAny(address, "\r\n<>,") || !strings.Contains(address, "@") {
+		return fmt.Errorf("%w: %q", ErrInvalidAddress, address)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// admin
+func (s *Storage) GetUserDetails(ctx context.Context, user entity.User) (entity.UserDetails, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[user.Login]
+	if !ok {
+		return entity.UserDetails{}, entity.ErrUserNotFound
+	}
+	details := entity.UserDetails{
+		ID:      u.id,
+		Login:   u.login,
+		Email:   u.email,
+		Role:    u.role,
+		Blocked: u.blocked,
+	}
+	if twoFactor, ok := s.twoFactors[u.id]; ok {
+		details.TwoFactorEnabled = twoFactor.Enabled
+	}
+	if balance, ok := s.balances[u.id]; ok {
+		details.Balance = *balance
+	}
+
+	return details, nil
+}
+
+// returns the user with its id
+func (s *Storage) SetUserBlocked(ctx context.Context, user entity.User, blocked bool) (entity.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[user.Login]
+	if !ok {
+		return user, entity.ErrUserNotFound
+	}
+
+	action := entity.AuditUserUnblocked
+	if blocked {
+		action = entity.AuditUserBlocked
+	}
+	if err := s.audit(ctx, action, entity.AuditTargetUser(u.id), map[string]any{"blocked": u.blocked}, map[string]any{"blocked": blocked}); err != nil {
+		return user, err
+	}
+
+	u.blocked = blocked
+	user.ID = u.id
+	user.Blocked = blocked
+
+	return user, nil
+}
+
+// post an adjustment to the ledger, the balance can not go below zero
+func (s *Storage) AdjustBalance(ctx context.Context, user entity.User, adjustment entity.BalanceAdjustment) (entity.Balance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[user.Login]
+	if !ok {
+		return entity.Balance{}, entity.ErrUserNotFound
+	}
+	balance, ok := s.balances[u.id]
+	if !ok {
+		return entity.Balance{}, ErrBalanceNotFound
+	}
+	if balance.Current+adjustment.Amount < 0 {
+		return *balance, entity.ErrInsufficientBalance
+	}
+
+	before := *balance
+	if err := s.postLedgerTransaction(entity.NewAdjustmentTransaction(u.id, adjustment.Amount, adjustment.Reason)); err != nil {
+		return *balance, err
+	}
+
+	after := map[string]any{"current": balance.Current, "withdrawn": balance.Withdrawn, "amount": adjustment.Amount, "reason": adjustment.Reason}
+	if err := s.audit(ctx, entity.AuditBalanceAdjusted, entity.AuditTargetUser(u.id), before, after); err != nil {
+		return *balance, err
+	}
+
+	return *balance, nil
+}
+
+func (s *Storage) GetOrder(ctx context.Context, number string) (entity.OrderDetails, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	o, ok := s.orderIndex[number]
+	if !ok {
+		return entity.OrderDetails{}, entity.ErrOrderNotFound
+	}
+	details := entity.OrderDetails{Order: o.Order, UserID: o.userID}
+	if u, ok := s.findUser(entity.User{ID: o.userID}); ok {
+		details.UserLogin = u.login
+	}
+
+	return details, nil
+}
+
+// send an unfinished order to the accrual system again
+func (s *Storage) RequeueOrder(ctx context.Context, number string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orderIndex[number]
+	if !ok {
+		return entity.ErrOrderNotFound
+	}
+	if !entity.CanRequeueOrder(o.Status) {
+		return entity.ErrOrderNotRequeueable
+	}
+
+	if err := s.audit(ctx, entity.AuditOrderRequeued, entity.AuditTargetOrder(number), map[string]any{"status": o.Status, "accrual": o.Accrual}, map[string]any{"status": entity.StatusNew}); err != nil {
+		return err
+	}
+	o.Status = entity.StatusNew
+	o.Accrual = 0
+	o.Pending = 0
+	o.claimedBy = ""
+	o.leaseUntil = time.Time{}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_AdminUser(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := entity.User{Login: "user1"}
+
+	// Arrange
+	id, _ := s.UserRegister(ctx, entity.User{Login: "user1", Password: "hash"})
+
+	// Act
+	balance, err := s.AdjustBalance(ctx, user, entity.BalanceAdjustment{Amount: entity.NewMoney(100, 0), Reason: "lost order"})
+	_, belowZeroErr := s.AdjustBalance(ctx, user, entity.BalanceAdjustment{Amount: entity.NewMoney(-150, 0), Reason: "fraud"})
+	blockedUser, blockErr := s.SetUserBlocked(ctx, user, true)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, entity.NewMoney(100, 0), balance.Current)
+	assert.ErrorIs(t, belowZeroErr, entity.ErrInsufficientBalance)
+	assert.NoError(t, blockErr)
+	assert.Equal(t, id, blockedUser.ID)
+
+	details, err := s.GetUserDetails(ctx, user)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.UserDetails{ID: id, Login: "user1", Role: entity.RoleUser, Blocked: true, Balance: entity.Balance{Current: entity.NewMoney(100, 0)}}, details)
+	credentials, _ := s.GetUserCredentials(ctx, user)
+	assert.True(t, credentials.Blocked)
+
+	transactions, _ := s.GetTransactions(ctx, entity.User{ID: id}, entity.TransactionFilter{})
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, entity.LedgerAdjustment, transactions[0].Type)
+
+	_, err = s.GetUserDetails(ctx, entity.User{Login: "user2"})
+	assert.ErrorIs(t, err, entity.ErrUserNotFound)
+	_, err = s.SetUserBlocked(ctx, entity.User{Login: "user2"}, true)
+	assert.ErrorIs(t, err, entity.ErrUserNotFound)
+}
+
+func TestStorage_RequeueOrder(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+
+	// Arrange
+	id, _ := s.UserRegister(ctx, entity.User{Login: "user1", Password: "hash"})
+	s.AddOrder(ctx, entity.Order{Number: "12345678903"}, entity.User{ID: id})
+	s.AddOrder(ctx, entity.Order{Number: "79927398713"}, entity.User{ID: id})
+	s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "12345678903", Status: entity.StatusInvalid})
+	s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "79927398713", Status: entity.StatusProcessed, Accrual: entity.NewMoney(10, 0)})
+
+	// Act
+	err := s.RequeueOrder(ctx, "12345678903")
+
+	// Assert
+	assert.NoError(t, err)
+	order, _ := s.GetOrder(ctx, "12345678903")
+	assert.Equal(t, entity.StatusNew, order.Status)
+	assert.Equal(t, "user1", order.UserLogin)
+	assert.ErrorIs(t, s.RequeueOrder(ctx, "79927398713"), entity.ErrOrderNotRequeueable)
+	assert.ErrorIs(t, s.RequeueOrder(ctx, "0"), entity.ErrOrderNotFound)
+	_, err = s.GetOrder(ctx, "0")
+	assert.ErrorIs(t, err, entity.ErrOrderNotFound)
+}
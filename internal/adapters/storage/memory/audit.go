@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// build the event and append it to the hash chain, the caller holds the lock
+func (s *Storage) audit(ctx context.Context, action entity.AuditAction, target string, before, after any) error {
+	event, err := entity.NewAuditEvent(ctx, action, target, before, after)
+	if err != nil {
+		return err
+	}
+
+	var prevHash string
+	if n := len(s.auditEvents); n > 0 {
+		prevHash = s.auditEvents[n-1].Hash
+	}
+	event.ID = int64(len(s.auditEvents) + 1)
+	event.Seal(prevHash)
+	s.auditEvents = append(s.auditEvents, event)
+
+	return nil
+}
+
+// audit log, newest first
+func (s *Storage) GetAuditEvents(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []entity.AuditEvent
+	for i := len(s.auditEvents) - 1; i >= 0 && !filter.Full(len(events)); i-- {
+		if filter.Match(s.auditEvents[i]) {
+			events = append(events, s.auditEvents[i])
+		}
+	}
+
+	if len(events) == 0 {
+		return nil, entity.ErrNoContent
+	}
+
+	return events, nil
+}
+
+// events after the given id, oldest first, for checking the hash chain
+func (s *Storage) GetAuditChain(ctx context.Context, afterID int64, limit int) ([]entity.AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// ids are positions in the log
+	if afterID >= int64(len(s.auditEvents)) {
+		return nil, nil
+	}
+	events := s.auditEvents[afterID:]
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	return append([]entity.AuditEvent(nil), events...), nil
+}
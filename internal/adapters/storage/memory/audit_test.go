@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_Audit(t *testing.T) {
+	s, _ := New()
+	ctx := entity.WithAuditActor(context.Background(), entity.AuditActor{IP: "127.0.0.1", UserAgent: "curl"})
+	adminCtx := entity.WithAuditActor(context.Background(), entity.AuditActor{UserID: 99, IP: "10.0.0.1"})
+
+	// Arrange
+	id, _ := s.UserRegister(ctx, entity.User{Login: "user1", Password: "hash"})
+	// the session opened by the registration is not a login
+	s.CreateSession(ctx, entity.Session{UserID: id, RefreshTokenHash: "registered", ExpiresAt: time.Now().Add(time.Hour)})
+	s.AddFailedLogin(ctx, entity.LoginAttemptsKey("user1", "127.0.0.1"), time.Now(), time.Now().Add(-time.Hour))
+	s.CreateSession(ctx, entity.Session{UserID: id, RefreshTokenHash: "first", ExpiresAt: time.Now().Add(time.Hour), OpenedBy: entity.AuditLoginSucceeded})
+	s.AdjustBalance(adminCtx, entity.User{Login: "user1"}, entity.BalanceAdjustment{Amount: entity.NewMoney(100, 0), Reason: "lost order"})
+	s.WithdrawBalance(ctx, entity.BalanceUpdate{Order: "12345678903", Sum: entity.NewMoney(40, 0)}, entity.User{ID: id})
+	// failed changes leave no trace
+	s.AdjustBalance(adminCtx, entity.User{Login: "user1"}, entity.BalanceAdjustment{Amount: entity.NewMoney(-500, 0), Reason: "fraud"})
+
+	// Act
+	events, err := s.GetAuditEvents(ctx, entity.AuditFilter{})
+	chain, chainErr := s.GetAuditChain(ctx, 0, entity.MaxPageLimit)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, chainErr)
+	actions := make([]entity.AuditAction, 0, len(events))
+	for _, event := range events {
+		actions = append(actions, event.Action)
+	}
+	assert.Equal(t, []entity.AuditAction{entity.AuditBalanceWithdrawn, entity.AuditBalanceAdjusted, entity.AuditLoginSucceeded, entity.AuditLoginFailed, entity.AuditUserRegistered}, actions)
+	assert.Equal(t, int64(99), events[1].ActorID)
+	assert.JSONEq(t, `{"current":0,"withdrawn":0}`, string(events[1].Before))
+	assert.JSONEq(t, `{"current":100,"withdrawn":0,"amount":100,"reason":"lost order"}`, string(events[1].After))
+	assert.JSONEq(t, `{"current":60,"order":"12345678903","sum":40}`, string(events[0].After))
+	assert.Equal(t, "127.0.0.1", events[4].IP)
+
+	var verification entity.AuditVerification
+	assert.True(t, verification.Check(chain))
+	assert.Equal(t, int64(5), verification.Checked)
+
+	adjusted, _ := s.GetAuditEvents(ctx, entity.AuditFilter{ActorID: 99, Actions: []entity.AuditAction{entity.AuditBalanceAdjusted}})
+	assert.Len(t, adjusted, 1)
+	_, err = s.GetAuditEvents(ctx, entity.AuditFilter{Target: entity.AuditTargetOrder("1")})
+	assert.ErrorIs(t, err, entity.ErrNoContent)
+	tail, _ := s.GetAuditChain(ctx, 4, entity.MaxPageLimit)
+	assert.Len(t, tail, 1)
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+type idempotencyIndex struct {
+	userID int64
+	key    string
+}
+
+// Take the key for a new request. Keys created before expiredBefore and
+// abandoned reservations of the same request are taken over.
+// Returns the stored key and false when the key is held by another request
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, key entity.IdempotencyKey, expiredBefore time.Time) (entity.IdempotencyKey, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := idempotencyIndex{key.UserID, key.Key}
+	if stored, ok := s.idempotencyKeys[index]; ok {
+		abandoned := stored.StatusCode == 0 && stored.LockedUntil.Before(key.CreatedAt) && stored.Fingerprint == key.Fingerprint
+		if !stored.CreatedAt.Before(expiredBefore) && !abandoned {
+			return stored, false, nil
+		}
+	}
+
+	key.StatusCode = 0
+	s.idempotencyKeys[index] = key
+
+	return key, true, nil
+}
+
+// store the response status of the request holding the key
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := idempotencyIndex{key.UserID, key.Key}
+	if stored, ok := s.idempotencyKeys[index]; ok && stored.Fingerprint == key.Fingerprint {
+		stored.StatusCode = key.StatusCode
+		s.idempotencyKeys[index] = stored
+	}
+
+	return nil
+}
+
+// give the key up, the request can be retried with it
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := idempotencyIndex{key.UserID, key.Key}
+	if stored, ok := s.idempotencyKeys[index]; ok && stored.StatusCode == 0 {
+		delete(s.idempotencyKeys, index)
+	}
+
+	return nil
+}
+
+// complete the key of the request together with its change,
+// must be called with the write lock held
+func (s *Storage) completeRequestIdempotencyKey(ctx context.Context) {
+	key, ok := entity.IdempotencyKeyFromContext(ctx)
+	if !ok || key.StatusCode == 0 {
+		return
+	}
+	index := idempotencyIndex{key.UserID, key.Key}
+	if stored, ok := s.idempotencyKeys[index]; ok && stored.Fingerprint == key.Fingerprint {
+		stored.StatusCode = key.StatusCode
+		s.idempotencyKeys[index] = stored
+	}
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_IdempotencyKey(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	now := time.Now()
+	key := entity.IdempotencyKey{UserID: 7, Key: "key1", Fingerprint: "first", CreatedAt: now, LockedUntil: now.Add(time.Minute)}
+	expiredBefore := now.Add(-entity.IdempotencyKeyLifeTime)
+
+	// Arrange
+	_, reserved, err := s.ReserveIdempotencyKey(ctx, key, expiredBefore)
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+
+	// Act
+	inProgress, reservedAgain, _ := s.ReserveIdempotencyKey(ctx, key, expiredBefore)
+	otherUser := key
+	otherUser.UserID = 8
+	_, otherReserved, _ := s.ReserveIdempotencyKey(ctx, otherUser, expiredBefore)
+	key.StatusCode = http.StatusOK
+	assert.NoError(t, s.CompleteIdempotencyKey(ctx, key))
+	completed, _, _ := s.ReserveIdempotencyKey(ctx, key, expiredBefore)
+
+	// Assert
+	assert.False(t, reservedAgain)
+	assert.Equal(t, 0, inProgress.StatusCode)
+	assert.True(t, otherReserved, "keys are kept per user")
+	assert.Equal(t, http.StatusOK, completed.StatusCode)
+
+	// abandoned requests and old keys are taken over
+	abandoned := entity.IdempotencyKey{UserID: 7, Key: "key2", Fingerprint: "first", CreatedAt: now.Add(-time.Hour), LockedUntil: now.Add(-59 * time.Minute)}
+	s.ReserveIdempotencyKey(ctx, abandoned, expiredBefore)
+	abandoned.CreatedAt = now
+	_, reserved, _ = s.ReserveIdempotencyKey(ctx, abandoned, expiredBefore)
+	assert.True(t, reserved)
+	_, reserved, _ = s.ReserveIdempotencyKey(ctx, key, now.Add(time.Second))
+	assert.True(t, reserved)
+
+	// released keys can be used again
+	assert.NoError(t, s.ReleaseIdempotencyKey(ctx, abandoned))
+	_, reserved, _ = s.ReserveIdempotencyKey(ctx, abandoned, expiredBefore)
+	assert.True(t, reserved)
+}
+
+func TestStorage_IdempotencyKeyCompletedWithChange(t *testing.T) {
+	// Arrange
+	s, _ := New()
+	ctx := context.Background()
+	id, _ := s.UserRegister(ctx, entity.User{Login: "user1", Password: "hash"})
+	user := entity.User{ID: id}
+	s.AddOrder(ctx, entity.Order{Number: "12345678903"}, user)
+	s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "12345678903", Status: entity.StatusProcessed, Accrual: entity.NewMoney(100, 0)})
+	now := time.Now()
+	expiredBefore := now.Add(-entity.IdempotencyKeyLifeTime)
+	key := entity.IdempotencyKey{UserID: id, Key: "key1", Fingerprint: "withdraw", CreatedAt: now, LockedUntil: now.Add(time.Minute)}
+	s.ReserveIdempotencyKey(ctx, key, expiredBefore)
+
+	// Act
+	// the request crashes before the middleware completes the key
+	requestCtx := entity.WithIdempotencyStatus(entity.WithIdempotencyKey(ctx, key), http.StatusOK)
+	err := s.WithdrawBalance(requestCtx, entity.BalanceUpdate{Order: "2377225624", Sum: entity.NewMoney(10, 0)}, user)
+
+	// Assert
+	assert.NoError(t, err)
+	retry := key
+	retry.CreatedAt = now.Add(2 * entity.IdempotencyLockTimeout)
+	stored, reserved, _ := s.ReserveIdempotencyKey(ctx, retry, expiredBefore)
+	assert.False(t, reserved, "the retry must not withdraw again")
+	assert.Equal(t, http.StatusOK, stored.StatusCode)
+}
@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// login attempts
+func (s *Storage) GetLoginAttempts(ctx context.Context, key string) (entity.LoginAttempts, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.loginAttempts[key], nil
+}
+
+// count a failure, failures before forgetBefore start over
+func (s *Storage) AddFailedLogin(ctx context.Context, key string, at, forgetBefore time.Time) (entity.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts := s.loginAttempts[key]
+	if attempts.LastFailureAt.Before(forgetBefore) {
+		attempts.Failures = 0
+	}
+	attempts.Failures++
+	attempts.LastFailureAt = at
+
+	if err := s.audit(ctx, entity.AuditLoginFailed, entity.AuditTargetLoginAttempts(key), nil, map[string]any{"failures": attempts.Failures}); err != nil {
+		return s.loginAttempts[key], err
+	}
+	s.loginAttempts[key] = attempts
+
+	return attempts, nil
+}
+
+func (s *Storage) ResetLoginAttempts(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.loginAttempts, key)
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_LoginAttempts(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	now := time.Now()
+	lockout := 15 * time.Minute
+
+	// Arrange
+	s.AddFailedLogin(ctx, "user1|127.0.0.1", now.Add(-time.Hour), now.Add(-time.Hour-lockout))
+	s.AddFailedLogin(ctx, "user1|127.0.0.1", now.Add(-time.Hour), now.Add(-time.Hour-lockout))
+
+	// Act
+	attempts, err := s.AddFailedLogin(ctx, "user1|127.0.0.1", now, now.Add(-lockout))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempts.Failures, "failures older than the lockout are forgotten")
+
+	attempts, _ = s.AddFailedLogin(ctx, "user1|127.0.0.1", now, now.Add(-lockout))
+	assert.Equal(t, 2, attempts.Failures)
+
+	got, _ := s.GetLoginAttempts(ctx, "user1|127.0.0.1")
+	assert.Equal(t, attempts, got)
+	other, _ := s.GetLoginAttempts(ctx, "user1|10.0.0.1")
+	assert.Zero(t, other.Failures)
+
+	assert.NoError(t, s.ResetLoginAttempts(ctx, "user1|127.0.0.1"))
+	got, _ = s.GetLoginAttempts(ctx, "user1|127.0.0.1")
+	assert.Zero(t, got.Failures)
+}
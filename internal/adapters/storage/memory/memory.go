@@ -0,0 +1,506 @@
+// in-memory storage, used for tests and local demos without a database
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+var (
+	ErrOrderNotFound    = entity.ErrOrderNotFound
+	ErrBalanceNotFound  = entity.ErrBalanceNotFound
+	ErrSessionNotUnique = errors.New("refresh token hash not unique")
+)
+
+// row of the users table
+type user struct {
+	id       int64
+	login    string
+	password string
+	email    string
+	role     entity.Role
+	blocked  bool
+}
+
+// row of the orders table
+type order struct {
+	userID int64
+	entity.Order
+	// lease of the replica polling the order
+	claimedBy  string
+	leaseUntil time.Time
+}
+
+// row of the withdrawals table
+type withdrawal struct {
+	userID int64
+	entity.BalanceUpdate
+}
+
+type Storage struct {
+	mu sync.RWMutex
+
+	users map[string]*user
+	// cached projection of the ledger
+	balances map[int64]*entity.Balance
+	ledger   []entity.LedgerTransaction
+	// point lots in insertion order
+	pointLots []*entity.PointLot
+	// points each debit took from the lots, by ledger transaction id
+	pointLotConsumptions map[int64][]entity.PointLotConsumption
+
+	// orders are kept in insertion order, index points into it
+	orders     []*order
+	orderIndex map[string]*order
+
+	// withdrawals are kept in insertion order, index by number
+	withdrawals     []*withdrawal
+	withdrawalIndex map[string]*withdrawal
+
+	// sessions by id - 1, index by refresh token hash
+	sessions     []*entity.Session
+	sessionIndex map[string]*entity.Session
+
+	// failed logins by entity.LoginAttemptsKey
+	loginAttempts map[string]entity.LoginAttempts
+
+	// password reset tokens by hash
+	passwordResets map[string]*entity.PasswordResetToken
+
+	// two-factor auth by user id, recovery codes by user id and hash,
+	// login challenges by hash
+	twoFactors      map[int64]*entity.TwoFactor
+	recoveryCodes   map[int64]map[string]bool
+	loginChallenges map[string]*entity.LoginChallenge
+
+	// append-only, ids are positions in the slice
+	auditEvents []entity.AuditEvent
+
+	// results of requests by user id and key
+	idempotencyKeys map[idempotencyIndex]entity.IdempotencyKey
+
+	lastUserID int64
+}
+
+func New() (*Storage, error) {
+	return &Storage{
+		users:           make(map[string]*user),
+		balances:        make(map[int64]*entity.Balance),
+		orderIndex:      make(map[string]*order),
+		withdrawalIndex: make(map[string]*withdrawal),
+
+		pointLotConsumptions: make(map[int64][]entity.PointLotConsumption),
+		sessionIndex:         make(map[string]*entity.Session),
+		loginAttempts:        make(map[string]entity.LoginAttempts),
+		passwordResets:       make(map[string]*entity.PasswordResetToken),
+		twoFactors:           make(map[int64]*entity.TwoFactor),
+		recoveryCodes:        make(map[int64]map[string]bool),
+		loginChallenges:      make(map[string]*entity.LoginChallenge),
+		idempotencyKeys:      make(map[idempotencyIndex]entity.IdempotencyKey),
+	}, nil
+}
+
+// add user or return ErrUserLoginNotUnique
+func (s *Storage) UserRegister(ctx context.Context, userFromReq entity.User) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userFromReq.Login]; ok {
+		return 0, entity.ErrUserLoginNotUnique
+	}
+
+	s.lastUserID++
+	s.users[userFromReq.Login] = &user{
+		id:       s.lastUserID,
+		login:    userFromReq.Login,
+		password: userFromReq.Password,
+		email:    userFromReq.Email,
+		role:     entity.RoleUser,
+	}
+	s.balances[s.lastUserID] = &entity.Balance{}
+
+	if err := s.audit(ctx, entity.AuditUserRegistered, entity.AuditTargetUser(s.lastUserID), nil, map[string]any{"login": userFromReq.Login, "email": userFromReq.Email}); err != nil {
+		return 0, err
+	}
+
+	return s.lastUserID, nil
+}
+
+// user with the password hash, looked up by id or by login when id is not set,
+// returns ErrUserLoginUnauthorized for unknown users
+func (s *Storage) GetUserCredentials(ctx context.Context, userFromReq entity.User) (entity.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.findUser(userFromReq)
+	if !ok {
+		return entity.User{}, entity.ErrUserLoginUnauthorized
+	}
+
+	return entity.User{ID: u.id, Login: u.login, Password: u.password, Email: u.email, Role: u.role, Blocked: u.blocked}, nil
+}
+func (s *Storage) SetUserPassword(ctx context.Context, user entity.User, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.findUser(entity.User{ID: user.ID})
+	if !ok {
+		return entity.ErrUserLoginUnauthorized
+	}
+	u.password = passwordHash
+
+	return nil
+}
+
+// caller must hold the lock
+func (s *Storage) findUser(userFromReq entity.User) (*user, bool) {
+	if userFromReq.ID == 0 {
+		u, ok := s.users[userFromReq.Login]
+		return u, ok
+	}
+	for _, u := range s.users {
+		if u.id == userFromReq.ID {
+			return u, true
+		}
+	}
+
+	return nil, false
+}
+func (s *Storage) GetUser(ctx context.Context, userFromReq entity.User) (entity.User, error) {
+	var userFromStorage entity.User
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[userFromReq.Login]
+	if !ok {
+		return userFromStorage, entity.ErrUserNotFound
+	}
+	userFromStorage.ID = u.id
+	userFromStorage.Role = u.role
+
+	return userFromStorage, nil
+}
+
+// orders
+func (s *Storage) AddOrder(ctx context.Context, newOrder entity.Order, userFromReq entity.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// an order with this number already exists
+	if existingOrder, ok := s.orderIndex[newOrder.Number]; ok {
+		if existingOrder.userID == userFromReq.ID {
+			return entity.ErrOrderAlreadyUploaded
+		}
+		return entity.ErrOrderAlreadyUploadedAnotherUser
+	}
+
+	s.insertOrder(&order{
+		userID: userFromReq.ID,
+		Order: entity.Order{
+			Number:     newOrder.Number,
+			Status:     entity.StatusNew,
+			UploadedAt: time.Now(),
+		},
+	})
+	s.completeRequestIdempotencyKey(ctx)
+
+	return nil
+}
+func (s *Storage) GetAllOrders(ctx context.Context, userFromReq entity.User, filter entity.OrderFilter) ([]entity.Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var orders []entity.Order
+	for i := len(s.orders) - 1; i >= 0 && !filter.Full(len(orders)); i-- {
+		o := s.orders[i]
+		if o.userID == userFromReq.ID && filter.Match(o.ID, string(o.Status), o.UploadedAt) {
+			orders = append(orders, o.Order)
+		}
+	}
+
+	if len(orders) == 0 {
+		return nil, entity.ErrNoContent
+	}
+
+	return orders, nil
+}
+
+// Lease the oldest orders that are not final and not leased by another replica.
+// The owner renews its own leases
+func (s *Storage) ClaimNotProcessedOrders(ctx context.Context, claim entity.OrderClaim) ([]entity.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var orders []entity.Order
+	for _, o := range s.orders {
+		if len(orders) == claim.Limit {
+			break
+		}
+		if o.Status.IsFinal() {
+			continue
+		}
+		if o.claimedBy != "" && o.claimedBy != claim.Owner && !o.leaseUntil.Before(claim.ClaimedAt) {
+			continue
+		}
+		o.claimedBy = claim.Owner
+		o.leaseUntil = claim.LeaseUntil
+		orders = append(orders, o.Order)
+	}
+
+	if len(orders) == 0 {
+		return nil, entity.ErrNoContent
+	}
+
+	return orders, nil
+}
+func (s *Storage) SetOrderStatusAndAccrual(ctx context.Context, newOrder entity.Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orderIndex[newOrder.Number]
+	if !ok {
+		return ErrOrderNotFound
+	}
+	// a final order is not updated, so a repeated reply does not credit it twice
+	changed, err := o.Status.TransitionTo(newOrder.Status)
+	if err != nil || !changed {
+		return err
+	}
+
+	if newOrder.Accrual > 0 {
+		if err := s.postLedgerTransaction(entity.NewAccrualTransaction(o.userID, o.Number, newOrder.Accrual)); err != nil {
+			return err
+		}
+	}
+	o.Status = newOrder.Status
+	o.Accrual = newOrder.Accrual
+	// the reply ends the lease, the next poll claims the order again if it is not final
+	o.claimedBy = ""
+	o.leaseUntil = time.Time{}
+	// the estimate is held until the order is final, then the accrual replaces it
+	o.Pending = 0
+	if newOrder.Status.IsPending() {
+		o.Pending = newOrder.Pending
+	}
+
+	return nil
+}
+
+// balance
+func (s *Storage) GetBalance(ctx context.Context, userFromReq entity.User) (entity.Balance, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if balance, ok := s.balances[userFromReq.ID]; ok {
+		return *balance, nil
+	}
+
+	return entity.Balance{}, nil
+}
+
+// accrual estimates of the user's orders, zero for final orders
+func (s *Storage) GetPendingAccrual(ctx context.Context, userFromReq entity.User) (entity.Money, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var pending entity.Money
+	for _, o := range s.orders {
+		if o.userID == userFromReq.ID {
+			pending += o.Pending
+		}
+	}
+
+	return pending, nil
+}
+func (s *Storage) WithdrawBalance(ctx context.Context, balanceUpdate entity.BalanceUpdate, userFromReq entity.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	balance, ok := s.balances[userFromReq.ID]
+	if !ok {
+		return ErrBalanceNotFound
+	}
+
+	if balance.Current < balanceUpdate.Sum {
+		return entity.ErrInsufficientBalance
+	}
+
+	if _, ok := s.withdrawalIndex[balanceUpdate.Order]; ok {
+		return entity.ErrWithdrawalNotUnique
+	}
+
+	before := map[string]any{"current": balance.Current}
+	if err := s.postLedgerTransaction(entity.NewWithdrawalTransaction(userFromReq.ID, balanceUpdate.Order, balanceUpdate.Sum)); err != nil {
+		return err
+	}
+
+	after := map[string]any{"current": balance.Current, "order": balanceUpdate.Order, "sum": balanceUpdate.Sum}
+	if err := s.audit(ctx, entity.AuditBalanceWithdrawn, entity.AuditTargetUser(userFromReq.ID), before, after); err != nil {
+		return err
+	}
+
+	w := &withdrawal{
+		userID: userFromReq.ID,
+		BalanceUpdate: entity.BalanceUpdate{
+			ID:         int64(len(s.withdrawals) + 1),
+			Order:      balanceUpdate.Order,
+			Sum:        balanceUpdate.Sum,
+			UploadedAt: time.Now(),
+			Status:     entity.WithdrawalProcessed,
+		},
+	}
+	s.withdrawals = append(s.withdrawals, w)
+	s.withdrawalIndex[w.Order] = w
+	s.completeRequestIdempotencyKey(ctx)
+
+	return nil
+}
+
+// Withdrawals
+func (s *Storage) Withdrawals(ctx context.Context, userFromReq entity.User, filter entity.OrderFilter) ([]entity.BalanceUpdate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var balances []entity.BalanceUpdate
+	for i := len(s.withdrawals) - 1; i >= 0 && !filter.Full(len(balances)); i-- {
+		w := s.withdrawals[i]
+		if w.userID == userFromReq.ID && filter.Match(w.ID, w.Status, w.UploadedAt) {
+			balances = append(balances, w.BalanceUpdate)
+		}
+	}
+
+	if len(balances) == 0 {
+		return nil, entity.ErrNoContent
+	}
+
+	return balances, nil
+}
+
+// Credit the sum of a withdrawal back to its user.
+// A withdrawal is reversed at most once
+func (s *Storage) ReverseWithdrawal(ctx context.Context, number string) (entity.BalanceUpdate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.withdrawalIndex[number]
+	if !ok {
+		return entity.BalanceUpdate{}, entity.ErrWithdrawalNotFound
+	}
+	if w.Status == entity.WithdrawalReversed {
+		return w.BalanceUpdate, entity.ErrWithdrawalAlreadyReversed
+	}
+	balance, ok := s.balances[w.userID]
+	if !ok {
+		return w.BalanceUpdate, ErrBalanceNotFound
+	}
+
+	// the points go back to the lots they were spent from
+	before := *balance
+	reversal := entity.NewReversalTransaction(w.userID, w.Order, w.Sum)
+	reversal.Restores = s.withdrawalTransactionID(w)
+	reversal.EarnedAt = w.UploadedAt
+	if err := s.postLedgerTransaction(reversal); err != nil {
+		return w.BalanceUpdate, err
+	}
+
+	after := map[string]any{"current": balance.Current, "withdrawn": balance.Withdrawn, "order": w.Order, "sum": w.Sum}
+	if err := s.audit(ctx, entity.AuditBalanceReversed, entity.AuditTargetUser(w.userID), before, after); err != nil {
+		return w.BalanceUpdate, err
+	}
+	reversedAt := time.Now()
+	w.Status = entity.WithdrawalReversed
+	w.ReversedAt = &reversedAt
+
+	return w.BalanceUpdate, nil
+}
+
+// ledger transaction that debited the withdrawal, caller must hold the lock
+func (s *Storage) withdrawalTransactionID(w *withdrawal) int64 {
+	for i := len(s.ledger) - 1; i >= 0; i-- {
+		t := s.ledger[i]
+		if t.Kind == entity.LedgerWithdrawal && t.UserID == w.userID && t.OrderNumber == w.Order {
+			return t.ID
+		}
+	}
+
+	return 0
+}
+
+// must be called with the write lock held
+func (s *Storage) insertOrder(o *order) {
+	o.ID = int64(len(s.orders) + 1)
+	s.orders = append(s.orders, o)
+	s.orderIndex[o.Number] = o
+}
+
+// append balanced entries and update the cached balance,
+// must be called with the write lock held
+func (s *Storage) postLedgerTransaction(transaction entity.LedgerTransaction) error {
+	if err := transaction.Validate(); err != nil {
+		return err
+	}
+	balance, ok := s.balances[transaction.UserID]
+	if !ok {
+		return ErrBalanceNotFound
+	}
+
+	transaction.ID = int64(len(s.ledger) + 1)
+	transaction.CreatedAt = time.Now()
+	for i := range transaction.Entries {
+		transaction.Entries[i].TransactionID = transaction.ID
+	}
+
+	delta := transaction.BalanceDelta(transaction.UserID)
+	balance.Current += delta.Current
+	balance.Withdrawn += delta.Withdrawn
+	transaction.BalanceAfter = balance.Current
+	s.ledger = append(s.ledger, transaction)
+
+	// point lots follow the current account
+	if delta.Current > 0 && transaction.Restores != 0 {
+		s.restorePointLots(transaction, delta.Current)
+	} else if delta.Current > 0 {
+		s.addPointLot(transaction, delta.Current)
+	}
+	if delta.Current < 0 {
+		s.consumePointLots(transaction, -delta.Current)
+	}
+
+	return nil
+}
+
+// transaction history with the running balance, newest first
+func (s *Storage) GetTransactions(ctx context.Context, userFromReq entity.User, filter entity.TransactionFilter) ([]entity.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// the balance is stored on the transaction when it is posted
+	var transactions []entity.Transaction
+	for i := len(s.ledger) - 1; i >= 0 && !filter.Full(len(transactions)); i-- {
+		if s.ledger[i].UserID != userFromReq.ID {
+			continue
+		}
+		transaction := entity.Transaction{
+			ID:        s.ledger[i].ID,
+			Type:      s.ledger[i].Kind,
+			Amount:    s.ledger[i].BalanceDelta(userFromReq.ID).Current,
+			Order:     s.ledger[i].OrderNumber,
+			Balance:   s.ledger[i].BalanceAfter,
+			CreatedAt: s.ledger[i].CreatedAt,
+		}
+		if filter.Match(transaction) {
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	if len(transactions) == 0 {
+		return nil, entity.ErrNoContent
+	}
+
+	return transactions, nil
+}
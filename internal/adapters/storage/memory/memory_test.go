@@ -0,0 +1,674 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newUser(t *testing.T, s *Storage, login, password string) entity.User {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := s.UserRegister(context.Background(), entity.User{Login: login, Password: string(hashedPassword)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entity.User{ID: userID, Login: login}
+}
+
+func TestStorage_UserRegister(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		user entity.User
+		want int64
+		err  error
+	}{
+		{
+			name: "positive",
+			user: entity.User{Login: "user1", Password: "root"},
+			want: 1,
+		},
+		{
+			name: "positive - second user",
+			user: entity.User{Login: "user2", Password: "root"},
+			want: 2,
+		},
+		{
+			name: "negative - login not unique",
+			user: entity.User{Login: "user1", Password: "root"},
+			err:  entity.ErrUserLoginNotUnique,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			userID, err := s.UserRegister(ctx, tt.user)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, userID)
+		})
+	}
+}
+
+func TestStorage_GetUserCredentials(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+
+	tests := []struct {
+		name string
+		user entity.User
+		err  error
+	}{
+		{
+			name: "positive - by login",
+			user: entity.User{Login: "user1"},
+		},
+		{
+			name: "positive - by id",
+			user: entity.User{ID: user.ID},
+		},
+		{
+			name: "negative - unknown login",
+			user: entity.User{Login: "user2"},
+			err:  entity.ErrUserLoginUnauthorized,
+		},
+		{
+			name: "negative - unknown id",
+			user: entity.User{ID: user.ID + 1},
+			err:  entity.ErrUserLoginUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got, err := s.GetUserCredentials(ctx, tt.user)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err == nil {
+				assert.Equal(t, user.ID, got.ID)
+				assert.Equal(t, "user1", got.Login)
+				assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(got.Password), []byte("root")))
+			}
+		})
+	}
+}
+
+func TestStorage_GetUser(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+
+	// Act
+	got, err := s.GetUser(ctx, entity.User{Login: "user1"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, entity.User{ID: user.ID, Role: entity.RoleUser}, got)
+	_, err = s.GetUser(ctx, entity.User{Login: "user2"})
+	assert.ErrorIs(t, err, entity.ErrUserNotFound)
+}
+
+func TestStorage_SetUserPassword(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+
+	// Act
+	err := s.SetUserPassword(ctx, user, "$argon2id$new")
+
+	// Assert
+	assert.NoError(t, err)
+	got, _ := s.GetUserCredentials(ctx, entity.User{Login: "user1"})
+	assert.Equal(t, "$argon2id$new", got.Password)
+	assert.ErrorIs(t, s.SetUserPassword(ctx, entity.User{ID: 42}, "x"), entity.ErrUserLoginUnauthorized)
+}
+
+func TestStorage_AddOrder(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user1 := newUser(t, s, "user1", "root")
+	user2 := newUser(t, s, "user2", "root")
+
+	tests := []struct {
+		name  string
+		order entity.Order
+		user  entity.User
+		err   error
+	}{
+		{
+			name:  "positive",
+			order: entity.Order{Number: "9278923470"},
+			user:  user1,
+		},
+		{
+			name:  "negative - already uploaded",
+			order: entity.Order{Number: "9278923470"},
+			user:  user1,
+			err:   entity.ErrOrderAlreadyUploaded,
+		},
+		{
+			name:  "negative - already uploaded another user",
+			order: entity.Order{Number: "9278923470"},
+			user:  user2,
+			err:   entity.ErrOrderAlreadyUploadedAnotherUser,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := s.AddOrder(ctx, tt.order, tt.user)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestStorage_GetAllOrders(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+
+	// Assert
+	_, err := s.GetAllOrders(ctx, user, entity.OrderFilter{})
+	assert.ErrorIs(t, err, entity.ErrNoContent)
+
+	// Arrange
+	for _, number := range []string{"9278923470", "12345678903"} {
+		if err := s.AddOrder(ctx, entity.Order{Number: number}, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Act
+	orders, err := s.GetAllOrders(ctx, user, entity.OrderFilter{})
+
+	// Assert
+	assert.NoError(t, err)
+	if assert.Len(t, orders, 2) {
+		assert.Equal(t, "12345678903", orders[0].Number)
+		assert.Equal(t, entity.StatusNew, orders[1].Status)
+	}
+
+	// Act
+	page, err := s.GetAllOrders(ctx, user, entity.OrderFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: 1}}})
+	assert.NoError(t, err)
+	nextPage, err := s.GetAllOrders(ctx, user, entity.OrderFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: 1, After: page[0].ID}}})
+	assert.NoError(t, err)
+	_, errStatus := s.GetAllOrders(ctx, user, entity.OrderFilter{Statuses: []string{string(entity.StatusProcessed)}})
+
+	// Assert
+	assert.Equal(t, []string{"12345678903", "9278923470"}, []string{page[0].Number, nextPage[0].Number})
+	assert.ErrorIs(t, errStatus, entity.ErrNoContent)
+}
+
+func TestStorage_SetOrderStatusAndAccrual(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+	if err := s.AddOrder(ctx, entity.Order{Number: "9278923470"}, user); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	notProcessed, err := s.ClaimNotProcessedOrders(ctx, newClaim("replica1"))
+	assert.NoError(t, err)
+	assert.Len(t, notProcessed, 1)
+
+	err = s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "9278923470", Status: entity.StatusProcessed, Accrual: entity.NewMoney(500, 0)})
+	assert.NoError(t, err)
+
+	// Assert
+	_, err = s.ClaimNotProcessedOrders(ctx, newClaim("replica1"))
+	assert.ErrorIs(t, err, entity.ErrNoContent)
+
+	balance, err := s.GetBalance(ctx, user)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.Balance{Current: entity.NewMoney(500, 0)}, balance)
+
+	err = s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "12345678903", Status: entity.StatusProcessed})
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+func TestStorage_WithdrawBalance(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+	if err := s.AddOrder(ctx, entity.Order{Number: "9278923470"}, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "9278923470", Status: entity.StatusProcessed, Accrual: entity.NewMoney(100, 0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, err := s.Withdrawals(ctx, user, entity.OrderFilter{})
+	assert.ErrorIs(t, err, entity.ErrNoContent)
+
+	// only 10 of 20 concurrent withdrawals of 10 may succeed
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var succeeded, insufficient int
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := s.WithdrawBalance(ctx, entity.BalanceUpdate{Order: string(rune('a' + i)), Sum: entity.NewMoney(10, 0)}, user)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch err {
+			case nil:
+				succeeded++
+			case entity.ErrInsufficientBalance:
+				insufficient++
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, 10, succeeded)
+	assert.Equal(t, 10, insufficient)
+
+	balance, err := s.GetBalance(ctx, user)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.Balance{Current: 0, Withdrawn: entity.NewMoney(100, 0)}, balance)
+
+	withdrawals, err := s.Withdrawals(ctx, user, entity.OrderFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, withdrawals, 10)
+}
+
+func TestStorage_ReverseWithdrawal(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+	if _, err := s.AdjustBalance(ctx, user, entity.BalanceAdjustment{Amount: entity.NewMoney(100, 0), Reason: "welcome"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WithdrawBalance(ctx, entity.BalanceUpdate{Order: "2377225624", Sum: entity.NewMoney(40, 0)}, user); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, unknownErr := s.ReverseWithdrawal(ctx, "79927398713")
+
+	// only one of concurrent reversals may succeed
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var reversed, alreadyReversed int
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.ReverseWithdrawal(ctx, "2377225624")
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch err {
+			case nil:
+				reversed++
+			case entity.ErrWithdrawalAlreadyReversed:
+				alreadyReversed++
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	assert.ErrorIs(t, unknownErr, entity.ErrWithdrawalNotFound)
+	assert.Equal(t, 1, reversed)
+	assert.Equal(t, 9, alreadyReversed)
+
+	balance, err := s.GetBalance(ctx, user)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.Balance{Current: entity.NewMoney(100, 0)}, balance)
+
+	withdrawals, err := s.Withdrawals(ctx, user, entity.OrderFilter{Statuses: []string{entity.WithdrawalReversed}})
+	assert.NoError(t, err)
+	assert.Len(t, withdrawals, 1)
+	assert.Equal(t, entity.WithdrawalReversed, withdrawals[0].Status)
+	assert.NotNil(t, withdrawals[0].ReversedAt)
+	_, err = s.Withdrawals(ctx, user, entity.OrderFilter{Statuses: []string{entity.WithdrawalProcessed}})
+	assert.ErrorIs(t, err, entity.ErrNoContent)
+
+	transactions, _ := s.GetTransactions(ctx, user, entity.TransactionFilter{Types: []entity.LedgerKind{entity.LedgerReversal}})
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, entity.NewMoney(40, 0), transactions[0].Amount)
+}
+
+func TestStorage_WithdrawalsAndOrdersDoNotMix(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+	if err := s.AddOrder(ctx, entity.Order{Number: "9278923470"}, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "9278923470", Status: entity.StatusProcessed, Accrual: entity.NewMoney(100, 0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	err := s.WithdrawBalance(ctx, entity.BalanceUpdate{Order: "9278923470", Sum: entity.NewMoney(10, 0)}, user)
+	errTwice := s.WithdrawBalance(ctx, entity.BalanceUpdate{Order: "9278923470", Sum: entity.NewMoney(10, 0)}, user)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.ErrorIs(t, errTwice, entity.ErrWithdrawalNotUnique)
+
+	orders, err := s.GetAllOrders(ctx, user, entity.OrderFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, orders, 1)
+	assert.Equal(t, entity.NewMoney(100, 0), orders[0].Accrual)
+
+	withdrawals, err := s.Withdrawals(ctx, user, entity.OrderFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, withdrawals, 1)
+	assert.Equal(t, entity.WithdrawalProcessed, withdrawals[0].Status)
+	assert.Equal(t, entity.NewMoney(10, 0), withdrawals[0].Sum)
+
+	// an accrual order can still be uploaded after a withdrawal with its number
+	assert.NoError(t, s.WithdrawBalance(ctx, entity.BalanceUpdate{Order: "12345678903", Sum: entity.NewMoney(10, 0)}, user))
+	assert.NoError(t, s.AddOrder(ctx, entity.Order{Number: "12345678903"}, user))
+	_, err = s.ClaimNotProcessedOrders(ctx, newClaim("replica1"))
+	assert.NoError(t, err)
+}
+
+func TestStorage_PendingAccrual(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+	for _, number := range []string{"9278923470", "12345678903"} {
+		if err := s.AddOrder(ctx, entity.Order{Number: number}, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Act
+	s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "9278923470", Status: entity.StatusRegistered, Pending: entity.NewMoney(100, 0)})
+	s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "12345678903", Status: entity.StatusProcessing, Pending: entity.NewMoney(50, 0)})
+
+	// Assert
+	pending, err := s.GetPendingAccrual(ctx, user)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.NewMoney(150, 0), pending)
+	balance, _ := s.GetBalance(ctx, user)
+	assert.Equal(t, entity.Balance{}, balance)
+
+	// final orders move the amount from pending to current
+	s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "9278923470", Status: entity.StatusProcessed, Accrual: entity.NewMoney(90, 0)})
+	s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "12345678903", Status: entity.StatusInvalid, Pending: entity.NewMoney(50, 0)})
+
+	pending, _ = s.GetPendingAccrual(ctx, user)
+	assert.Equal(t, entity.Money(0), pending)
+	balance, _ = s.GetBalance(ctx, user)
+	assert.Equal(t, entity.Balance{Current: entity.NewMoney(90, 0)}, balance)
+}
+
+// balance after any sequence of accruals and withdrawals reconciles exactly
+func TestStorage_BalanceReconcile(t *testing.T) {
+	property := func(accruals, withdrawals []uint16) bool {
+		s, _ := New()
+		ctx := context.Background()
+		user := newUser(t, s, "user1", "root")
+
+		var accrued entity.Money
+		for i, a := range accruals {
+			number := fmt.Sprintf("accrual-%d", i)
+			if err := s.AddOrder(ctx, entity.Order{Number: number}, user); err != nil {
+				return false
+			}
+			if err := s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: number, Status: entity.StatusProcessed, Accrual: entity.Money(a)}); err != nil {
+				return false
+			}
+			accrued += entity.Money(a)
+		}
+
+		var withdrawn entity.Money
+		for i, w := range withdrawals {
+			err := s.WithdrawBalance(ctx, entity.BalanceUpdate{Order: fmt.Sprintf("withdrawal-%d", i), Sum: entity.Money(w)}, user)
+			if err == nil {
+				withdrawn += entity.Money(w)
+			}
+		}
+
+		balance, err := s.GetBalance(ctx, user)
+		if err != nil {
+			return false
+		}
+		// the cached balance always matches the ledger
+		if balance != entity.ProjectBalance(user.ID, s.ledger) {
+			return false
+		}
+		return balance.Withdrawn == withdrawn && balance.Current+balance.Withdrawn == accrued && balance.Current >= 0
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 50}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStorage_GetTransactions(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+	another := newUser(t, s, "user2", "root")
+
+	// Arrange
+	for _, o := range []struct {
+		number string
+		user   entity.User
+	}{{"9278923470", user}, {"12345678903", another}, {"49927398716", user}} {
+		if err := s.AddOrder(ctx, entity.Order{Number: o.number}, o.user); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: o.number, Status: entity.StatusProcessed, Accrual: entity.NewMoney(500, 0)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.WithdrawBalance(ctx, entity.BalanceUpdate{Order: "2377225624", Sum: entity.NewMoney(100, 50)}, user); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter entity.TransactionFilter
+		want   []entity.Transaction
+		err    error
+	}{
+		{
+			name:   "all",
+			filter: entity.TransactionFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: 10}}},
+			want: []entity.Transaction{
+				{ID: 4, Type: entity.LedgerWithdrawal, Order: "2377225624", Amount: entity.NewMoney(-100, 50), Balance: entity.NewMoney(899, 50)},
+				{ID: 3, Type: entity.LedgerAccrual, Order: "49927398716", Amount: entity.NewMoney(500, 0), Balance: entity.NewMoney(1000, 0)},
+				{ID: 1, Type: entity.LedgerAccrual, Order: "9278923470", Amount: entity.NewMoney(500, 0), Balance: entity.NewMoney(500, 0)},
+			},
+		},
+		{
+			name:   "by type keeps running balance",
+			filter: entity.TransactionFilter{Types: []entity.LedgerKind{entity.LedgerAccrual}, ListFilter: entity.ListFilter{Page: entity.Page{Limit: 1}}},
+			want: []entity.Transaction{
+				{ID: 3, Type: entity.LedgerAccrual, Order: "49927398716", Amount: entity.NewMoney(500, 0), Balance: entity.NewMoney(1000, 0)},
+			},
+		},
+		{
+			name:   "next page",
+			filter: entity.TransactionFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: 10, After: 3}}},
+			want: []entity.Transaction{
+				{ID: 1, Type: entity.LedgerAccrual, Order: "9278923470", Amount: entity.NewMoney(500, 0), Balance: entity.NewMoney(500, 0)},
+			},
+		},
+		{
+			name:   "empty range",
+			filter: entity.TransactionFilter{ListFilter: entity.ListFilter{To: time.Now().Add(-time.Hour), Page: entity.Page{Limit: 10}}},
+			err:    entity.ErrNoContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			transactions, err := s.GetTransactions(ctx, user, tt.filter)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+			for i := range transactions {
+				transactions[i].CreatedAt = time.Time{}
+			}
+			assert.Equal(t, tt.want, transactions)
+		})
+	}
+}
+
+func newClaim(owner string) entity.OrderClaim {
+	now := time.Now()
+	return entity.OrderClaim{Owner: owner, ClaimedAt: now, LeaseUntil: now.Add(time.Minute), Limit: 10}
+}
+
+func TestStorage_ClaimNotProcessedOrders(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+
+	// Arrange
+	for _, number := range []string{"12345678903", "79927398713", "9278923470"} {
+		if err := s.AddOrder(ctx, entity.Order{Number: number}, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	limited := newClaim("replica1")
+	limited.Limit = 2
+
+	// Act
+	claimed, err := s.ClaimNotProcessedOrders(ctx, limited)
+	other, otherErr := s.ClaimNotProcessedOrders(ctx, newClaim("replica2"))
+	renewed, renewErr := s.ClaimNotProcessedOrders(ctx, newClaim("replica1"))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"12345678903", "79927398713"}, []string{claimed[0].Number, claimed[1].Number})
+	// each order is polled by one replica
+	assert.NoError(t, otherErr)
+	assert.Len(t, other, 1)
+	assert.Equal(t, "9278923470", other[0].Number)
+	assert.NoError(t, renewErr)
+	assert.Len(t, renewed, 2)
+
+	// a reply ends the lease
+	assert.NoError(t, s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "12345678903", Status: entity.StatusProcessing}))
+	other, err = s.ClaimNotProcessedOrders(ctx, newClaim("replica2"))
+	assert.NoError(t, err)
+	assert.Len(t, other, 2)
+
+	// a lease that ended is taken over
+	later := newClaim("replica2")
+	later.ClaimedAt = later.ClaimedAt.Add(2 * time.Minute)
+	later.LeaseUntil = later.ClaimedAt.Add(time.Minute)
+	other, err = s.ClaimNotProcessedOrders(ctx, later)
+	assert.NoError(t, err)
+	assert.Len(t, other, 3)
+	_, err = s.ClaimNotProcessedOrders(ctx, newClaim("replica1"))
+	assert.ErrorIs(t, err, entity.ErrNoContent)
+}
+
+func TestStorage_SetOrderStatusAndAccrualCreditsOnce(t *testing.T) {
+	tests := []struct {
+		name    string
+		replies []entity.Order
+		err     error
+		status  entity.OrderStatus
+		balance entity.Money
+	}{
+		{
+			name: "repeated processed reply",
+			replies: []entity.Order{
+				{Number: "9278923470", Status: entity.StatusProcessed, Accrual: entity.NewMoney(100, 0)},
+				{Number: "9278923470", Status: entity.StatusProcessed, Accrual: entity.NewMoney(100, 0)},
+			},
+			status:  entity.StatusProcessed,
+			balance: entity.NewMoney(100, 0),
+		},
+		{
+			name: "processed reply after invalid",
+			replies: []entity.Order{
+				{Number: "9278923470", Status: entity.StatusInvalid},
+				{Number: "9278923470", Status: entity.StatusProcessed, Accrual: entity.NewMoney(100, 0)},
+			},
+			err:    entity.ErrOrderStatusTransition,
+			status: entity.StatusInvalid,
+		},
+		{
+			name: "registered reply after processing",
+			replies: []entity.Order{
+				{Number: "9278923470", Status: entity.StatusProcessing, Pending: entity.NewMoney(100, 0)},
+				{Number: "9278923470", Status: entity.StatusRegistered, Pending: entity.NewMoney(100, 0)},
+			},
+			err:    entity.ErrOrderStatusTransition,
+			status: entity.StatusProcessing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := New()
+			ctx := context.Background()
+			user := newUser(t, s, "user1", "root")
+			if err := s.AddOrder(ctx, entity.Order{Number: "9278923470"}, user); err != nil {
+				t.Fatal(err)
+			}
+
+			// Act
+			assert.NoError(t, s.SetOrderStatusAndAccrual(ctx, tt.replies[0]))
+			err := s.SetOrderStatusAndAccrual(ctx, tt.replies[1])
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+			orders, _ := s.GetAllOrders(ctx, user, entity.OrderFilter{})
+			assert.Equal(t, tt.status, orders[0].Status)
+			balance, _ := s.GetBalance(ctx, user)
+			assert.Equal(t, tt.balance, balance.Current)
+		})
+	}
+}
+
+func TestStorage_SetOrderStatusAndAccrualConcurrentCreditsOnce(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+	if err := s.AddOrder(ctx, entity.Order{Number: "9278923470"}, user); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "9278923470", Status: entity.StatusProcessed, Accrual: entity.NewMoney(100, 0)})
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	balance, _ := s.GetBalance(ctx, user)
+	assert.Equal(t, entity.Balance{Current: entity.NewMoney(100, 0)}, balance)
+	transactions, _ := s.GetTransactions(ctx, user, entity.TransactionFilter{})
+	assert.Len(t, transactions, 1)
+	lots, _ := s.GetPointLots(ctx, user)
+	assert.Len(t, lots, 1)
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// password reset
+func (s *Storage) CreatePasswordReset(ctx context.Context, token entity.PasswordResetToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.passwordResets[token.TokenHash]; ok {
+		return entity.ErrResetTokenInvalid
+	}
+	token.ID = int64(len(s.passwordResets) + 1)
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	s.passwordResets[token.TokenHash] = &token
+
+	return nil
+}
+
+// use the token and set the new password in one step,
+// other unused tokens of the user stop working as well
+func (s *Storage) ResetPassword(ctx context.Context, tokenHash, passwordHash string, at time.Time) (entity.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.passwordResets[tokenHash]
+	if !ok || !token.IsUsable(at) {
+		return entity.User{}, entity.ErrResetTokenInvalid
+	}
+
+	u, ok := s.findUser(entity.User{ID: token.UserID})
+	if !ok {
+		return entity.User{}, entity.ErrResetTokenInvalid
+	}
+	u.password = passwordHash
+
+	for _, t := range s.passwordResets {
+		if t.UserID == token.UserID && t.UsedAt.IsZero() {
+			t.UsedAt = at
+		}
+	}
+
+	return entity.User{ID: u.id, Login: u.login, Email: u.email}, nil
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_ResetPassword(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	now := time.Now()
+
+	// Arrange
+	userID, _ := s.UserRegister(ctx, entity.User{Login: "user1", Password: "old", Email: "user1@example.com"})
+	s.CreatePasswordReset(ctx, entity.PasswordResetToken{UserID: userID, TokenHash: "first", ExpiresAt: now.Add(time.Hour)})
+	s.CreatePasswordReset(ctx, entity.PasswordResetToken{UserID: userID, TokenHash: "second", ExpiresAt: now.Add(time.Hour)})
+	s.CreatePasswordReset(ctx, entity.PasswordResetToken{UserID: userID, TokenHash: "expired", ExpiresAt: now.Add(-time.Minute)})
+
+	// Act
+	user, err := s.ResetPassword(ctx, "first", "new", now)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, entity.User{ID: userID, Login: "user1", Email: "user1@example.com"}, user)
+	credentials, _ := s.GetUserCredentials(ctx, entity.User{ID: userID})
+	assert.Equal(t, "new", credentials.Password)
+
+	_, err = s.ResetPassword(ctx, "first", "again", now)
+	assert.ErrorIs(t, err, entity.ErrResetTokenInvalid, "token is single-use")
+	_, err = s.ResetPassword(ctx, "second", "again", now)
+	assert.ErrorIs(t, err, entity.ErrResetTokenInvalid, "other tokens of the user stop working")
+	_, err = s.ResetPassword(ctx, "unknown", "again", now)
+	assert.ErrorIs(t, err, entity.ErrResetTokenInvalid)
+	assert.ErrorIs(t, s.CreatePasswordReset(ctx, entity.PasswordResetToken{UserID: userID, TokenHash: "first"}), entity.ErrResetTokenInvalid)
+}
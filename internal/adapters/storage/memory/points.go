@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// credited points open a lot, must be called with the write lock held
+func (s *Storage) addPointLot(transaction entity.LedgerTransaction, amount entity.Money) {
+	earnedAt := transaction.EarnedAt
+	if earnedAt.IsZero() {
+		earnedAt = transaction.CreatedAt
+	}
+	s.pointLots = append(s.pointLots, &entity.PointLot{
+		ID:          int64(len(s.pointLots) + 1),
+		UserID:      transaction.UserID,
+		OrderNumber: transaction.OrderNumber,
+		Amount:      amount,
+		Remaining:   amount,
+		EarnedAt:    earnedAt,
+	})
+}
+
+// debited points are taken from the oldest lots first,
+// must be called with the write lock held
+func (s *Storage) consumePointLots(transaction entity.LedgerTransaction, amount entity.Money) {
+	taken := entity.ConsumePointLots(s.userPointLots(transaction.UserID), amount)
+	for _, consumption := range taken {
+		s.pointLots[consumption.LotID-1].Remaining -= consumption.Amount
+	}
+	s.pointLotConsumptions[transaction.ID] = taken
+}
+
+// reversed points go back to the lots the debit took them from and keep
+// their earned_at, must be called with the write lock held
+func (s *Storage) restorePointLots(transaction entity.LedgerTransaction, amount entity.Money) {
+	restored, leftOver := entity.RestorePointLots(s.pointLotConsumptions[transaction.Restores], amount)
+	for _, consumption := range restored {
+		s.pointLots[consumption.LotID-1].Remaining += consumption.Amount
+	}
+	if leftOver > 0 {
+		s.addPointLot(transaction, leftOver)
+	}
+}
+
+// lots of the user with points left, oldest first, caller must hold the lock
+func (s *Storage) userPointLots(userID int64) []entity.PointLot {
+	var lots []entity.PointLot
+	for _, lot := range s.pointLots {
+		if lot.UserID == userID && lot.Remaining > 0 {
+			lots = append(lots, *lot)
+		}
+	}
+	sort.SliceStable(lots, func(i, j int) bool {
+		return lots[i].EarnedAt.Before(lots[j].EarnedAt)
+	})
+
+	return lots
+}
+
+// lots with points left, oldest first
+func (s *Storage) GetPointLots(ctx context.Context, user entity.User) ([]entity.PointLot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.userPointLots(user.ID), nil
+}
+
+// Write off the points of lots earned before the given time,
+// one expiry transaction per user. Returns the points written off
+func (s *Storage) ExpirePoints(ctx context.Context, earnedBefore time.Time) (entity.Money, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// users in the order their first expired lot was written
+	var userIDs []int64
+	amounts := make(map[int64]entity.Money)
+	for _, lot := range s.pointLots {
+		if lot.Remaining <= 0 || !lot.EarnedAt.Before(earnedBefore) {
+			continue
+		}
+		if _, ok := amounts[lot.UserID]; !ok {
+			userIDs = append(userIDs, lot.UserID)
+		}
+		amounts[lot.UserID] += lot.Remaining
+	}
+
+	var expired entity.Money
+	for _, userID := range userIDs {
+		balance, ok := s.balances[userID]
+		if !ok {
+			return expired, ErrBalanceNotFound
+		}
+		amount := amounts[userID]
+		if amount > balance.Current {
+			amount = balance.Current
+		}
+		if amount <= 0 {
+			continue
+		}
+
+		// the expired lots are the oldest ones, so they are consumed first
+		before := *balance
+		if err := s.postLedgerTransaction(entity.NewExpiryTransaction(userID, amount)); err != nil {
+			return expired, err
+		}
+
+		after := map[string]any{"current": balance.Current, "withdrawn": balance.Withdrawn, "expired": amount}
+		if err := s.audit(ctx, entity.AuditPointsExpired, entity.AuditTargetUser(userID), before, after); err != nil {
+			return expired, err
+		}
+		expired += amount
+	}
+
+	return expired, nil
+}
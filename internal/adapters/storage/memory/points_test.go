@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_PointLots(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+
+	// Arrange
+	id, _ := s.UserRegister(ctx, entity.User{Login: "user1", Password: "hash"})
+	user := entity.User{ID: id}
+	for _, number := range []string{"9278923470", "12345678903"} {
+		s.AddOrder(ctx, entity.Order{Number: number}, user)
+		s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: number, Status: entity.StatusProcessed, Accrual: entity.NewMoney(100, 0)})
+	}
+
+	// Act
+	err := s.WithdrawBalance(ctx, entity.BalanceUpdate{Order: "2377225624", Sum: entity.NewMoney(130, 0)}, user)
+
+	// Assert
+	assert.NoError(t, err)
+	lots, err := s.GetPointLots(ctx, user)
+	assert.NoError(t, err)
+	assert.Len(t, lots, 1)
+	assert.Equal(t, "12345678903", lots[0].OrderNumber)
+	assert.Equal(t, entity.NewMoney(70, 0), lots[0].Remaining)
+
+	// the reversed points go back to the lots they were spent from
+	earnedAt := lots[0].EarnedAt
+	_, err = s.ReverseWithdrawal(ctx, "2377225624")
+	assert.NoError(t, err)
+	lots, _ = s.GetPointLots(ctx, user)
+	assert.Len(t, lots, 2)
+	assert.Equal(t, []string{"9278923470", "12345678903"}, []string{lots[0].OrderNumber, lots[1].OrderNumber})
+	assert.Equal(t, []entity.Money{entity.NewMoney(100, 0), entity.NewMoney(100, 0)}, []entity.Money{lots[0].Remaining, lots[1].Remaining})
+	assert.Equal(t, earnedAt, lots[1].EarnedAt)
+}
+
+func TestStorage_ReverseWithdrawalKeepsExpiry(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+
+	// Arrange
+	id, _ := s.UserRegister(ctx, entity.User{Login: "user1", Password: "hash"})
+	user := entity.User{ID: id}
+	s.AddOrder(ctx, entity.Order{Number: "9278923470"}, user)
+	s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "9278923470", Status: entity.StatusProcessed, Accrual: entity.NewMoney(100, 0)})
+	// the points were earned before the cutoff and spent after it
+	time.Sleep(time.Millisecond)
+	earnedBefore := time.Now()
+	time.Sleep(time.Millisecond)
+	s.WithdrawBalance(ctx, entity.BalanceUpdate{Order: "2377225624", Sum: entity.NewMoney(100, 0)}, user)
+
+	// Act
+	_, err := s.ReverseWithdrawal(ctx, "2377225624")
+	expired, expireErr := s.ExpirePoints(ctx, earnedBefore)
+
+	// Assert
+	// withdraw and reverse do not make the points younger
+	assert.NoError(t, err)
+	assert.NoError(t, expireErr)
+	assert.Equal(t, entity.NewMoney(100, 0), expired)
+	balance, _ := s.GetBalance(ctx, user)
+	assert.Equal(t, entity.Balance{}, balance)
+}
+
+func TestStorage_ExpirePoints(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+
+	// Arrange
+	id, _ := s.UserRegister(ctx, entity.User{Login: "user1", Password: "hash"})
+	user := entity.User{ID: id}
+	s.AddOrder(ctx, entity.Order{Number: "9278923470"}, user)
+	s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "9278923470", Status: entity.StatusProcessed, Accrual: entity.NewMoney(100, 0)})
+	s.WithdrawBalance(ctx, entity.BalanceUpdate{Order: "2377225624", Sum: entity.NewMoney(30, 0)}, user)
+	earnedBefore := time.Now()
+	s.AddOrder(ctx, entity.Order{Number: "12345678903"}, user)
+	s.SetOrderStatusAndAccrual(ctx, entity.Order{Number: "12345678903", Status: entity.StatusProcessed, Accrual: entity.NewMoney(50, 0)})
+
+	// Act
+	expired, err := s.ExpirePoints(ctx, earnedBefore)
+	expiredAgain, errAgain := s.ExpirePoints(ctx, earnedBefore)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, entity.NewMoney(70, 0), expired)
+	assert.NoError(t, errAgain)
+	assert.Equal(t, entity.Money(0), expiredAgain)
+
+	balance, _ := s.GetBalance(ctx, user)
+	assert.Equal(t, entity.Balance{Current: entity.NewMoney(50, 0), Withdrawn: entity.NewMoney(30, 0)}, balance)
+	assert.Equal(t, balance, entity.ProjectBalance(id, s.ledger))
+
+	transactions, _ := s.GetTransactions(ctx, user, entity.TransactionFilter{Types: []entity.LedgerKind{entity.LedgerExpiry}})
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, entity.NewMoney(-70, 0), transactions[0].Amount)
+
+	events, _ := s.GetAuditEvents(ctx, entity.AuditFilter{Actions: []entity.AuditAction{entity.AuditPointsExpired}})
+	assert.Len(t, events, 1)
+}
@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// sessions
+func (s *Storage) CreateSession(ctx context.Context, session entity.Session) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessionIndex[session.RefreshTokenHash]; ok {
+		return 0, ErrSessionNotUnique
+	}
+
+	session.ID = int64(len(s.sessions) + 1)
+
+	if session.OpenedBy != "" {
+		if err := s.audit(ctx, session.OpenedBy, entity.AuditTargetUser(session.UserID), nil, map[string]any{"session_id": session.ID}); err != nil {
+			return 0, err
+		}
+	}
+
+	session.CreatedAt = time.Now()
+	session.LastSeenAt = session.CreatedAt
+	s.sessions = append(s.sessions, &session)
+	s.sessionIndex[session.RefreshTokenHash] = &session
+
+	return session.ID, nil
+}
+
+// swap the refresh token of an active session, the old token stops working
+func (s *Storage) RotateSession(ctx context.Context, oldHash string, next entity.Session) (entity.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessionIndex[oldHash]
+	if !ok || !session.IsActive(time.Now()) {
+		return entity.Session{}, entity.ErrSessionNotFound
+	}
+
+	if err := s.audit(ctx, entity.AuditTokenRefreshed, entity.AuditTargetUser(session.UserID), nil, map[string]any{"session_id": session.ID}); err != nil {
+		return entity.Session{}, err
+	}
+
+	delete(s.sessionIndex, oldHash)
+	session.RefreshTokenHash = next.RefreshTokenHash
+	session.ExpiresAt = next.ExpiresAt
+	session.IP = next.IP
+	session.UserAgent = next.UserAgent
+	session.LastSeenAt = time.Now()
+	s.sessionIndex[session.RefreshTokenHash] = session
+
+	return *session, nil
+}
+
+func (s *Storage) GetSession(ctx context.Context, sessionID int64) (entity.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, err := s.session(sessionID)
+	if err != nil {
+		return entity.Session{}, err
+	}
+
+	return *session, nil
+}
+
+// active sessions of the user, newest first
+func (s *Storage) GetSessions(ctx context.Context, user entity.User) ([]entity.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	var sessions []entity.Session
+	for i := len(s.sessions) - 1; i >= 0; i-- {
+		session := s.sessions[i]
+		if session.UserID != user.ID || !session.IsActive(now) {
+			continue
+		}
+		sessions = append(sessions, *session)
+	}
+
+	if len(sessions) == 0 {
+		return nil, entity.ErrNoContent
+	}
+
+	return sessions, nil
+}
+
+func (s *Storage) TouchSession(ctx context.Context, sessionID int64, seenAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.session(sessionID)
+	if err != nil {
+		return err
+	}
+	session.LastSeenAt = seenAt
+
+	return nil
+}
+
+func (s *Storage) RevokeSession(ctx context.Context, userFromReq entity.User, sessionID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.session(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userFromReq.ID || !session.RevokedAt.IsZero() {
+		return entity.ErrSessionNotFound
+	}
+	session.RevokedAt = time.Now()
+
+	return nil
+}
+
+// revoke all sessions of the user except keepSessionID
+func (s *Storage) RevokeOtherSessions(ctx context.Context, user entity.User, keepSessionID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, session := range s.sessions {
+		if session.UserID == user.ID && session.ID != keepSessionID && session.RevokedAt.IsZero() {
+			session.RevokedAt = now
+		}
+	}
+
+	return nil
+}
+
+// caller must hold the lock
+func (s *Storage) session(sessionID int64) (*entity.Session, error) {
+	if sessionID <= 0 || sessionID > int64(len(s.sessions)) {
+		return nil, entity.ErrSessionNotFound
+	}
+
+	return s.sessions[sessionID-1], nil
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_Sessions(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+	expiresAt := time.Now().Add(time.Hour)
+
+	// Arrange
+	sessionID, err := s.CreateSession(ctx, entity.Session{UserID: user.ID, RefreshTokenHash: "first", IP: "127.0.0.1", UserAgent: "phone", ExpiresAt: expiresAt})
+	assert.NoError(t, err)
+
+	// Act
+	session, err := s.RotateSession(ctx, "first", entity.Session{RefreshTokenHash: "second", IP: "10.0.0.1", UserAgent: "phone", ExpiresAt: expiresAt})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, sessionID, session.ID)
+	assert.Equal(t, user.ID, session.UserID)
+	assert.Equal(t, "10.0.0.1", session.IP)
+
+	// old refresh token is gone after rotation
+	_, err = s.RotateSession(ctx, "first", entity.Session{RefreshTokenHash: "third", ExpiresAt: expiresAt})
+	assert.ErrorIs(t, err, entity.ErrSessionNotFound)
+
+	// only the owner can revoke
+	assert.ErrorIs(t, s.RevokeSession(ctx, entity.User{ID: user.ID + 1}, sessionID), entity.ErrSessionNotFound)
+	assert.NoError(t, s.RevokeSession(ctx, user, sessionID))
+
+	session, err = s.GetSession(ctx, sessionID)
+	assert.NoError(t, err)
+	assert.False(t, session.IsActive(time.Now()))
+
+	_, err = s.RotateSession(ctx, "second", entity.Session{RefreshTokenHash: "third", ExpiresAt: expiresAt})
+	assert.ErrorIs(t, err, entity.ErrSessionNotFound)
+}
+
+func TestStorage_GetSessions(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+	other := newUser(t, s, "user2", "root")
+	expiresAt := time.Now().Add(time.Hour)
+
+	// Arrange
+	laptop, _ := s.CreateSession(ctx, entity.Session{UserID: user.ID, RefreshTokenHash: "laptop", ExpiresAt: expiresAt})
+	phone, _ := s.CreateSession(ctx, entity.Session{UserID: user.ID, RefreshTokenHash: "phone", ExpiresAt: expiresAt})
+	lost, _ := s.CreateSession(ctx, entity.Session{UserID: user.ID, RefreshTokenHash: "lost", ExpiresAt: expiresAt})
+	s.CreateSession(ctx, entity.Session{UserID: user.ID, RefreshTokenHash: "expired", ExpiresAt: time.Now().Add(-time.Hour)})
+	s.CreateSession(ctx, entity.Session{UserID: other.ID, RefreshTokenHash: "other", ExpiresAt: expiresAt})
+	assert.NoError(t, s.RevokeSession(ctx, user, lost))
+
+	seenAt := time.Now().Add(time.Minute)
+	assert.NoError(t, s.TouchSession(ctx, laptop, seenAt))
+
+	// Act
+	sessions, err := s.GetSessions(ctx, user)
+
+	// Assert
+	assert.NoError(t, err)
+	if assert.Len(t, sessions, 2) {
+		assert.Equal(t, phone, sessions[0].ID)
+		assert.Equal(t, laptop, sessions[1].ID)
+		assert.Equal(t, seenAt, sessions[1].LastSeenAt)
+	}
+
+	_, err = s.GetSessions(ctx, entity.User{ID: 42})
+	assert.ErrorIs(t, err, entity.ErrNoContent)
+}
+
+func TestStorage_RevokeOtherSessions(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := newUser(t, s, "user1", "root")
+	other := newUser(t, s, "user2", "root")
+	expiresAt := time.Now().Add(time.Hour)
+
+	// Arrange
+	current, _ := s.CreateSession(ctx, entity.Session{UserID: user.ID, RefreshTokenHash: "current", ExpiresAt: expiresAt})
+	s.CreateSession(ctx, entity.Session{UserID: user.ID, RefreshTokenHash: "phone", ExpiresAt: expiresAt})
+	s.CreateSession(ctx, entity.Session{UserID: other.ID, RefreshTokenHash: "other", ExpiresAt: expiresAt})
+
+	// Act
+	err := s.RevokeOtherSessions(ctx, user, current)
+
+	// Assert
+	assert.NoError(t, err)
+	sessions, _ := s.GetSessions(ctx, user)
+	if assert.Len(t, sessions, 1) {
+		assert.Equal(t, current, sessions[0].ID)
+	}
+	sessions, _ = s.GetSessions(ctx, other)
+	assert.Len(t, sessions, 1)
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// two-factor auth, users without totp get the zero value
+func (s *Storage) GetTwoFactor(ctx context.Context, user entity.User) (entity.TwoFactor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	twoFactor, ok := s.twoFactors[user.ID]
+	if !ok {
+		return entity.TwoFactor{}, nil
+	}
+
+	return *twoFactor, nil
+}
+
+// set a new secret until the enrolment is confirmed
+func (s *Storage) SetTwoFactorSecret(ctx context.Context, user entity.User, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if twoFactor, ok := s.twoFactors[user.ID]; ok && twoFactor.Enabled {
+		return entity.ErrTwoFactorAlreadyEnabled
+	}
+	s.twoFactors[user.ID] = &entity.TwoFactor{Secret: secret}
+
+	return nil
+}
+
+// confirm the enrolment and replace the recovery codes
+func (s *Storage) EnableTwoFactor(ctx context.Context, user entity.User, step int64, recoveryCodeHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	twoFactor, ok := s.twoFactors[user.ID]
+	if !ok {
+		return entity.ErrTwoFactorNotEnrolled
+	}
+	if twoFactor.Enabled {
+		return entity.ErrTwoFactorAlreadyEnabled
+	}
+	twoFactor.Enabled = true
+	twoFactor.LastStep = step
+
+	codes := make(map[string]bool, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		codes[hash] = false
+	}
+	s.recoveryCodes[user.ID] = codes
+
+	return nil
+}
+
+// accept a code of the step only once and never an older one
+func (s *Storage) UseTOTPStep(ctx context.Context, user entity.User, step int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	twoFactor, ok := s.twoFactors[user.ID]
+	if !ok || !twoFactor.Enabled || step <= twoFactor.LastStep {
+		return entity.ErrInvalidTOTPCode
+	}
+	twoFactor.LastStep = step
+
+	return nil
+}
+
+func (s *Storage) UseRecoveryCode(ctx context.Context, user entity.User, codeHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	used, ok := s.recoveryCodes[user.ID][codeHash]
+	if !ok || used {
+		return entity.ErrInvalidTOTPCode
+	}
+	s.recoveryCodes[user.ID][codeHash] = true
+
+	return nil
+}
+
+// login challenges
+func (s *Storage) CreateLoginChallenge(ctx context.Context, challenge entity.LoginChallenge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.loginChallenges[challenge.TokenHash]; ok {
+		return entity.ErrLoginChallengeInvalid
+	}
+	challenge.ID = int64(len(s.loginChallenges) + 1)
+	challenge.Token = ""
+	s.loginChallenges[challenge.TokenHash] = &challenge
+
+	return nil
+}
+
+func (s *Storage) GetLoginChallenge(ctx context.Context, tokenHash string) (entity.LoginChallenge, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	challenge, ok := s.loginChallenges[tokenHash]
+	if !ok {
+		return entity.LoginChallenge{}, entity.ErrLoginChallengeInvalid
+	}
+
+	return *challenge, nil
+}
+
+// only one of concurrent logins with the same challenge completes
+func (s *Storage) UseLoginChallenge(ctx context.Context, challengeID int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, challenge := range s.loginChallenges {
+		if challenge.ID != challengeID {
+			continue
+		}
+		if !challenge.UsedAt.IsZero() {
+			return entity.ErrLoginChallengeInvalid
+		}
+		challenge.UsedAt = at
+		return nil
+	}
+
+	return entity.ErrLoginChallengeInvalid
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_TwoFactor(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	user := entity.User{ID: 1}
+
+	// Arrange
+	twoFactor, err := s.GetTwoFactor(ctx, user)
+	assert.NoError(t, err)
+	assert.False(t, twoFactor.Enabled)
+	assert.NoError(t, s.SetTwoFactorSecret(ctx, user, "first"))
+	assert.NoError(t, s.SetTwoFactorSecret(ctx, user, "second"), "secret can change until confirmed")
+
+	// Act
+	err = s.EnableTwoFactor(ctx, user, 10, []string{"code1", "code2"})
+
+	// Assert
+	assert.NoError(t, err)
+	twoFactor, _ = s.GetTwoFactor(ctx, user)
+	assert.Equal(t, entity.TwoFactor{Secret: "second", Enabled: true, LastStep: 10}, twoFactor)
+	assert.ErrorIs(t, s.SetTwoFactorSecret(ctx, user, "third"), entity.ErrTwoFactorAlreadyEnabled)
+
+	assert.ErrorIs(t, s.UseTOTPStep(ctx, user, 10), entity.ErrInvalidTOTPCode, "code of the step is used")
+	assert.NoError(t, s.UseTOTPStep(ctx, user, 11))
+	assert.ErrorIs(t, s.UseTOTPStep(ctx, user, 9), entity.ErrInvalidTOTPCode, "older step")
+
+	assert.NoError(t, s.UseRecoveryCode(ctx, user, "code1"))
+	assert.ErrorIs(t, s.UseRecoveryCode(ctx, user, "code1"), entity.ErrInvalidTOTPCode)
+	assert.ErrorIs(t, s.UseRecoveryCode(ctx, entity.User{ID: 2}, "code2"), entity.ErrInvalidTOTPCode)
+}
+
+func TestStorage_LoginChallenge(t *testing.T) {
+	s, _ := New()
+	ctx := context.Background()
+	now := time.Now()
+
+	// Arrange
+	assert.NoError(t, s.CreateLoginChallenge(ctx, entity.LoginChallenge{UserID: 1, TokenHash: "hash", ExpiresAt: now.Add(time.Minute)}))
+
+	// Act
+	challenge, err := s.GetLoginChallenge(ctx, "hash")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), challenge.UserID)
+	assert.True(t, challenge.IsUsable(now))
+	assert.NoError(t, s.UseLoginChallenge(ctx, challenge.ID, now))
+	assert.ErrorIs(t, s.UseLoginChallenge(ctx, challenge.ID, now), entity.ErrLoginChallengeInvalid)
+	challenge, _ = s.GetLoginChallenge(ctx, "hash")
+	assert.False(t, challenge.IsUsable(now))
+	_, err = s.GetLoginChallenge(ctx, "unknown")
+	assert.ErrorIs(t, err, entity.ErrLoginChallengeInvalid)
+}
@@ -0,0 +1,149 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// admin
+func (s *Storage) GetUserDetails(ctx context.Context, user entity.User) (entity.UserDetails, error) {
+	var details entity.UserDetails
+	err := s.db.QueryRowContext(ctx, `
+		SELECT u.id, u.login, u.email, u.role, u.blocked_at IS NOT NULL, COALESCE(t.enabled, false), b.current, b.withdrawn
+		FROM users u
+		JOIN balances b ON b.user_id = u.id
+		LEFT JOIN user_totp t ON t.user_id = u.id
+		WHERE u.login = $1`,
+		user.Login).Scan(&details.ID, &details.Login, &details.Email, &details.Role, &details.Blocked, &details.TwoFactorEnabled, &details.Balance.Current, &details.Balance.Withdrawn)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return details, entity.ErrUserNotFound
+		}
+		return details, err
+	}
+
+	return details, nil
+}
+
+// returns the user with its id
+func (s *Storage) SetUserBlocked(ctx context.Context, user entity.User, blocked bool) (entity.User, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return user, err
+	}
+	defer tx.Rollback()
+
+	var wasBlocked bool
+	if err := tx.QueryRowContext(ctx, "SELECT id, blocked_at IS NOT NULL FROM users WHERE login = $1 FOR UPDATE", user.Login).Scan(&user.ID, &wasBlocked); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, entity.ErrUserNotFound
+		}
+		return user, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET blocked_at = CASE WHEN $1 THEN COALESCE(blocked_at, now()) END WHERE id = $2", blocked, user.ID); err != nil {
+		return user, err
+	}
+
+	action := entity.AuditUserUnblocked
+	if blocked {
+		action = entity.AuditUserBlocked
+	}
+	if err := s.audit(ctx, tx, action, entity.AuditTargetUser(user.ID), map[string]any{"blocked": wasBlocked}, map[string]any{"blocked": blocked}); err != nil {
+		return user, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return user, err
+	}
+	user.Blocked = blocked
+
+	return user, nil
+}
+
+// post an adjustment to the ledger, the balance can not go below zero
+func (s *Storage) AdjustBalance(ctx context.Context, user entity.User, adjustment entity.BalanceAdjustment) (entity.Balance, error) {
+	var balance entity.Balance
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return balance, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, "SELECT b.user_id, b.current, b.withdrawn FROM balances b JOIN users u ON u.id = b.user_id WHERE u.login = $1 FOR UPDATE OF b", user.Login).Scan(&user.ID, &balance.Current, &balance.Withdrawn)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return balance, entity.ErrUserNotFound
+		}
+		return balance, err
+	}
+
+	if balance.Current+adjustment.Amount < 0 {
+		return balance, entity.ErrInsufficientBalance
+	}
+
+	if err := s.postLedgerTransaction(ctx, tx, entity.NewAdjustmentTransaction(user.ID, adjustment.Amount, adjustment.Reason)); err != nil {
+		return balance, err
+	}
+
+	before := balance
+	balance.Current += adjustment.Amount
+	after := map[string]any{"current": balance.Current, "withdrawn": balance.Withdrawn, "amount": adjustment.Amount, "reason": adjustment.Reason}
+	if err := s.audit(ctx, tx, entity.AuditBalanceAdjusted, entity.AuditTargetUser(user.ID), before, after); err != nil {
+		return before, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return before, err
+	}
+
+	return balance, nil
+}
+
+func (s *Storage) GetOrder(ctx context.Context, number string) (entity.OrderDetails, error) {
+	var order entity.OrderDetails
+	err := s.db.QueryRowContext(ctx, "SELECT o.id, o.number, o.status, o.accrual, o.pending, o.uploaded_at, o.user_id, u.login FROM orders o JOIN users u ON u.id = o.user_id WHERE o.number = $1", number).Scan(&order.ID, &order.Number, &order.Status, &order.Accrual, &order.Pending, &order.UploadedAt, &order.UserID, &order.UserLogin)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return order, entity.ErrOrderNotFound
+		}
+		return order, err
+	}
+
+	return order, nil
+}
+
+// send an unfinished order to the accrual system again
+func (s *Storage) RequeueOrder(ctx context.Context, number string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status entity.OrderStatus
+	var accrual entity.Money
+	if err := tx.QueryRowContext(ctx, "SELECT status, accrual FROM orders WHERE number = $1 FOR UPDATE", number).Scan(&status, &accrual); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.ErrOrderNotFound
+		}
+		return err
+	}
+	if !entity.CanRequeueOrder(status) {
+		return entity.ErrOrderNotRequeueable
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE orders SET status = $1, accrual = 0, pending = 0, claimed_by = NULL, lease_until = NULL WHERE number = $2", entity.StatusNew, number); err != nil {
+		return err
+	}
+
+	if err := s.audit(ctx, tx, entity.AuditOrderRequeued, entity.AuditTargetOrder(number), map[string]any{"status": status, "accrual": accrual}, map[string]any{"status": entity.StatusNew}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
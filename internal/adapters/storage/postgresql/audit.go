@@ -0,0 +1,119 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// advisory lock taken by every transaction that appends to the audit log
+const auditLockID = 0x617564
+
+// Append the event to the hash chain within the business transaction.
+// The lock keeps the chain linear until the transaction ends
+func (s *Storage) appendAuditEvent(ctx context.Context, tx *sql.Tx, event entity.AuditEvent) error {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", auditLockID); err != nil {
+		return fmt.Errorf("lock audit log: %w", err)
+	}
+
+	var prevHash string
+	err := tx.QueryRowContext(ctx, "SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1").Scan(&prevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("get last audit event: %w", err)
+	}
+	event.Seal(prevHash)
+
+	actorID := sql.NullInt64{Int64: event.ActorID, Valid: event.ActorID != 0}
+	before := sql.NullString{String: string(event.Before), Valid: event.Before != nil}
+	after := sql.NullString{String: string(event.After), Valid: event.After != nil}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO audit_events (actor_id, ip, user_agent, action, target, before_value, after_value, created_at, prev_hash, hash) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)", actorID, event.IP, event.UserAgent, event.Action, event.Target, before, after, event.CreatedAt, event.PrevHash, event.Hash); err != nil {
+		return fmt.Errorf("insert audit event: %w", err)
+	}
+
+	return nil
+}
+
+// build and append the event in one step
+func (s *Storage) audit(ctx context.Context, tx *sql.Tx, action entity.AuditAction, target string, before, after any) error {
+	event, err := entity.NewAuditEvent(ctx, action, target, before, after)
+	if err != nil {
+		return err
+	}
+
+	return s.appendAuditEvent(ctx, tx, event)
+}
+
+// audit log, newest first
+func (s *Storage) GetAuditEvents(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEvent, error) {
+	actions := make([]string, 0, len(filter.Actions))
+	for _, action := range filter.Actions {
+		actions = append(actions, string(action))
+	}
+	from := sql.NullTime{Time: filter.From, Valid: !filter.From.IsZero()}
+	to := sql.NullTime{Time: filter.To, Valid: !filter.To.IsZero()}
+
+	rows, err := s.db.QueryContext(ctx, auditEventsSelect+`
+		WHERE ($1::BIGINT = 0 OR id < $1)
+			AND ($2::BIGINT = 0 OR actor_id = $2)
+			AND ($3::TEXT = '' OR target = $3)
+			AND (cardinality($4::TEXT[]) = 0 OR action = ANY($4::TEXT[]))
+			AND ($5::TIMESTAMP IS NULL OR created_at >= $5)
+			AND ($6::TIMESTAMP IS NULL OR created_at < $6)
+		ORDER BY id DESC
+		LIMIT $7`,
+		filter.After, filter.ActorID, filter.Target, actions, from, to, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := scanAuditEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, entity.ErrNoContent
+	}
+
+	return events, nil
+}
+
+// events after the given id, oldest first, for checking the hash chain
+func (s *Storage) GetAuditChain(ctx context.Context, afterID int64, limit int) ([]entity.AuditEvent, error) {
+	rows, err := s.db.QueryContext(ctx, auditEventsSelect+" WHERE id > $1 ORDER BY id LIMIT $2", afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanAuditEvents(rows)
+}
+
+const auditEventsSelect = "SELECT id, COALESCE(actor_id, 0), ip, user_agent, action, target, before_value, after_value, created_at, prev_hash, hash FROM audit_events"
+
+func scanAuditEvents(rows *sql.Rows) ([]entity.AuditEvent, error) {
+	defer rows.Close()
+
+	var events []entity.AuditEvent
+	for rows.Next() {
+		var event entity.AuditEvent
+		var before, after sql.NullString
+		if err := rows.Scan(&event.ID, &event.ActorID, &event.IP, &event.UserAgent, &event.Action, &event.Target, &before, &after, &event.CreatedAt, &event.PrevHash, &event.Hash); err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			event.Before = []byte(before.String)
+		}
+		if after.Valid {
+			event.After = []byte(after.String)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,60 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// Take the key for a new request. Keys created before expiredBefore and
+// abandoned reservations of the same request are taken over.
+// Returns the stored key and false when the key is held by another request
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, key entity.IdempotencyKey, expiredBefore time.Time) (entity.IdempotencyKey, bool, error) {
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint, status_code, created_at, locked_until) VALUES ($1, $2, $3, 0, $4, $5)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			status_code = 0,
+			created_at = EXCLUDED.created_at,
+			locked_until = EXCLUDED.locked_until
+		WHERE idempotency_keys.created_at < $6
+			OR (idempotency_keys.status_code = 0 AND idempotency_keys.locked_until < EXCLUDED.created_at AND idempotency_keys.fingerprint = EXCLUDED.fingerprint)
+		RETURNING user_id`,
+		key.UserID, key.Key, key.Fingerprint, key.CreatedAt, key.LockedUntil, expiredBefore).Scan(&key.UserID)
+	if err == nil {
+		return key, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return key, false, err
+	}
+
+	var stored entity.IdempotencyKey
+	err = s.db.QueryRowContext(ctx, "SELECT user_id, key, fingerprint, status_code, created_at, locked_until FROM idempotency_keys WHERE user_id = $1 AND key = $2", key.UserID, key.Key).Scan(&stored.UserID, &stored.Key, &stored.Fingerprint, &stored.StatusCode, &stored.CreatedAt, &stored.LockedUntil)
+
+	return stored, false, err
+}
+
+// store the response status of the request holding the key
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE idempotency_keys SET status_code = $1 WHERE user_id = $2 AND key = $3 AND fingerprint = $4", key.StatusCode, key.UserID, key.Key, key.Fingerprint)
+	return err
+}
+
+// give the key up, the request can be retried with it
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code = 0", key.UserID, key.Key)
+	return err
+}
+
+// complete the key of the request in the transaction of its change
+func (s *Storage) completeRequestIdempotencyKey(ctx context.Context, tx *sql.Tx) error {
+	key, ok := entity.IdempotencyKeyFromContext(ctx)
+	if !ok || key.StatusCode == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, "UPDATE idempotency_keys SET status_code = $1 WHERE user_id = $2 AND key = $3 AND fingerprint = $4", key.StatusCode, key.UserID, key.Key, key.Fingerprint)
+	return err
+}
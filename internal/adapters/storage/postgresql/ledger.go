@@ -0,0 +1,114 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// write balanced ledger entries and update the cached balance,
+// must run inside the transaction of the business change
+func (s *Storage) postLedgerTransaction(ctx context.Context, tx *sql.Tx, transaction entity.LedgerTransaction) error {
+	if err := transaction.Validate(); err != nil {
+		return err
+	}
+
+	var orderNumber, reason sql.NullString
+	if transaction.OrderNumber != "" {
+		orderNumber = sql.NullString{String: transaction.OrderNumber, Valid: true}
+	}
+	if transaction.Reason != "" {
+		reason = sql.NullString{String: transaction.Reason, Valid: true}
+	}
+
+	// balances is a cached projection of the ledger,
+	// the transaction keeps the balance it left so the history needs no running sum
+	delta := transaction.BalanceDelta(transaction.UserID)
+	if err := tx.QueryRowContext(ctx, "UPDATE balances SET current = current + $1, withdrawn = withdrawn + $2 WHERE user_id = $3 RETURNING current", delta.Current, delta.Withdrawn, transaction.UserID).Scan(&transaction.BalanceAfter); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.ErrBalanceNotFound
+		}
+		return fmt.Errorf("update balance: %w", err)
+	}
+
+	var transactionID int64
+	if err := tx.QueryRowContext(ctx, "INSERT INTO ledger_transactions (kind, user_id, order_number, reason, balance_after) VALUES ($1, $2, $3, $4, $5) RETURNING id", transaction.Kind, transaction.UserID, orderNumber, reason, transaction.BalanceAfter).Scan(&transactionID); err != nil {
+		return fmt.Errorf("insert ledger transaction: %w", err)
+	}
+
+	for _, e := range transaction.Entries {
+		var userID sql.NullInt64
+		if e.UserID != 0 {
+			userID = sql.NullInt64{Int64: e.UserID, Valid: true}
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO ledger_entries (transaction_id, account, user_id, debit, credit) VALUES ($1, $2, $3, $4, $5)", transactionID, e.Account, userID, e.Debit, e.Credit); err != nil {
+			return fmt.Errorf("insert ledger entry: %w", err)
+		}
+	}
+
+	// point lots follow the current account
+	transaction.ID = transactionID
+	if delta.Current > 0 && transaction.Restores != 0 {
+		return s.restorePointLots(ctx, tx, transaction, delta.Current)
+	}
+	if delta.Current > 0 {
+		return s.addPointLot(ctx, tx, transaction, delta.Current)
+	}
+	if delta.Current < 0 {
+		return s.consumePointLots(ctx, tx, transaction, -delta.Current)
+	}
+
+	return nil
+}
+
+// transaction history with the running balance, newest first
+func (s *Storage) GetTransactions(ctx context.Context, user entity.User, filter entity.TransactionFilter) ([]entity.Transaction, error) {
+	types := make([]string, 0, len(filter.Types))
+	for _, kind := range filter.Types {
+		types = append(types, string(kind))
+	}
+	from := sql.NullTime{Time: filter.From, Valid: !filter.From.IsZero()}
+	to := sql.NullTime{Time: filter.To, Valid: !filter.To.IsZero()}
+
+	// the balance is stored on the transaction, only the entries of the page are summed
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.id, t.kind, COALESCE(t.order_number, ''),
+			(SELECT COALESCE(SUM(e.credit - e.debit), 0) FROM ledger_entries e
+				WHERE e.transaction_id = t.id AND e.account = $2 AND e.user_id = t.user_id),
+			t.balance_after, t.created_at
+		FROM ledger_transactions t
+		WHERE t.user_id = $1
+			AND ($3::BIGINT = 0 OR t.id < $3)
+			AND (cardinality($4::TEXT[]) = 0 OR t.kind = ANY($4::TEXT[]))
+			AND ($5::TIMESTAMP IS NULL OR t.created_at >= $5)
+			AND ($6::TIMESTAMP IS NULL OR t.created_at < $6)
+		ORDER BY t.id DESC
+		LIMIT $7`,
+		user.ID, entity.AccountCurrent, filter.After, types, from, to, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []entity.Transaction
+	for rows.Next() {
+		var transaction entity.Transaction
+		if err := rows.Scan(&transaction.ID, &transaction.Type, &transaction.Order, &transaction.Amount, &transaction.Balance, &transaction.CreatedAt); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(transactions) == 0 {
+		return nil, entity.ErrNoContent
+	}
+
+	return transactions, nil
+}
@@ -0,0 +1,54 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// login attempts
+func (s *Storage) GetLoginAttempts(ctx context.Context, key string) (entity.LoginAttempts, error) {
+	var attempts entity.LoginAttempts
+	err := s.db.QueryRowContext(ctx, "SELECT failures, last_failure_at FROM login_attempts WHERE key = $1", key).Scan(&attempts.Failures, &attempts.LastFailureAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return attempts, err
+	}
+
+	return attempts, nil
+}
+
+// count a failure, failures before forgetBefore start over
+func (s *Storage) AddFailedLogin(ctx context.Context, key string, at, forgetBefore time.Time) (entity.LoginAttempts, error) {
+	var attempts entity.LoginAttempts
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return attempts, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures, last_failure_at`,
+		key, at, forgetBefore).Scan(&attempts.Failures, &attempts.LastFailureAt)
+	if err != nil {
+		return attempts, err
+	}
+
+	if err := s.audit(ctx, tx, entity.AuditLoginFailed, entity.AuditTargetLoginAttempts(key), nil, map[string]any{"failures": attempts.Failures}); err != nil {
+		return attempts, err
+	}
+
+	return attempts, tx.Commit()
+}
+
+func (s *Storage) ResetLoginAttempts(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM login_attempts WHERE key = $1", key)
+	return err
+}
@@ -0,0 +1,61 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// password reset
+func (s *Storage) CreatePasswordReset(ctx context.Context, token entity.PasswordResetToken) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)", token.UserID, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return entity.ErrResetTokenInvalid
+		}
+		return err
+	}
+
+	return nil
+}
+
+// use the token and set the new password in one transaction,
+// other unused tokens of the user stop working as well
+func (s *Storage) ResetPassword(ctx context.Context, tokenHash, passwordHash string, at time.Time) (entity.User, error) {
+	var user entity.User
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return user, err
+	}
+	defer tx.Rollback()
+
+	// only one of concurrent requests with the same token gets the row
+	err = tx.QueryRowContext(ctx, "UPDATE password_reset_tokens SET used_at = $1 WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1 RETURNING user_id", at, tokenHash).Scan(&user.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, entity.ErrResetTokenInvalid
+		}
+		return user, err
+	}
+
+	if err := tx.QueryRowContext(ctx, "UPDATE users SET password = $1 WHERE id = $2 RETURNING login, email", passwordHash, user.ID).Scan(&user.Login, &user.Email); err != nil {
+		return user, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE password_reset_tokens SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL", at, user.ID); err != nil {
+		return user, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
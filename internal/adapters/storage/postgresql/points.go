@@ -0,0 +1,189 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// credited points open a lot
+func (s *Storage) addPointLot(ctx context.Context, tx *sql.Tx, transaction entity.LedgerTransaction, amount entity.Money) error {
+	orderNumber := sql.NullString{String: transaction.OrderNumber, Valid: transaction.OrderNumber != ""}
+	earnedAt := sql.NullTime{Time: transaction.EarnedAt, Valid: !transaction.EarnedAt.IsZero()}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO point_lots (user_id, order_number, amount, remaining, earned_at) VALUES ($1, $2, $3, $3, COALESCE($4, now()))", transaction.UserID, orderNumber, amount, earnedAt); err != nil {
+		return fmt.Errorf("insert point lot: %w", err)
+	}
+
+	return nil
+}
+
+// debited points are taken from the oldest lots first,
+// what is taken from each lot is kept for a reversal
+func (s *Storage) consumePointLots(ctx context.Context, tx *sql.Tx, transaction entity.LedgerTransaction, amount entity.Money) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id, remaining FROM point_lots WHERE user_id = $1 AND remaining > 0 ORDER BY earned_at, id FOR UPDATE", transaction.UserID)
+	if err != nil {
+		return fmt.Errorf("get point lots: %w", err)
+	}
+	var lots []entity.PointLot
+	for rows.Next() {
+		var lot entity.PointLot
+		if err := rows.Scan(&lot.ID, &lot.Remaining); err != nil {
+			rows.Close()
+			return err
+		}
+		lots = append(lots, lot)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, consumption := range entity.ConsumePointLots(lots, amount) {
+		if _, err := tx.ExecContext(ctx, "UPDATE point_lots SET remaining = remaining - $1 WHERE id = $2", consumption.Amount, consumption.LotID); err != nil {
+			return fmt.Errorf("update point lot: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO point_lot_consumptions (transaction_id, lot_id, amount) VALUES ($1, $2, $3)", transaction.ID, consumption.LotID, consumption.Amount); err != nil {
+			return fmt.Errorf("insert point lot consumption: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// reversed points go back to the lots the debit took them from and keep their earned_at
+func (s *Storage) restorePointLots(ctx context.Context, tx *sql.Tx, transaction entity.LedgerTransaction, amount entity.Money) error {
+	rows, err := tx.QueryContext(ctx, "SELECT lot_id, amount FROM point_lot_consumptions WHERE transaction_id = $1 ORDER BY id", transaction.Restores)
+	if err != nil {
+		return fmt.Errorf("get point lot consumptions: %w", err)
+	}
+	var taken []entity.PointLotConsumption
+	for rows.Next() {
+		var consumption entity.PointLotConsumption
+		if err := rows.Scan(&consumption.LotID, &consumption.Amount); err != nil {
+			rows.Close()
+			return err
+		}
+		taken = append(taken, consumption)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	restored, leftOver := entity.RestorePointLots(taken, amount)
+	for _, consumption := range restored {
+		if _, err := tx.ExecContext(ctx, "UPDATE point_lots SET remaining = remaining + $1 WHERE id = $2", consumption.Amount, consumption.LotID); err != nil {
+			return fmt.Errorf("restore point lot: %w", err)
+		}
+	}
+	if leftOver > 0 {
+		return s.addPointLot(ctx, tx, transaction, leftOver)
+	}
+
+	return nil
+}
+
+// lots with points left, oldest first
+func (s *Storage) GetPointLots(ctx context.Context, user entity.User) ([]entity.PointLot, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, user_id, COALESCE(order_number, ''), amount, remaining, earned_at FROM point_lots WHERE user_id = $1 AND remaining > 0 ORDER BY earned_at, id", user.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lots []entity.PointLot
+	for rows.Next() {
+		var lot entity.PointLot
+		if err := rows.Scan(&lot.ID, &lot.UserID, &lot.OrderNumber, &lot.Amount, &lot.Remaining, &lot.EarnedAt); err != nil {
+			return nil, err
+		}
+		lots = append(lots, lot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lots, nil
+}
+
+// Write off the points of lots earned before the given time,
+// one expiry transaction per user. Returns the points written off
+func (s *Storage) ExpirePoints(ctx context.Context, earnedBefore time.Time) (entity.Money, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT DISTINCT user_id FROM point_lots WHERE remaining > 0 AND earned_at < $1", earnedBefore)
+	if err != nil {
+		return 0, err
+	}
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var expired entity.Money
+	for _, userID := range userIDs {
+		amount, err := s.expireUserPoints(ctx, userID, earnedBefore)
+		if err != nil {
+			return expired, err
+		}
+		expired += amount
+	}
+
+	return expired, nil
+}
+
+func (s *Storage) expireUserPoints(ctx context.Context, userID int64, earnedBefore time.Time) (entity.Money, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// the balance lock serializes the expiry with withdrawals of the user
+	var balance entity.Balance
+	if err := tx.QueryRowContext(ctx, "SELECT current, withdrawn FROM balances WHERE user_id = $1 FOR UPDATE", userID).Scan(&balance.Current, &balance.Withdrawn); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, entity.ErrBalanceNotFound
+		}
+		return 0, err
+	}
+	var amount entity.Money
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(SUM(remaining), 0) FROM point_lots WHERE user_id = $1 AND remaining > 0 AND earned_at < $2", userID, earnedBefore).Scan(&amount); err != nil {
+		return 0, err
+	}
+	// lots and balance can only disagree for data written before the lots existed
+	if amount > balance.Current {
+		amount = balance.Current
+	}
+	if amount <= 0 {
+		return 0, nil
+	}
+
+	// the expired lots are the oldest ones, so they are consumed first
+	if err := s.postLedgerTransaction(ctx, tx, entity.NewExpiryTransaction(userID, amount)); err != nil {
+		return 0, err
+	}
+
+	after := map[string]any{"current": balance.Current - amount, "withdrawn": balance.Withdrawn, "expired": amount}
+	if err := s.audit(ctx, tx, entity.AuditPointsExpired, entity.AuditTargetUser(userID), balance, after); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return amount, nil
+}
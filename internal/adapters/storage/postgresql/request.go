@@ -4,10 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/korovindenis/go-market/internal/domain/entity"
-	"golang.org/x/crypto/bcrypt"
 
 	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -37,7 +37,7 @@ func (s *Storage) UserRegister(ctx context.Context, user entity.User) (int64, er
 	}
 
 	var userID int64
-	err = tx.QueryRowContext(ctx, "INSERT INTO users (login, password) VALUES ($1, $2) RETURNING id", user.Login, user.Password).Scan(&userID)
+	err = tx.QueryRowContext(ctx, "INSERT INTO users (login, password, email) VALUES ($1, $2, $3) RETURNING id", user.Login, user.Password, user.Email).Scan(&userID)
 	if err != nil {
 		tx.Rollback()
 
@@ -55,6 +55,11 @@ func (s *Storage) UserRegister(ctx context.Context, user entity.User) (int64, er
 		return 0, err
 	}
 
+	if err := s.audit(ctx, tx, entity.AuditUserRegistered, entity.AuditTargetUser(userID), nil, map[string]any{"login": user.Login, "email": user.Email}); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return 0, err
@@ -63,23 +68,30 @@ func (s *Storage) UserRegister(ctx context.Context, user entity.User) (int64, er
 	return userID, nil
 }
 
-// auth user or return ErrUserLoginUnauthorized
-func (s *Storage) UserLogin(ctx context.Context, user entity.User) error {
-	var userPassword string
-	if err := s.db.QueryRowContext(ctx, "SELECT password FROM users WHERE login = $1", user.Login).Scan(&userPassword); err != nil {
-		return entity.ErrUserLoginUnauthorized
-	}
-
-	// compare password
-	if err := bcrypt.CompareHashAndPassword([]byte(userPassword), []byte(user.Password)); err != nil {
-		return entity.ErrUserLoginUnauthorized
+// user with the password hash, looked up by id or by login when id is not set,
+// returns ErrUserLoginUnauthorized for unknown users
+func (s *Storage) GetUserCredentials(ctx context.Context, userFromReq entity.User) (entity.User, error) {
+	var userFromStorage entity.User
+	err := s.db.QueryRowContext(ctx, "SELECT id, login, password, email, role, blocked_at IS NOT NULL FROM users WHERE ($1::BIGINT <> 0 AND id = $1) OR ($1::BIGINT = 0 AND login = $2)", userFromReq.ID, userFromReq.Login).Scan(&userFromStorage.ID, &userFromStorage.Login, &userFromStorage.Password, &userFromStorage.Email, &userFromStorage.Role, &userFromStorage.Blocked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return userFromStorage, entity.ErrUserLoginUnauthorized
+		}
+		return userFromStorage, err
 	}
 
-	return nil
+	return userFromStorage, nil
+}
+func (s *Storage) SetUserPassword(ctx context.Context, user entity.User, passwordHash string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE users SET password = $1 WHERE id = $2", passwordHash, user.ID)
+	return err
 }
 func (s *Storage) GetUser(ctx context.Context, userFromReq entity.User) (entity.User, error) {
 	var userFromStorage entity.User
-	if err := s.db.QueryRowContext(ctx, "SELECT id FROM users WHERE login = $1", userFromReq.Login).Scan(&userFromStorage.ID); err != nil {
+	if err := s.db.QueryRowContext(ctx, "SELECT id, role FROM users WHERE login = $1", userFromReq.Login).Scan(&userFromStorage.ID, &userFromStorage.Role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return userFromStorage, entity.ErrUserNotFound
+		}
 		return userFromStorage, err
 	}
 	return userFromStorage, nil
@@ -91,10 +103,7 @@ func (s *Storage) AddOrder(ctx context.Context, order entity.Order, user entity.
 	err := s.db.QueryRowContext(ctx, "SELECT user_id FROM orders WHERE number = $1", order.Number).Scan(&existingOrderUser)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			if _, err := s.db.ExecContext(ctx, "INSERT INTO orders (number, user_id, status) VALUES ($1, $2, $3)", order.Number, user.ID, "NEW"); err != nil {
-				return err
-			}
-			return nil
+			return s.insertOrder(ctx, order, user)
 		}
 		return err
 	}
@@ -105,9 +114,36 @@ func (s *Storage) AddOrder(ctx context.Context, order entity.Order, user entity.
 	}
 	return entity.ErrOrderAlreadyUploadedAnotherUser
 }
-func (s *Storage) GetAllOrders(ctx context.Context, user entity.User) ([]entity.Order, error) {
+
+// the idempotency key of the request is completed with the order
+func (s *Storage) insertOrder(ctx context.Context, order entity.Order, user entity.User) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO orders (number, user_id, status) VALUES ($1, $2, $3)", order.Number, user.ID, entity.StatusNew); err != nil {
+		return err
+	}
+	if err := s.completeRequestIdempotencyKey(ctx, tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+func (s *Storage) GetAllOrders(ctx context.Context, user entity.User, filter entity.OrderFilter) ([]entity.Order, error) {
 	var orders []entity.Order
-	rows, err := s.db.QueryContext(ctx, "SELECT number,status,accrual,uploaded_at FROM orders WHERE user_id = $1 ORDER BY id DESC", user.ID)
+	after, statuses, from, to, limit := orderFilterArgs(filter)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id,number,status,accrual,pending,uploaded_at FROM orders
+		WHERE user_id = $1
+			AND ($2::BIGINT = 0 OR id < $2)
+			AND (cardinality($3::TEXT[]) = 0 OR status = ANY($3::TEXT[]))
+			AND ($4::TIMESTAMP IS NULL OR uploaded_at >= $4)
+			AND ($5::TIMESTAMP IS NULL OR uploaded_at < $5)
+		ORDER BY id DESC
+		LIMIT $6`, user.ID, after, statuses, from, to, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -115,7 +151,7 @@ func (s *Storage) GetAllOrders(ctx context.Context, user entity.User) ([]entity.
 
 	for rows.Next() {
 		var order entity.Order
-		err := rows.Scan(&order.Number, &order.Status, &order.Accrual, &order.UploadedAt)
+		err := rows.Scan(&order.ID, &order.Number, &order.Status, &order.Accrual, &order.Pending, &order.UploadedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -132,9 +168,23 @@ func (s *Storage) GetAllOrders(ctx context.Context, user entity.User) ([]entity.
 
 	return orders, err
 }
-func (s *Storage) GetAllNotProcessedOrders(ctx context.Context) ([]entity.Order, error) {
+
+// Lease the oldest orders that are not final and not leased by another replica.
+// The owner renews its own leases, rows locked by another claim are skipped
+func (s *Storage) ClaimNotProcessedOrders(ctx context.Context, claim entity.OrderClaim) ([]entity.Order, error) {
 	var orders []entity.Order
-	rows, err := s.db.QueryContext(ctx, "SELECT number,status,accrual,uploaded_at FROM orders WHERE status NOT IN ($1,$2)", entity.StatusInvalid, entity.StatusProcessed)
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE orders SET claimed_by = $1, lease_until = $2
+		WHERE id IN (
+			SELECT id FROM orders
+			WHERE status NOT IN ($3, $4)
+				AND (claimed_by IS NULL OR claimed_by = $1 OR lease_until < $5)
+			ORDER BY uploaded_at
+			LIMIT $6
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING number, status, accrual, uploaded_at`,
+		claim.Owner, claim.LeaseUntil, entity.StatusInvalid, entity.StatusProcessed, claim.ClaimedAt, claim.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -164,31 +214,40 @@ func (s *Storage) SetOrderStatusAndAccrual(ctx context.Context, order entity.Ord
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
+	// the lock makes concurrent replies for the order wait for this one
 	var userID int64
-	err = tx.QueryRowContext(ctx, "UPDATE orders SET status = $1, accrual = $2 WHERE number = $3 RETURNING user_id", order.Status, order.Accrual, order.Number).Scan(&userID)
-	if err != nil {
-		tx.Rollback()
-
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-			return entity.ErrUserLoginNotUnique
+	var status entity.OrderStatus
+	if err := tx.QueryRowContext(ctx, "SELECT user_id, status FROM orders WHERE number = $1 FOR UPDATE", order.Number).Scan(&userID, &status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.ErrOrderNotFound
 		}
 		return err
 	}
-
-	_, err = tx.ExecContext(ctx, "UPDATE balances SET current = current + $1 WHERE user_id = $2", order.Accrual, userID)
-	if err != nil {
-		tx.Rollback()
+	// a final order is not updated, so a repeated reply does not credit it twice
+	changed, err := status.TransitionTo(order.Status)
+	if err != nil || !changed {
 		return err
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return err
+	// the estimate is held until the order is final, then the accrual replaces it
+	if !order.Status.IsPending() {
+		order.Pending = 0
 	}
 
-	return nil
+	// the reply ends the lease, the next poll claims the order again if it is not final
+	if _, err := tx.ExecContext(ctx, "UPDATE orders SET status = $1, accrual = $2, pending = $3, claimed_by = NULL, lease_until = NULL WHERE number = $4", order.Status, order.Accrual, order.Pending, order.Number); err != nil {
+		return fmt.Errorf("update order status: %w", err)
+	}
+
+	if order.Accrual > 0 {
+		if err := s.postLedgerTransaction(ctx, tx, entity.NewAccrualTransaction(userID, order.Number, order.Accrual)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 // balance
@@ -213,6 +272,13 @@ func (s *Storage) GetBalance(ctx context.Context, user entity.User) (entity.Bala
 
 	return balance, nil
 }
+
+// accrual estimates of the user's orders, zero for final orders
+func (s *Storage) GetPendingAccrual(ctx context.Context, user entity.User) (entity.Money, error) {
+	var pending entity.Money
+	err := s.db.QueryRowContext(ctx, "SELECT COALESCE(SUM(pending), 0) FROM orders WHERE user_id = $1", user.ID).Scan(&pending)
+	return pending, err
+}
 func (s *Storage) WithdrawBalance(ctx context.Context, balance entity.BalanceUpdate, user entity.User) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -220,8 +286,11 @@ func (s *Storage) WithdrawBalance(ctx context.Context, balance entity.BalanceUpd
 	}
 	defer tx.Rollback()
 
-	var currentBalance float64
-	if err := tx.QueryRowContext(ctx, "SELECT current FROM balances WHERE id = $1 FOR UPDATE", user.ID).Scan(&currentBalance); err != nil {
+	var currentBalance entity.Money
+	if err := tx.QueryRowContext(ctx, "SELECT current FROM balances WHERE user_id = $1 FOR UPDATE", user.ID).Scan(&currentBalance); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.ErrBalanceNotFound
+		}
 		return err
 	}
 
@@ -229,11 +298,24 @@ func (s *Storage) WithdrawBalance(ctx context.Context, balance entity.BalanceUpd
 		return entity.ErrInsufficientBalance
 	}
 
-	if _, err := tx.ExecContext(ctx, "UPDATE balances SET current = current - $1, withdrawn = withdrawn + $1 WHERE id = $2", balance.Sum, user.ID); err != nil {
+	if err := s.postLedgerTransaction(ctx, tx, entity.NewWithdrawalTransaction(user.ID, balance.Order, balance.Sum)); err != nil {
 		return err
 	}
 
-	if _, err := tx.ExecContext(ctx, "INSERT INTO orders (number, sum, user_id, status) VALUES ($1, $2, $3, $4)", balance.Order, balance.Sum, user.ID, "PROCESSED"); err != nil {
+	if _, err := tx.ExecContext(ctx, "INSERT INTO withdrawals (number, sum, user_id, status) VALUES ($1, $2, $3, $4)", balance.Order, balance.Sum, user.ID, entity.WithdrawalProcessed); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return entity.ErrWithdrawalNotUnique
+		}
+		return err
+	}
+
+	before := map[string]any{"current": currentBalance}
+	after := map[string]any{"current": currentBalance - balance.Sum, "order": balance.Order, "sum": balance.Sum}
+	if err := s.audit(ctx, tx, entity.AuditBalanceWithdrawn, entity.AuditTargetUser(user.ID), before, after); err != nil {
+		return err
+	}
+	if err := s.completeRequestIdempotencyKey(ctx, tx); err != nil {
 		return err
 	}
 
@@ -246,40 +328,119 @@ func (s *Storage) WithdrawBalance(ctx context.Context, balance entity.BalanceUpd
 }
 
 // Withdrawals
-func (s *Storage) Withdrawals(ctx context.Context, user entity.User) ([]entity.BalanceUpdate, error) {
-	var orders []entity.Order
-	rows, err := s.db.QueryContext(ctx, "SELECT number,sum,uploaded_at FROM orders WHERE sum > $1 AND status = $2 AND user_id = $3 ORDER BY id DESC", 0, entity.StatusProcessed, user.ID)
+func (s *Storage) Withdrawals(ctx context.Context, user entity.User, filter entity.OrderFilter) ([]entity.BalanceUpdate, error) {
+	after, statuses, from, to, limit := orderFilterArgs(filter)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id,number,sum,status,processed_at,reversed_at FROM withdrawals
+		WHERE user_id = $1
+			AND ($2::BIGINT = 0 OR id < $2)
+			AND (cardinality($3::TEXT[]) = 0 OR status = ANY($3::TEXT[]))
+			AND ($4::TIMESTAMP IS NULL OR processed_at >= $4)
+			AND ($5::TIMESTAMP IS NULL OR processed_at < $5)
+		ORDER BY id DESC
+		LIMIT $6`, user.ID, after, statuses, from, to, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var balances []entity.BalanceUpdate
 	for rows.Next() {
-		var order entity.Order
-		err := rows.Scan(&order.Number, &order.Sum, &order.UploadedAt)
+		var balance entity.BalanceUpdate
+		var reversedAt sql.NullTime
+		err := rows.Scan(&balance.ID, &balance.Order, &balance.Sum, &balance.Status, &balance.UploadedAt, &reversedAt)
 		if err != nil {
 			return nil, err
 		}
-		orders = append(orders, order)
+		if reversedAt.Valid {
+			balance.ReversedAt = &reversedAt.Time
+		}
+		balances = append(balances, balance)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	if len(orders) == 0 {
+	if len(balances) == 0 {
 		return nil, entity.ErrNoContent
 	}
 
-	var balances []entity.BalanceUpdate
-	for _, order := range orders {
-		balance := entity.BalanceUpdate{
-			Order:      order.Number,
-			Sum:        order.Sum,
-			UploadedAt: order.UploadedAt,
+	return balances, nil
+}
+
+// Credit the sum of a withdrawal back to its user.
+// A withdrawal is reversed at most once
+func (s *Storage) ReverseWithdrawal(ctx context.Context, number string) (entity.BalanceUpdate, error) {
+	var withdrawal entity.BalanceUpdate
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return withdrawal, err
+	}
+	defer tx.Rollback()
+
+	var reversedAt sql.NullTime
+	var user entity.User
+	err = tx.QueryRowContext(ctx, "SELECT id, user_id, number, sum, status, processed_at FROM withdrawals WHERE number = $1 FOR UPDATE", number).Scan(&withdrawal.ID, &user.ID, &withdrawal.Order, &withdrawal.Sum, &withdrawal.Status, &withdrawal.UploadedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return withdrawal, entity.ErrWithdrawalNotFound
 		}
-		balances = append(balances, balance)
+		return withdrawal, err
+	}
+	if withdrawal.Status == entity.WithdrawalReversed {
+		return withdrawal, entity.ErrWithdrawalAlreadyReversed
 	}
 
-	return balances, nil
+	var balance entity.Balance
+	if err := tx.QueryRowContext(ctx, "SELECT current, withdrawn FROM balances WHERE user_id = $1 FOR UPDATE", user.ID).Scan(&balance.Current, &balance.Withdrawn); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return withdrawal, entity.ErrBalanceNotFound
+		}
+		return withdrawal, err
+	}
+
+	// the points go back to the lots they were spent from,
+	// withdrawals older than the lots get a lot earned when they were spent
+	reversal := entity.NewReversalTransaction(user.ID, withdrawal.Order, withdrawal.Sum)
+	reversal.EarnedAt = withdrawal.UploadedAt
+	err = tx.QueryRowContext(ctx, "SELECT id FROM ledger_transactions WHERE kind = $1 AND user_id = $2 AND order_number = $3 ORDER BY id DESC LIMIT 1", entity.LedgerWithdrawal, user.ID, withdrawal.Order).Scan(&reversal.Restores)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return withdrawal, err
+	}
+	if err := s.postLedgerTransaction(ctx, tx, reversal); err != nil {
+		return withdrawal, err
+	}
+
+	if err := tx.QueryRowContext(ctx, "UPDATE withdrawals SET status = $1, reversed_at = now() WHERE id = $2 RETURNING reversed_at", entity.WithdrawalReversed, withdrawal.ID).Scan(&reversedAt); err != nil {
+		return withdrawal, err
+	}
+
+	after := map[string]any{"current": balance.Current + withdrawal.Sum, "withdrawn": balance.Withdrawn - withdrawal.Sum, "order": withdrawal.Order, "sum": withdrawal.Sum}
+	if err := s.audit(ctx, tx, entity.AuditBalanceReversed, entity.AuditTargetUser(user.ID), balance, after); err != nil {
+		return withdrawal, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return withdrawal, err
+	}
+	withdrawal.Status = entity.WithdrawalReversed
+	withdrawal.ReversedAt = &reversedAt.Time
+
+	return withdrawal, nil
+}
+
+// query arguments for OrderFilter, a zero limit means no limit
+func orderFilterArgs(filter entity.OrderFilter) (int64, []string, sql.NullTime, sql.NullTime, sql.NullInt64) {
+	statuses := filter.Statuses
+	if statuses == nil {
+		statuses = []string{}
+	}
+
+	return filter.After,
+		statuses,
+		sql.NullTime{Time: filter.From, Valid: !filter.From.IsZero()},
+		sql.NullTime{Time: filter.To, Valid: !filter.To.IsZero()},
+		sql.NullInt64{Int64: int64(filter.Limit), Valid: filter.Limit > 0}
 }
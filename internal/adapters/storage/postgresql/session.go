@@ -0,0 +1,135 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// sessions
+func (s *Storage) CreateSession(ctx context.Context, session entity.Session) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var sessionID int64
+	if err := tx.QueryRowContext(ctx, "INSERT INTO sessions (user_id, refresh_token_hash, ip, user_agent, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING id", session.UserID, session.RefreshTokenHash, session.IP, session.UserAgent, session.ExpiresAt).Scan(&sessionID); err != nil {
+		return 0, err
+	}
+
+	if session.OpenedBy != "" {
+		if err := s.audit(ctx, tx, session.OpenedBy, entity.AuditTargetUser(session.UserID), nil, map[string]any{"session_id": sessionID}); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return sessionID, nil
+}
+
+// swap the refresh token of an active session, the old token stops working
+func (s *Storage) RotateSession(ctx context.Context, oldHash string, next entity.Session) (entity.Session, error) {
+	session := next
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return session, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, "UPDATE sessions SET refresh_token_hash = $1, expires_at = $2, ip = $3, user_agent = $4, last_seen_at = now() WHERE refresh_token_hash = $5 AND revoked_at IS NULL AND expires_at > now() RETURNING id, user_id, created_at, last_seen_at", next.RefreshTokenHash, next.ExpiresAt, next.IP, next.UserAgent, oldHash).Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.LastSeenAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return session, entity.ErrSessionNotFound
+		}
+		return session, err
+	}
+
+	if err := s.audit(ctx, tx, entity.AuditTokenRefreshed, entity.AuditTargetUser(session.UserID), nil, map[string]any{"session_id": session.ID}); err != nil {
+		return session, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return session, err
+	}
+
+	return session, nil
+}
+
+func (s *Storage) GetSession(ctx context.Context, sessionID int64) (entity.Session, error) {
+	var session entity.Session
+	var revokedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, "SELECT id, user_id, refresh_token_hash, ip, user_agent, created_at, last_seen_at, expires_at, revoked_at FROM sessions WHERE id = $1", sessionID).Scan(&session.ID, &session.UserID, &session.RefreshTokenHash, &session.IP, &session.UserAgent, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return session, entity.ErrSessionNotFound
+		}
+		return session, err
+	}
+	session.RevokedAt = revokedAt.Time
+
+	return session, nil
+}
+
+// active sessions of the user, newest first
+func (s *Storage) GetSessions(ctx context.Context, user entity.User) ([]entity.Session, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, user_id, ip, user_agent, created_at, last_seen_at, expires_at FROM sessions WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now() ORDER BY id DESC", user.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []entity.Session
+	for rows.Next() {
+		var session entity.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.IP, &session.UserAgent, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(sessions) == 0 {
+		return nil, entity.ErrNoContent
+	}
+
+	return sessions, nil
+}
+
+func (s *Storage) TouchSession(ctx context.Context, sessionID int64, seenAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE sessions SET last_seen_at = $1 WHERE id = $2", seenAt, sessionID)
+	return err
+}
+
+func (s *Storage) RevokeSession(ctx context.Context, user entity.User, sessionID int64) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE sessions SET revoked_at = now() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL", sessionID, user.ID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return entity.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// revoke all sessions of the user except keepSessionID
+func (s *Storage) RevokeOtherSessions(ctx context.Context, user entity.User, keepSessionID int64) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL", user.ID, keepSessionID)
+	return err
+}
@@ -0,0 +1,126 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// two-factor auth, users without totp get the zero value
+func (s *Storage) GetTwoFactor(ctx context.Context, user entity.User) (entity.TwoFactor, error) {
+	var twoFactor entity.TwoFactor
+	err := s.db.QueryRowContext(ctx, "SELECT secret, enabled, last_step FROM user_totp WHERE user_id = $1", user.ID).Scan(&twoFactor.Secret, &twoFactor.Enabled, &twoFactor.LastStep)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return twoFactor, err
+	}
+
+	return twoFactor, nil
+}
+
+// set a new secret until the enrolment is confirmed
+func (s *Storage) SetTwoFactorSecret(ctx context.Context, user entity.User, secret string) error {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_step = 0
+		WHERE user_totp.enabled = false`,
+		user.ID, secret)
+	if err != nil {
+		return err
+	}
+
+	return affectedOrErr(res, entity.ErrTwoFactorAlreadyEnabled)
+}
+
+// confirm the enrolment and replace the recovery codes
+func (s *Storage) EnableTwoFactor(ctx context.Context, user entity.User, step int64, recoveryCodeHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE user_totp SET enabled = true, last_step = $1 WHERE user_id = $2 AND enabled = false", step, user.ID)
+	if err != nil {
+		return err
+	}
+	if err := affectedOrErr(res, entity.ErrTwoFactorAlreadyEnabled); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", user.ID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", user.ID, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// accept a code of the step only once and never an older one
+func (s *Storage) UseTOTPStep(ctx context.Context, user entity.User, step int64) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE user_totp SET last_step = $1 WHERE user_id = $2 AND enabled = true AND last_step < $1", step, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return affectedOrErr(res, entity.ErrInvalidTOTPCode)
+}
+
+func (s *Storage) UseRecoveryCode(ctx context.Context, user entity.User, codeHash string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL", user.ID, codeHash)
+	if err != nil {
+		return err
+	}
+
+	return affectedOrErr(res, entity.ErrInvalidTOTPCode)
+}
+
+// login challenges
+func (s *Storage) CreateLoginChallenge(ctx context.Context, challenge entity.LoginChallenge) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO login_challenges (user_id, token_hash, expires_at) VALUES ($1, $2, $3)", challenge.UserID, challenge.TokenHash, challenge.ExpiresAt)
+	return err
+}
+
+func (s *Storage) GetLoginChallenge(ctx context.Context, tokenHash string) (entity.LoginChallenge, error) {
+	var challenge entity.LoginChallenge
+	var usedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, "SELECT id, user_id, token_hash, expires_at, used_at FROM login_challenges WHERE token_hash = $1", tokenHash).Scan(&challenge.ID, &challenge.UserID, &challenge.TokenHash, &challenge.ExpiresAt, &usedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return challenge, entity.ErrLoginChallengeInvalid
+		}
+		return challenge, err
+	}
+	challenge.UsedAt = usedAt.Time
+
+	return challenge, nil
+}
+
+// only one of concurrent logins with the same challenge completes
+func (s *Storage) UseLoginChallenge(ctx context.Context, challengeID int64, at time.Time) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE login_challenges SET used_at = $1 WHERE id = $2 AND used_at IS NULL", at, challengeID)
+	if err != nil {
+		return err
+	}
+
+	return affectedOrErr(res, entity.ErrLoginChallengeInvalid)
+}
+
+// err when the statement changed no rows
+func affectedOrErr(res sql.Result, err error) error {
+	affected, resErr := res.RowsAffected()
+	if resErr != nil {
+		return resErr
+	}
+	if affected == 0 {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/korovindenis/go-market/internal/adapters/totp/mocks"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTOTP(t *testing.T) *TOTP {
+	config := mocks.NewConfig(t)
+	config.On("GetTOTPIssuer").Return("gomarket", nil)
+
+	totp, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return totp
+}
+
+// test vectors of RFC 6238 appendix B for SHA1
+func TestGenerate_RFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "94287082"},
+		{unix: 1111111109, code: "07081804"},
+		{unix: 1111111111, code: "14050471"},
+		{unix: 1234567890, code: "89005924"},
+		{unix: 2000000000, code: "69279037"},
+		{unix: 20000000000, code: "65353130"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.code, generate(key, tt.unix/30, 8))
+	}
+}
+
+func TestTOTP_Verify(t *testing.T) {
+	totp := newTOTP(t)
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+	key, _ := encoding.DecodeString(secret)
+
+	now := time.Unix(1700000000, 0)
+	current := now.Unix() / 30
+
+	tests := []struct {
+		name string
+		code string
+		step int64
+		ok   bool
+	}{
+		{name: "current step", code: generate(key, current, digits), step: current, ok: true},
+		{name: "previous step", code: generate(key, current-1, digits), step: current - 1, ok: true},
+		{name: "next step", code: generate(key, current+1, digits), step: current + 1, ok: true},
+		{name: "too old", code: generate(key, current-2, digits)},
+		{name: "wrong length", code: "12345"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			step, ok := totp.Verify(secret, tt.code, now)
+
+			// Assert
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.step, step)
+		})
+	}
+}
+
+func TestTOTP_URI(t *testing.T) {
+	totp := newTOTP(t)
+
+	uri := totp.URI("JBSWY3DPEHPK3PXP", "root")
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/gomarket:root?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=gomarket")
+}
+
+func TestTOTP_GenerateRecoveryCodes(t *testing.T) {
+	totp := newTOTP(t)
+
+	codes, err := totp.GenerateRecoveryCodes(entity.RecoveryCodesCount)
+
+	assert.NoError(t, err)
+	assert.Len(t, codes, entity.RecoveryCodesCount)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Len(t, code, 11)
+		assert.Equal(t, code, entity.NormalizeRecoveryCode(code)[:5]+"-"+entity.NormalizeRecoveryCode(code)[5:])
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+}
@@ -0,0 +1,35 @@
+package entity
+
+// user as seen by support
+type UserDetails struct {
+	ID               int64   `json:"id"`
+	Login            string  `json:"login"`
+	Email            string  `json:"email,omitempty"`
+	Role             Role    `json:"role"`
+	Blocked          bool    `json:"blocked"`
+	TwoFactorEnabled bool    `json:"two_factor_enabled"`
+	Balance          Balance `json:"balance"`
+}
+
+// order with its owner
+type OrderDetails struct {
+	Order
+	UserID    int64  `json:"user_id"`
+	UserLogin string `json:"user_login"`
+}
+
+// manual correction of a balance, a negative amount takes points away
+type BalanceAdjustment struct {
+	Amount Money  `json:"amount" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// orders the accrual system has not finished can be asked again
+func CanRequeueOrder(status OrderStatus) bool {
+	switch status {
+	case StatusNew, StatusRegistered, StatusProcessing, StatusInvalid:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,203 @@
+package entity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// what happened, stored with every audit event
+type AuditAction string
+
+const (
+	AuditUserRegistered   AuditAction = "user.registered"
+	AuditLoginSucceeded   AuditAction = "login.succeeded"
+	AuditLoginFailed      AuditAction = "login.failed"
+	AuditTokenRefreshed   AuditAction = "token.refreshed"
+	AuditBalanceWithdrawn AuditAction = "balance.withdrawn"
+	AuditBalanceAdjusted  AuditAction = "balance.adjusted"
+	AuditBalanceReversed  AuditAction = "balance.reversed"
+	AuditPointsExpired    AuditAction = "points.expired"
+	AuditUserBlocked      AuditAction = "user.blocked"
+	AuditUserUnblocked    AuditAction = "user.unblocked"
+	AuditOrderRequeued    AuditAction = "order.requeued"
+)
+
+func ParseAuditAction(s string) (AuditAction, error) {
+	switch action := AuditAction(s); action {
+	case AuditUserRegistered, AuditLoginSucceeded, AuditLoginFailed, AuditTokenRefreshed,
+		AuditBalanceWithdrawn, AuditBalanceAdjusted, AuditBalanceReversed, AuditPointsExpired, AuditUserBlocked, AuditUserUnblocked, AuditOrderRequeued:
+		return action, nil
+	}
+
+	return "", ErrInvalidAuditAction
+}
+
+// who made the request, zero user id for anonymous requests and the system
+type AuditActor struct {
+	UserID    int64
+	IP        string
+	UserAgent string
+}
+
+type auditActorKey struct{}
+
+// the actor travels with the request down to the storage
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+func AuditActorFromContext(ctx context.Context) AuditActor {
+	actor, _ := ctx.Value(auditActorKey{}).(AuditActor)
+	return actor
+}
+
+// targets of audit events
+func AuditTargetUser(userID int64) string {
+	return "user:" + strconv.FormatInt(userID, 10)
+}
+func AuditTargetOrder(number string) string {
+	return "order:" + number
+}
+func AuditTargetLoginAttempts(key string) string {
+	return "attempts:" + key
+}
+
+// Entry of the append-only audit log.
+// Every event carries the hash of the previous one, so changing or
+// removing an event breaks the chain from that point on
+type AuditEvent struct {
+	ID        int64           `json:"id"`
+	ActorID   int64           `json:"actor_id,omitempty"`
+	IP        string          `json:"ip,omitempty"`
+	UserAgent string          `json:"user_agent,omitempty"`
+	Action    AuditAction     `json:"action"`
+	Target    string          `json:"target"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
+}
+
+// event by the actor of ctx, before and after are stored as json
+func NewAuditEvent(ctx context.Context, action AuditAction, target string, before, after any) (AuditEvent, error) {
+	actor := AuditActorFromContext(ctx)
+	event := AuditEvent{
+		ActorID:   actor.UserID,
+		IP:        actor.IP,
+		UserAgent: actor.UserAgent,
+		Action:    action,
+		Target:    target,
+		// the storage keeps microseconds, the hash must survive the round trip
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	var err error
+	if event.Before, err = marshalAuditValue(before); err != nil {
+		return event, err
+	}
+	if event.After, err = marshalAuditValue(after); err != nil {
+		return event, err
+	}
+
+	return event, nil
+}
+
+func marshalAuditValue(value any) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("marshal audit value: %w", err)
+	}
+
+	return raw, nil
+}
+
+// link the event to the previous one
+func (e *AuditEvent) Seal(prevHash string) {
+	e.PrevHash = prevHash
+	e.Hash = e.ComputeHash()
+}
+
+// sha256 over all fields but the id, which the storage assigns
+func (e *AuditEvent) ComputeHash() string {
+	// a json array keeps the fields apart whatever they contain
+	canonical, _ := json.Marshal([]any{
+		e.PrevHash,
+		e.ActorID,
+		e.IP,
+		e.UserAgent,
+		e.Action,
+		e.Target,
+		string(e.Before),
+		string(e.After),
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(canonical)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// result of checking the hash chain
+type AuditVerification struct {
+	Checked int64 `json:"checked"`
+	Valid   bool  `json:"valid"`
+	// first event that does not match its hash or its predecessor
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	LastHash string `json:"last_hash,omitempty"`
+}
+
+// Check the next events of the chain, oldest first.
+// Stops at the first broken event
+func (v *AuditVerification) Check(events []AuditEvent) bool {
+	for _, event := range events {
+		v.Checked++
+		if event.PrevHash != v.LastHash || event.Hash != event.ComputeHash() {
+			v.Valid = false
+			v.BrokenAt = event.ID
+			return false
+		}
+		v.LastHash = event.Hash
+	}
+	v.Valid = true
+
+	return true
+}
+
+// filter for the audit log
+type AuditFilter struct {
+	ActorID int64
+	Actions []AuditAction
+	Target  string
+	ListFilter
+}
+
+func (f *AuditFilter) Match(e AuditEvent) bool {
+	if f.ActorID != 0 && e.ActorID != f.ActorID {
+		return false
+	}
+	if f.Target != "" && e.Target != f.Target {
+		return false
+	}
+	if len(f.Actions) > 0 {
+		found := false
+		for _, action := range f.Actions {
+			if action == e.Action {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return f.ListFilter.Match(e.ID, e.CreatedAt)
+}
@@ -0,0 +1,113 @@
+package entity
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuditChain(t *testing.T, n int) []AuditEvent {
+	ctx := WithAuditActor(context.Background(), AuditActor{UserID: 1, IP: "127.0.0.1", UserAgent: "curl"})
+
+	var events []AuditEvent
+	var prevHash string
+	for i := 0; i < n; i++ {
+		event, err := NewAuditEvent(ctx, AuditBalanceAdjusted, AuditTargetUser(7), Balance{}, map[string]any{"current": NewMoney(int64(i), 0)})
+		assert.NoError(t, err)
+		event.ID = int64(i + 1)
+		event.Seal(prevHash)
+		prevHash = event.Hash
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func TestNewAuditEvent(t *testing.T) {
+	ctx := WithAuditActor(context.Background(), AuditActor{UserID: 1, IP: "127.0.0.1", UserAgent: "curl"})
+
+	// Act
+	event, err := NewAuditEvent(ctx, AuditUserBlocked, AuditTargetUser(7), nil, map[string]any{"blocked": true})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), event.ActorID)
+	assert.Equal(t, "127.0.0.1", event.IP)
+	assert.Equal(t, "user:7", event.Target)
+	assert.Nil(t, event.Before)
+	assert.JSONEq(t, `{"blocked":true}`, string(event.After))
+	assert.Equal(t, AuditActor{}, AuditActorFromContext(context.Background()), "anonymous without an actor")
+}
+
+func TestAuditVerification_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(events []AuditEvent) []AuditEvent
+		valid    bool
+		brokenAt int64
+	}{
+		{
+			name:  "intact",
+			valid: true,
+		},
+		{
+			name: "changed value",
+			tamper: func(events []AuditEvent) []AuditEvent {
+				events[1].After = json.RawMessage(`{"current":1000}`)
+				return events
+			},
+			brokenAt: 2,
+		},
+		{
+			name: "changed value with a new hash",
+			tamper: func(events []AuditEvent) []AuditEvent {
+				events[1].Target = AuditTargetUser(8)
+				events[1].Hash = events[1].ComputeHash()
+				return events
+			},
+			brokenAt: 3,
+		},
+		{
+			name: "removed event",
+			tamper: func(events []AuditEvent) []AuditEvent {
+				return append(events[:1], events[2:]...)
+			},
+			brokenAt: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			events := newAuditChain(t, 3)
+			if tt.tamper != nil {
+				events = tt.tamper(events)
+			}
+
+			// Act
+			var verification AuditVerification
+			valid := verification.Check(events)
+
+			// Assert
+			assert.Equal(t, tt.valid, valid)
+			assert.Equal(t, tt.valid, verification.Valid)
+			assert.Equal(t, tt.brokenAt, verification.BrokenAt)
+		})
+	}
+}
+
+func TestAuditVerification_CheckInPages(t *testing.T) {
+	events := newAuditChain(t, 5)
+
+	// Act
+	var verification AuditVerification
+	first := verification.Check(events[:2])
+	second := verification.Check(events[2:])
+
+	// Assert
+	assert.True(t, first)
+	assert.True(t, second)
+	assert.Equal(t, int64(5), verification.Checked)
+	assert.Equal(t, events[4].Hash, verification.LastHash)
+}
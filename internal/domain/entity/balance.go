@@ -8,15 +8,45 @@ import (
 
 // struct for user Balance
 type Balance struct {
-	Current   float64 `json:"current"`
-	Withdrawn float64 `json:"withdrawn"`
+	Current   Money `json:"current"`
+	Withdrawn Money `json:"withdrawn"`
+}
+
+// balance shown to the user, expiring points are part of the current ones
+type BalanceDetails struct {
+	Balance
+	// accrual estimates of orders still processed, can not be spent yet
+	Pending      Money            `json:"pending"`
+	ExpiringSoon []ExpiringPoints `json:"expiring_soon"`
+}
+
+// withdrawal status
+const (
+	WithdrawalProcessed = string(StatusProcessed)
+	WithdrawalReversed  = "REVERSED"
+)
+
+// withdrawal status by its name, used to parse filters
+func ParseWithdrawalStatus(s string) (string, error) {
+	switch s {
+	case WithdrawalProcessed, WithdrawalReversed:
+		return s, nil
+	}
+
+	return "", ErrInvalidOrderStatus
 }
 
 // struct for update user Balance
 type BalanceUpdate struct {
+	ID         int64     `json:"-"`
 	Order      string    `json:"order"`
-	Sum        float64   `json:"sum"`
+	Sum        Money     `json:"sum"`
 	UploadedAt time.Time `json:"processed_at,omitempty"`
+	// set in withdrawal lists only
+	Status     string     `json:"status,omitempty"`
+	ReversedAt *time.Time `json:"reversed_at,omitempty"`
+	// needed above the withdrawal threshold when two-factor auth is enabled
+	TOTPCode string `json:"totp_code,omitempty"`
 }
 
 // Luhn algorithm
@@ -26,3 +56,11 @@ func (b *BalanceUpdate) IsValidNumber() error {
 	}
 	return nil
 }
+
+// withdrawal sum must be positive
+func (b *BalanceUpdate) IsValidSum() error {
+	if b.Sum <= 0 {
+		return ErrInvalidSum
+	}
+	return nil
+}
@@ -16,4 +16,40 @@ var (
 	ErrOrderAlreadyUploaded            = errors.New("order already uploaded")
 	ErrNoContent                       = errors.New("no content")
 	ErrInsufficientBalance             = errors.New("insufficient balance")
+	ErrInvalidSum                      = errors.New("sum must be positive")
+	ErrMoneyPrecision                  = errors.New("money has more than two decimal places")
+	ErrLedgerEmpty                     = errors.New("ledger transaction has no entries")
+	ErrLedgerUnbalanced                = errors.New("ledger transaction debits and credits do not match")
+	ErrLedgerAmount                    = errors.New("ledger entry amount must be positive")
+	ErrInvalidLedgerKind               = errors.New("unknown ledger transaction kind")
+	ErrInvalidOrderStatus              = errors.New("unknown order status")
+	ErrSessionNotFound                 = errors.New("session not found")
+	ErrSessionRevoked                  = errors.New("session revoked or expired")
+	ErrInvalidCursor                   = errors.New("invalid cursor")
+	ErrTooManyLoginAttempts            = errors.New("too many login attempts")
+	ErrLoginLocked                     = errors.New("login temporarily locked")
+	ErrWeakPassword                    = errors.New("password does not match the policy")
+	ErrWrongPassword                   = errors.New("wrong password")
+	ErrResetTokenInvalid               = errors.New("password reset token is invalid or expired")
+	ErrTwoFactorAlreadyEnabled         = errors.New("two-factor auth already enabled")
+	ErrTwoFactorNotEnrolled            = errors.New("two-factor auth not enrolled")
+	ErrInvalidTOTPCode                 = errors.New("invalid or used totp code")
+	ErrTOTPRequired                    = errors.New("totp code required")
+	ErrLoginChallengeInvalid           = errors.New("login challenge is invalid or expired")
+	ErrInvalidRole                     = errors.New("unknown role")
+	ErrForbidden                       = errors.New("forbidden")
+	ErrUserBlocked                     = errors.New("user blocked")
+	ErrUserNotFound                    = errors.New("user not found")
+	ErrBalanceNotFound                 = errors.New("balance not found")
+	ErrOrderNotFound                   = errors.New("order not found")
+	ErrOrderNotRequeueable             = errors.New("order is processed and can not be requeued")
+	ErrOrderStatusTransition           = errors.New("illegal order status transition")
+	ErrAdjustmentReasonRequired        = errors.New("adjustment reason required")
+	ErrInvalidAuditAction              = errors.New("unknown audit action")
+	ErrInvalidIdempotencyKey           = errors.New("invalid idempotency key")
+	ErrIdempotencyKeyReused            = errors.New("idempotency key was used with another request")
+	ErrIdempotencyKeyInProgress        = errors.New("request with this idempotency key is in progress")
+	ErrWithdrawalNotFound              = errors.New("withdrawal not found")
+	ErrWithdrawalNotUnique             = errors.New("withdrawal for this order already exists")
+	ErrWithdrawalAlreadyReversed       = errors.New("withdrawal already reversed")
 )
@@ -0,0 +1,88 @@
+package entity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// header clients send to make a retried request safe
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	MaxIdempotencyKeyLength = 255
+	// results are replayed for this long, then the key can be used again
+	IdempotencyKeyLifeTime = 24 * time.Hour
+	// a request that did not finish in time gives its key up for a retry
+	IdempotencyLockTimeout = time.Minute
+)
+
+// result of a request stored under the key the client sent
+type IdempotencyKey struct {
+	UserID int64
+	Key    string
+	// hash of the request the key was first used with
+	Fingerprint string
+	// zero while the first request is in progress
+	StatusCode  int
+	CreatedAt   time.Time
+	LockedUntil time.Time
+}
+
+func (k *IdempotencyKey) Validate() error {
+	if k.Key == "" || len(k.Key) > MaxIdempotencyKeyLength {
+		return ErrInvalidIdempotencyKey
+	}
+
+	return nil
+}
+
+// The client is expected to retry these answers with the same key:
+// server errors, a missing login or totp code and throttling
+func (k *IdempotencyKey) Retryable() bool {
+	switch k.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	}
+
+	return k.StatusCode >= http.StatusInternalServerError
+}
+
+// the same key must come with the same method, path and body
+func RequestFingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+type idempotencyKeyKey struct{}
+
+// key held by the request, set by the middleware once it is reserved
+func WithIdempotencyKey(ctx context.Context, key IdempotencyKey) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+func IdempotencyKeyFromContext(ctx context.Context) (IdempotencyKey, bool) {
+	key, ok := ctx.Value(idempotencyKeyKey{}).(IdempotencyKey)
+	return key, ok
+}
+
+// Status the request answers with when the change is stored.
+// The storage completes the key with it in the transaction of the change,
+// so a retry after a crash replays the result instead of repeating it
+func WithIdempotencyStatus(ctx context.Context, status int) context.Context {
+	key, ok := IdempotencyKeyFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	key.StatusCode = status
+
+	return WithIdempotencyKey(ctx, key)
+}
@@ -0,0 +1,39 @@
+package entity
+
+import "time"
+
+// signing algorithms of access tokens
+const (
+	SigningAlgorithmHS256 = "HS256"
+	SigningAlgorithmRS256 = "RS256"
+	SigningAlgorithmEdDSA = "EdDSA"
+)
+
+// asymmetric key used to sign access tokens
+type SigningKey struct {
+	KID       string
+	Algorithm string
+	// PEM encoded private key, PKCS#8 or PKCS#1 for RSA
+	PrivateKeyFile string
+	// the key stops signing new tokens, the tokens it signed are verified
+	// for one more access token lifetime; zero means the key never retires
+	SignUntil time.Time
+}
+
+// public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KID       string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
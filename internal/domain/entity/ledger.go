@@ -0,0 +1,182 @@
+package entity
+
+import "time"
+
+// kind of a ledger transaction
+type LedgerKind string
+
+const (
+	LedgerAccrual    LedgerKind = "ACCRUAL"
+	LedgerWithdrawal LedgerKind = "WITHDRAWAL"
+	LedgerReversal   LedgerKind = "REVERSAL"
+	LedgerAdjustment LedgerKind = "ADJUSTMENT"
+	LedgerExpiry     LedgerKind = "EXPIRY"
+)
+
+// ledger kind by its name, used to parse filters
+func ParseLedgerKind(s string) (LedgerKind, error) {
+	switch kind := LedgerKind(s); kind {
+	case LedgerAccrual, LedgerWithdrawal, LedgerReversal, LedgerAdjustment, LedgerExpiry:
+		return kind, nil
+	}
+
+	return "", ErrInvalidLedgerKind
+}
+
+// ledger accounts, user accounts are credit-normal liabilities
+type LedgerAccount string
+
+const (
+	// points the user can spend
+	AccountCurrent LedgerAccount = "current"
+	// points the user has already spent
+	AccountWithdrawn LedgerAccount = "withdrawn"
+	// system account the issued points are booked against
+	AccountLoyaltyExpense LedgerAccount = "loyalty_expense"
+)
+
+// one leg of a ledger transaction
+type LedgerEntry struct {
+	TransactionID int64
+	Account       LedgerAccount
+	// zero for system accounts
+	UserID int64
+	Debit  Money
+	Credit Money
+}
+
+// group of entries written atomically, debits always equal credits
+type LedgerTransaction struct {
+	ID          int64
+	Kind        LedgerKind
+	UserID      int64
+	OrderNumber string
+	Reason      string
+	CreatedAt   time.Time
+	// current balance of the user right after the transaction, set when posted
+	BalanceAfter Money
+	// when the credited points count as earned, zero means now
+	EarnedAt time.Time
+	// debit whose point lots get the credited points back instead of a new lot
+	Restores int64
+	Entries  []LedgerEntry
+}
+
+// points earned for an order
+func NewAccrualTransaction(userID int64, orderNumber string, amount Money) LedgerTransaction {
+	return LedgerTransaction{
+		Kind:        LedgerAccrual,
+		UserID:      userID,
+		OrderNumber: orderNumber,
+		Entries: []LedgerEntry{
+			{Account: AccountLoyaltyExpense, Debit: amount},
+			{Account: AccountCurrent, UserID: userID, Credit: amount},
+		},
+	}
+}
+
+// points spent on an order
+func NewWithdrawalTransaction(userID int64, orderNumber string, amount Money) LedgerTransaction {
+	return LedgerTransaction{
+		Kind:        LedgerWithdrawal,
+		UserID:      userID,
+		OrderNumber: orderNumber,
+		Entries: []LedgerEntry{
+			{Account: AccountCurrent, UserID: userID, Debit: amount},
+			{Account: AccountWithdrawn, UserID: userID, Credit: amount},
+		},
+	}
+}
+
+// cancelled withdrawal, points go back to the user
+func NewReversalTransaction(userID int64, orderNumber string, amount Money) LedgerTransaction {
+	return LedgerTransaction{
+		Kind:        LedgerReversal,
+		UserID:      userID,
+		OrderNumber: orderNumber,
+		Entries: []LedgerEntry{
+			{Account: AccountWithdrawn, UserID: userID, Debit: amount},
+			{Account: AccountCurrent, UserID: userID, Credit: amount},
+		},
+	}
+}
+
+// points that were not spent in time
+func NewExpiryTransaction(userID int64, amount Money) LedgerTransaction {
+	return LedgerTransaction{
+		Kind:   LedgerExpiry,
+		UserID: userID,
+		Entries: []LedgerEntry{
+			{Account: AccountCurrent, UserID: userID, Debit: amount},
+			{Account: AccountLoyaltyExpense, Credit: amount},
+		},
+	}
+}
+
+// manual correction, a negative amount takes points away
+func NewAdjustmentTransaction(userID int64, amount Money, reason string) LedgerTransaction {
+	expense := LedgerEntry{Account: AccountLoyaltyExpense, Debit: amount}
+	current := LedgerEntry{Account: AccountCurrent, UserID: userID, Credit: amount}
+	if amount < 0 {
+		expense = LedgerEntry{Account: AccountLoyaltyExpense, Credit: -amount}
+		current = LedgerEntry{Account: AccountCurrent, UserID: userID, Debit: -amount}
+	}
+
+	return LedgerTransaction{
+		Kind:    LedgerAdjustment,
+		UserID:  userID,
+		Reason:  reason,
+		Entries: []LedgerEntry{expense, current},
+	}
+}
+
+// checks double-entry invariants before the transaction is written
+func (t *LedgerTransaction) Validate() error {
+	if len(t.Entries) == 0 {
+		return ErrLedgerEmpty
+	}
+
+	var debit, credit Money
+	for _, e := range t.Entries {
+		if e.Debit < 0 || e.Credit < 0 || (e.Debit == 0 && e.Credit == 0) {
+			return ErrLedgerAmount
+		}
+		debit += e.Debit
+		credit += e.Credit
+	}
+	if debit != credit {
+		return ErrLedgerUnbalanced
+	}
+
+	return nil
+}
+
+// change the transaction makes to the user's balance
+func (t *LedgerTransaction) BalanceDelta(userID int64) Balance {
+	var delta Balance
+	for _, e := range t.Entries {
+		if e.UserID != userID {
+			continue
+		}
+		switch e.Account {
+		case AccountCurrent:
+			delta.Current += e.Credit - e.Debit
+		case AccountWithdrawn:
+			delta.Withdrawn += e.Credit - e.Debit
+		}
+	}
+
+	return delta
+}
+
+// balance derived from the user's ledger history
+func ProjectBalance(userID int64, transactions []LedgerTransaction) Balance {
+	var balance Balance
+	for i := range transactions {
+		delta := transactions[i].BalanceDelta(userID)
+		balance.Current += delta.Current
+		balance.Withdrawn += delta.Withdrawn
+	}
+
+	return balance
+}
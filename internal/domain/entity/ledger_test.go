@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedgerTransaction_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		t    LedgerTransaction
+		err  error
+	}{
+		{
+			name: "accrual",
+			t:    NewAccrualTransaction(1, "9278923470", NewMoney(500, 50)),
+		},
+		{
+			name: "withdrawal",
+			t:    NewWithdrawalTransaction(1, "2377225624", NewMoney(100, 0)),
+		},
+		{
+			name: "reversal",
+			t:    NewReversalTransaction(1, "2377225624", NewMoney(100, 0)),
+		},
+		{
+			name: "negative adjustment",
+			t:    NewAdjustmentTransaction(1, NewMoney(-10, 0), "support"),
+		},
+		{
+			name: "empty",
+			t:    LedgerTransaction{Kind: LedgerAdjustment},
+			err:  ErrLedgerEmpty,
+		},
+		{
+			name: "zero amount",
+			t:    NewAccrualTransaction(1, "9278923470", 0),
+			err:  ErrLedgerAmount,
+		},
+		{
+			name: "unbalanced",
+			t: LedgerTransaction{
+				Kind: LedgerAdjustment,
+				Entries: []LedgerEntry{
+					{Account: AccountLoyaltyExpense, Debit: NewMoney(10, 0)},
+					{Account: AccountCurrent, UserID: 1, Credit: NewMoney(9, 99)},
+				},
+			},
+			err: ErrLedgerUnbalanced,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.t.Validate(), tt.err)
+		})
+	}
+}
+
+func TestProjectBalance(t *testing.T) {
+	transactions := []LedgerTransaction{
+		NewAccrualTransaction(1, "9278923470", NewMoney(500, 50)),
+		NewAccrualTransaction(2, "12345678903", NewMoney(42, 0)),
+		NewWithdrawalTransaction(1, "2377225624", NewMoney(100, 0)),
+		NewWithdrawalTransaction(1, "49927398716", NewMoney(0, 50)),
+		NewReversalTransaction(1, "49927398716", NewMoney(0, 50)),
+		NewAdjustmentTransaction(1, NewMoney(0, 50), "goodwill"),
+	}
+
+	assert.Equal(t, Balance{Current: NewMoney(401, 0), Withdrawn: NewMoney(100, 0)}, ProjectBalance(1, transactions))
+	assert.Equal(t, Balance{Current: NewMoney(42, 0)}, ProjectBalance(2, transactions))
+}
+
+func TestCursor(t *testing.T) {
+	id, err := DecodeCursor(EncodeCursor(42))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), id)
+
+	_, err = DecodeCursor("not-a-cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
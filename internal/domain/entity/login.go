@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+// limits for failed login attempts
+type LoginPolicy struct {
+	// failures before the login is locked
+	MaxFailures int
+	// wait after the first failure, doubled on every next one
+	BaseDelay time.Duration
+	// how long the login stays locked, failures are forgotten after it as well
+	Lockout time.Duration
+}
+
+// failed logins for a login and client ip
+type LoginAttempts struct {
+	Failures      int
+	LastFailureAt time.Time
+}
+
+// key the attempts are tracked by
+func LoginAttemptsKey(login, ip string) string {
+	return fmt.Sprintf("%s|%s", login, ip)
+}
+
+// failures older than the lockout do not count
+func (p LoginPolicy) Forgotten(attempts LoginAttempts, now time.Time) bool {
+	return !now.Before(attempts.LastFailureAt.Add(p.Lockout))
+}
+
+// time before the next attempt is allowed,
+// returns ErrLoginLocked or ErrTooManyLoginAttempts while it is positive
+func (p LoginPolicy) Wait(attempts LoginAttempts, now time.Time) (time.Duration, error) {
+	if attempts.Failures == 0 || p.Forgotten(attempts, now) {
+		return 0, nil
+	}
+
+	if attempts.Failures >= p.MaxFailures {
+		return attempts.LastFailureAt.Add(p.Lockout).Sub(now), ErrLoginLocked
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempts.Failures && delay < p.Lockout; i++ {
+		delay *= 2
+	}
+	if delay > p.Lockout {
+		delay = p.Lockout
+	}
+	if wait := attempts.LastFailureAt.Add(delay).Sub(now); wait > 0 {
+		return wait, ErrTooManyLoginAttempts
+	}
+
+	return 0, nil
+}
+
+// login attempt rejected before the password was checked
+type LoginBlockedError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", e.Err, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginBlockedError) Unwrap() error {
+	return e.Err
+}
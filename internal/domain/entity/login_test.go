@@ -0,0 +1,58 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginPolicy_Wait(t *testing.T) {
+	policy := LoginPolicy{MaxFailures: 5, BaseDelay: time.Second, Lockout: 15 * time.Minute}
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		attempts LoginAttempts
+		wait     time.Duration
+		err      error
+	}{
+		{
+			name: "no failures",
+		},
+		{
+			name:     "first failure",
+			attempts: LoginAttempts{Failures: 1, LastFailureAt: now},
+			wait:     time.Second,
+			err:      ErrTooManyLoginAttempts,
+		},
+		{
+			name:     "delay doubles",
+			attempts: LoginAttempts{Failures: 4, LastFailureAt: now},
+			wait:     8 * time.Second,
+			err:      ErrTooManyLoginAttempts,
+		},
+		{
+			name:     "delay passed",
+			attempts: LoginAttempts{Failures: 4, LastFailureAt: now.Add(-8 * time.Second)},
+		},
+		{
+			name:     "locked",
+			attempts: LoginAttempts{Failures: 5, LastFailureAt: now.Add(-time.Minute)},
+			wait:     14 * time.Minute,
+			err:      ErrLoginLocked,
+		},
+		{
+			name:     "lock expired",
+			attempts: LoginAttempts{Failures: 9, LastFailureAt: now.Add(-15 * time.Minute)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, err := policy.Wait(tt.attempts, now)
+
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.wait, wait)
+		})
+	}
+}
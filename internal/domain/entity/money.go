@@ -0,0 +1,135 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// number of minor units in one point, matches DECIMAL(10, 2)
+const MoneyScale = 100
+
+// plain decimal, the fraction is checked for precision separately
+var moneyRe = regexp.MustCompile(`^(-?)(\d+)(?:\.(\d+))?$`)
+
+// Money is an exact amount of points stored in minor units (hundredths),
+// so sums and comparisons never drift the way float64 does
+type Money int64
+
+// builds Money from whole points and hundredths, e.g. NewMoney(500, 50) is 500.50
+func NewMoney(units, cents int64) Money {
+	if units < 0 {
+		return Money(units*MoneyScale - cents)
+	}
+	return Money(units*MoneyScale + cents)
+}
+
+// parses a plain decimal like "500", "500.5" or "500.05" without going through float64,
+// exponents, fractions and extra places are rejected
+func ParseMoney(s string) (Money, error) {
+	match := moneyRe.FindStringSubmatch(s)
+	if match == nil {
+		return 0, fmt.Errorf("invalid money value %q", s)
+	}
+	sign, whole, fraction := match[1], match[2], match[3]
+	if len(fraction) > 2 {
+		return 0, fmt.Errorf("%w: %q", ErrMoneyPrecision, s)
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units > (math.MaxInt64-MoneyScale+1)/MoneyScale {
+		return 0, fmt.Errorf("money value out of range %q", s)
+	}
+	var cents int64
+	if fraction != "" {
+		// "5" is 50 hundredths
+		cents, _ = strconv.ParseInt((fraction + "0")[:2], 10, 64)
+	}
+
+	money := units*MoneyScale + cents
+	if sign == "-" {
+		money = -money
+	}
+
+	return Money(money), nil
+}
+
+// formats as a plain decimal without trailing zeros: 500, 500.5, 500.05
+func (m Money) String() string {
+	sign := ""
+	minor := int64(m)
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	units, cents := minor/MoneyScale, minor%MoneyScale
+	switch {
+	case cents == 0:
+		return fmt.Sprintf("%s%d", sign, units)
+	case cents%10 == 0:
+		return fmt.Sprintf("%s%d.%d", sign, units, cents/10)
+	default:
+		return fmt.Sprintf("%s%d.%02d", sign, units, cents)
+	}
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	// accept amounts sent as JSON strings too
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	money, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = money
+
+	return nil
+}
+
+// implements sql.Scanner for DECIMAL columns
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = Money(v * MoneyScale)
+	case float64:
+		*m = Money(math.Round(v * MoneyScale))
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	money, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = money
+
+	return nil
+}
+
+// implements driver.Valuer, the decimal string keeps the value exact
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
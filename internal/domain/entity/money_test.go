@@ -0,0 +1,216 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Money
+		wantErr bool
+	}{
+		{
+			name: "integer",
+			s:    "500",
+			want: NewMoney(500, 0),
+		},
+		{
+			name: "one decimal place",
+			s:    "500.5",
+			want: NewMoney(500, 50),
+		},
+		{
+			name: "postgres decimal",
+			s:    "729.98",
+			want: NewMoney(729, 98),
+		},
+		{
+			name: "negative",
+			s:    "-0.01",
+			want: Money(-1),
+		},
+		{
+			name:    "too precise",
+			s:       "0.001",
+			wantErr: true,
+		},
+		{
+			name:    "three places of zeros",
+			s:       "0.100",
+			wantErr: true,
+		},
+		{
+			name:    "exponent",
+			s:       "5.005e2",
+			wantErr: true,
+		},
+		{
+			name:    "fraction",
+			s:       "1/3",
+			wantErr: true,
+		},
+		{
+			name:    "no integer part",
+			s:       ".5",
+			wantErr: true,
+		},
+		{
+			name:    "out of range",
+			s:       "92233720368547758.08",
+			wantErr: true,
+		},
+		{
+			name:    "not a number",
+			s:       "abc",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMoney(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMoney() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMoney_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		b    Balance
+		want string
+	}{
+		{
+			name: "integer",
+			b:    Balance{Current: NewMoney(500, 0), Withdrawn: 0},
+			want: `{"current":500,"withdrawn":0}`,
+		},
+		{
+			name: "fractional",
+			b:    Balance{Current: NewMoney(500, 50), Withdrawn: NewMoney(42, 7)},
+			want: `{"current":500.5,"withdrawn":42.07}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.b)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestMoney_Scan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     any
+		want    Money
+		wantErr bool
+	}{
+		{
+			name: "numeric as bytes",
+			src:  []byte("500.50"),
+			want: NewMoney(500, 50),
+		},
+		{
+			name: "numeric as string",
+			src:  "0.10",
+			want: NewMoney(0, 10),
+		},
+		{
+			name: "float",
+			src:  0.1 + 0.2,
+			want: NewMoney(0, 30),
+		},
+		{
+			name: "null",
+			src:  nil,
+			want: 0,
+		},
+		{
+			name:    "unsupported",
+			src:     true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := got.Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Money.Scan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// any amount survives a JSON round trip unchanged
+func TestMoney_JSONRoundTrip(t *testing.T) {
+	property := func(minor int64) bool {
+		m := Money(minor / MoneyScale)
+
+		data, err := json.Marshal(m)
+		if err != nil {
+			return false
+		}
+		var got Money
+		if err := json.Unmarshal(data, &got); err != nil {
+			return false
+		}
+		return got == m
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// accruals and withdrawals parsed from decimal strings reconcile to the cent,
+// which float64 sums of the same strings do not guarantee
+func TestMoney_Reconcile(t *testing.T) {
+	property := func(accruals, withdrawals []uint32) bool {
+		var current, withdrawn, expected Money
+		for _, a := range accruals {
+			m, err := ParseMoney(Money(a).String())
+			if err != nil {
+				return false
+			}
+			current += m
+			expected += Money(a)
+		}
+		for _, w := range withdrawals {
+			m, err := ParseMoney(Money(w).String())
+			if err != nil {
+				return false
+			}
+			if current < m {
+				continue
+			}
+			current -= m
+			withdrawn += m
+		}
+		return current+withdrawn == expected && current >= 0
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func ExampleMoney() {
+	var total Money
+	for i := 0; i < 10; i++ {
+		total += NewMoney(0, 10)
+	}
+	fmt.Println(total == NewMoney(1, 0), total)
+
+	// Output: true 1
+}
@@ -6,22 +6,115 @@ import (
 	"github.com/ShiraazMoollatjie/goluhn"
 )
 
+// status of an order in the accrual system
+type OrderStatus string
+
 // order status
 const (
-	StatusNew        = "NEW"
-	StatusRegistered = "REGISTERED"
-	StatusProcessed  = "PROCESSED"
-	StatusInvalid    = "INVALID"
-	StatusProcessing = "PROCESSING"
+	StatusNew        OrderStatus = "NEW"
+	StatusRegistered OrderStatus = "REGISTERED"
+	StatusProcessed  OrderStatus = "PROCESSED"
+	StatusInvalid    OrderStatus = "INVALID"
+	StatusProcessing OrderStatus = "PROCESSING"
 )
 
+// Statuses an order can move to from each status.
+// Pending statuses can be reported again with a new estimate,
+// final statuses do not move, an admin requeue starts the order over
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	StatusNew:        {StatusRegistered, StatusProcessing, StatusInvalid, StatusProcessed},
+	StatusRegistered: {StatusRegistered, StatusProcessing, StatusInvalid, StatusProcessed},
+	StatusProcessing: {StatusProcessing, StatusInvalid, StatusProcessed},
+	StatusInvalid:    {},
+	StatusProcessed:  {},
+}
+
 // struct for user Order
 type Order struct {
-	Number     string    `json:"number"`
-	Status     string    `json:"Status"`
-	Accrual    float64   `json:"accrual,omitempty"`
+	ID      int64       `json:"-"`
+	Number  string      `json:"number"`
+	Status  OrderStatus `json:"Status"`
+	Accrual Money       `json:"accrual,omitempty"`
+	// estimate of the accrual while the order is processed
+	Pending    Money     `json:"pending,omitempty"`
 	UploadedAt time.Time `json:"uploaded_at"`
-	Sum        float64   `json:"sum,omitempty"`
+}
+
+// Lease on the orders the accrual system still processes.
+// Only the owner polls a leased order, a lease that ended is taken over
+type OrderClaim struct {
+	Owner      string
+	ClaimedAt  time.Time
+	LeaseUntil time.Time
+	// most orders claimed at once
+	Limit int
+}
+
+// filter for order and withdrawal lists
+type OrderFilter struct {
+	Statuses []string
+	ListFilter
+}
+
+func (f *OrderFilter) Match(id int64, status string, at time.Time) bool {
+	if len(f.Statuses) > 0 {
+		found := false
+		for _, s := range f.Statuses {
+			if s == status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return f.ListFilter.Match(id, at)
+}
+
+// order status by its name, used to parse filters and accrual replies
+func ParseOrderStatus(s string) (OrderStatus, error) {
+	status := OrderStatus(s)
+	if _, ok := orderStatusTransitions[status]; !ok {
+		return "", ErrInvalidOrderStatus
+	}
+
+	return status, nil
+}
+
+// the accrual system is still processing the order,
+// its accrual is an estimate held as pending
+func (s OrderStatus) IsPending() bool {
+	return s == StatusRegistered || s == StatusProcessing
+}
+
+// the accrual system is done with the order, its accrual is credited once
+func (s OrderStatus) IsFinal() bool {
+	return s == StatusInvalid || s == StatusProcessed
+}
+
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, status := range orderStatusTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check the move to the status the accrual system reported.
+// A final status reported again is no move, so the order is not updated
+func (s OrderStatus) TransitionTo(next OrderStatus) (bool, error) {
+	if s.IsFinal() && s == next {
+		return false, nil
+	}
+	if !s.CanTransitionTo(next) {
+		return false, ErrOrderStatusTransition
+	}
+
+	return true, nil
 }
 
 // Luhn algorithm
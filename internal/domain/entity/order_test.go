@@ -1,6 +1,9 @@
 package entity
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestOrder_IsValidNumber(t *testing.T) {
 	tests := []struct {
@@ -26,3 +29,78 @@ func TestOrder_IsValidNumber(t *testing.T) {
 		})
 	}
 }
+
+func TestOrderStatus_TransitionTo(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      OrderStatus
+		next        OrderStatus
+		wantChanged bool
+		wantErr     error
+	}{
+		{
+			name:        "new to registered",
+			status:      StatusNew,
+			next:        StatusRegistered,
+			wantChanged: true,
+		},
+		{
+			name:        "processing estimate again",
+			status:      StatusProcessing,
+			next:        StatusProcessing,
+			wantChanged: true,
+		},
+		{
+			name:        "processing to processed",
+			status:      StatusProcessing,
+			next:        StatusProcessed,
+			wantChanged: true,
+		},
+		{
+			name:   "processed again",
+			status: StatusProcessed,
+			next:   StatusProcessed,
+		},
+		{
+			name:    "processing back to registered",
+			status:  StatusProcessing,
+			next:    StatusRegistered,
+			wantErr: ErrOrderStatusTransition,
+		},
+		{
+			name:    "invalid to processed",
+			status:  StatusInvalid,
+			next:    StatusProcessed,
+			wantErr: ErrOrderStatusTransition,
+		},
+		{
+			name:    "back to new",
+			status:  StatusRegistered,
+			next:    StatusNew,
+			wantErr: ErrOrderStatusTransition,
+		},
+		{
+			name:    "unknown status",
+			status:  StatusNew,
+			next:    "DONE",
+			wantErr: ErrOrderStatusTransition,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed, err := tt.status.TransitionTo(tt.next)
+			if changed != tt.wantChanged || !errors.Is(err, tt.wantErr) {
+				t.Errorf("OrderStatus.TransitionTo() = %v, %v, want %v, %v", changed, err, tt.wantChanged, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseOrderStatus(t *testing.T) {
+	if status, err := ParseOrderStatus("REGISTERED"); err != nil || status != StatusRegistered {
+		t.Errorf("ParseOrderStatus() = %v, %v, want %v", status, err, StatusRegistered)
+	}
+	if _, err := ParseOrderStatus("DONE"); !errors.Is(err, ErrInvalidOrderStatus) {
+		t.Errorf("ParseOrderStatus() error = %v, want %v", err, ErrInvalidOrderStatus)
+	}
+}
@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"encoding/base64"
+	"strconv"
+	"time"
+)
+
+// page size limits for list endpoints
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 1000
+)
+
+// keyset pagination, lists are returned newest first
+type Page struct {
+	Limit int
+	// id of the last item of the previous page, zero for the first page
+	After int64
+}
+
+// the page already holds count items, a zero limit never fills up
+func (p *Page) Full(count int) bool {
+	return p.Limit > 0 && count >= p.Limit
+}
+
+// date range and page shared by list endpoints
+type ListFilter struct {
+	// inclusive lower and exclusive upper bound, zero means unbounded
+	From time.Time
+	To   time.Time
+	Page
+}
+
+func (f *ListFilter) Match(id int64, at time.Time) bool {
+	if !f.From.IsZero() && at.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !at.Before(f.To) {
+		return false
+	}
+	if f.After != 0 && id >= f.After {
+		return false
+	}
+
+	return true
+}
+
+// opaque cursor handed out to clients
+func EncodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func DecodeCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	return id, nil
+}
+
+// default and upper bound for the page size
+func (p *Page) Normalize() {
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageLimit
+	}
+	p.Clamp()
+}
+
+// upper bound for the page size, a zero limit stays unlimited
+func (p *Page) Clamp() {
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+}
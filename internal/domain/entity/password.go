@@ -0,0 +1,62 @@
+package entity
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// strength rules for new passwords, zero values disable a rule
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// returns ErrWeakPassword with the broken rule
+func (p PasswordPolicy) Validate(password string) error {
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		return fmt.Errorf("%w: at least %d characters required", ErrWeakPassword, p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("%w: at most %d characters allowed", ErrWeakPassword, p.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		return fmt.Errorf("%w: an upper case letter required", ErrWeakPassword)
+	}
+	if p.RequireLower && !lower {
+		return fmt.Errorf("%w: a lower case letter required", ErrWeakPassword)
+	}
+	if p.RequireDigit && !digit {
+		return fmt.Errorf("%w: a digit required", ErrWeakPassword)
+	}
+	if p.RequireSymbol && !symbol {
+		return fmt.Errorf("%w: a symbol required", ErrWeakPassword)
+	}
+
+	return nil
+}
+
+// body of the password change request
+type PasswordChange struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+// single-use password reset token, only its hash is stored
+type PasswordResetToken struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// zero until the token is used
+	UsedAt time.Time
+}
+
+func (t *PasswordResetToken) IsUsable(now time.Time) bool {
+	return t.UsedAt.IsZero() && now.Before(t.ExpiresAt)
+}
+
+// body of the reset request
+type PasswordResetRequest struct {
+	Login string `json:"login" binding:"required"`
+}
+
+// body of the reset with the token from the notification
+type PasswordReset struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
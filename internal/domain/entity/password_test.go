@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 8, MaxLength: 64, RequireUpper: true, RequireLower: true, RequireDigit: true}
+
+	tests := []struct {
+		name     string
+		password string
+		err      error
+	}{
+		{
+			name:     "strong",
+			password: "Gophermart1",
+		},
+		{
+			name:     "too short",
+			password: "Go1",
+			err:      ErrWeakPassword,
+		},
+		{
+			name:     "no digit",
+			password: "Gophermart",
+			err:      ErrWeakPassword,
+		},
+		{
+			name:     "no upper",
+			password: "gophermart1",
+			err:      ErrWeakPassword,
+		},
+		{
+			name:     "unicode counts runes",
+			password: "Пароль12",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, policy.Validate(tt.password), tt.err)
+		})
+	}
+
+	assert.NoError(t, PasswordPolicy{}.Validate("root"), "empty policy allows anything")
+}
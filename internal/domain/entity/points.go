@@ -0,0 +1,114 @@
+package entity
+
+import "time"
+
+// when earned points expire
+type PointsPolicy struct {
+	// months after the points were earned, zero keeps them forever
+	LifetimeMonths int
+	// points expiring within it are shown in the balance
+	ExpiringSoon time.Duration
+	// how often the expirer runs
+	ExpireInterval time.Duration
+}
+
+// zero when points never expire
+func (p PointsPolicy) ExpiresAt(earnedAt time.Time) time.Time {
+	if p.LifetimeMonths <= 0 {
+		return time.Time{}
+	}
+	return earnedAt.AddDate(0, p.LifetimeMonths, 0)
+}
+
+// lots earned before it have expired by now, zero when points never expire
+func (p PointsPolicy) EarnedBefore(now time.Time) time.Time {
+	if p.LifetimeMonths <= 0 {
+		return time.Time{}
+	}
+	return now.AddDate(0, -p.LifetimeMonths, 0)
+}
+
+// Points credited to the user at once.
+// Debits of the current account use the oldest lots first
+type PointLot struct {
+	ID          int64
+	UserID      int64
+	OrderNumber string
+	Amount      Money
+	Remaining   Money
+	EarnedAt    time.Time
+}
+
+// points of a lot that expire soon, part of the balance
+type ExpiringPoints struct {
+	Order     string    `json:"order,omitempty"`
+	Amount    Money     `json:"amount"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// remaining points of the lots expiring before now + ExpiringSoon, soonest first
+func (p PointsPolicy) ExpiringSoonPoints(lots []PointLot, now time.Time) []ExpiringPoints {
+	expiring := []ExpiringPoints{}
+	if p.LifetimeMonths <= 0 {
+		return expiring
+	}
+
+	until := now.Add(p.ExpiringSoon)
+	for _, lot := range lots {
+		expiresAt := p.ExpiresAt(lot.EarnedAt)
+		if lot.Remaining <= 0 || !expiresAt.Before(until) {
+			continue
+		}
+		expiring = append(expiring, ExpiringPoints{Order: lot.OrderNumber, Amount: lot.Remaining, ExpiresAt: expiresAt})
+	}
+
+	return expiring
+}
+
+// points a debit took from a lot, a reversal gives them back to the same lot
+type PointLotConsumption struct {
+	LotID  int64
+	Amount Money
+}
+
+// Take amount from the lots, oldest first.
+// Returns what was taken from every lot it took from
+func ConsumePointLots(lots []PointLot, amount Money) []PointLotConsumption {
+	var taken []PointLotConsumption
+	for _, lot := range lots {
+		if amount <= 0 {
+			break
+		}
+		if lot.Remaining <= 0 {
+			continue
+		}
+		take := lot.Remaining
+		if take > amount {
+			take = amount
+		}
+		amount -= take
+		taken = append(taken, PointLotConsumption{LotID: lot.ID, Amount: take})
+	}
+
+	return taken
+}
+
+// Give amount back to the lots a debit took it from.
+// Returns what goes back to every lot and the points left over,
+// debits written before the lots existed took from none
+func RestorePointLots(taken []PointLotConsumption, amount Money) ([]PointLotConsumption, Money) {
+	var restored []PointLotConsumption
+	for _, consumption := range taken {
+		if amount <= 0 {
+			break
+		}
+		give := consumption.Amount
+		if give > amount {
+			give = amount
+		}
+		amount -= give
+		restored = append(restored, PointLotConsumption{LotID: consumption.LotID, Amount: give})
+	}
+
+	return restored, amount
+}
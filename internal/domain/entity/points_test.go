@@ -0,0 +1,117 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsumePointLots(t *testing.T) {
+	lots := []PointLot{
+		{ID: 1, Remaining: NewMoney(10, 0)},
+		{ID: 2, Remaining: 0},
+		{ID: 3, Remaining: NewMoney(30, 0)},
+		{ID: 4, Remaining: NewMoney(5, 0)},
+	}
+
+	tests := []struct {
+		name   string
+		amount Money
+		want   []PointLotConsumption
+	}{
+		{
+			name:   "nothing",
+			amount: 0,
+		},
+		{
+			name:   "part of the oldest lot",
+			amount: NewMoney(4, 0),
+			want:   []PointLotConsumption{{LotID: 1, Amount: NewMoney(4, 0)}},
+		},
+		{
+			name:   "oldest first, empty lots skipped",
+			amount: NewMoney(25, 50),
+			want:   []PointLotConsumption{{LotID: 1, Amount: NewMoney(10, 0)}, {LotID: 3, Amount: NewMoney(15, 50)}},
+		},
+		{
+			name:   "more than there is",
+			amount: NewMoney(100, 0),
+			want:   []PointLotConsumption{{LotID: 1, Amount: NewMoney(10, 0)}, {LotID: 3, Amount: NewMoney(30, 0)}, {LotID: 4, Amount: NewMoney(5, 0)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			taken := ConsumePointLots(lots, tt.amount)
+
+			// Assert
+			assert.Equal(t, tt.want, taken)
+			assert.Equal(t, NewMoney(10, 0), lots[0].Remaining)
+		})
+	}
+}
+
+func TestRestorePointLots(t *testing.T) {
+	taken := []PointLotConsumption{{LotID: 1, Amount: NewMoney(10, 0)}, {LotID: 3, Amount: NewMoney(15, 50)}}
+
+	tests := []struct {
+		name     string
+		amount   Money
+		want     []PointLotConsumption
+		leftOver Money
+	}{
+		{
+			name:   "everything taken",
+			amount: NewMoney(25, 50),
+			want:   taken,
+		},
+		{
+			name:   "part of it",
+			amount: NewMoney(12, 0),
+			want:   []PointLotConsumption{{LotID: 1, Amount: NewMoney(10, 0)}, {LotID: 3, Amount: NewMoney(2, 0)}},
+		},
+		{
+			name:     "more than was taken",
+			amount:   NewMoney(30, 0),
+			want:     taken,
+			leftOver: NewMoney(4, 50),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			restored, leftOver := RestorePointLots(taken, tt.amount)
+
+			// Assert
+			assert.Equal(t, tt.want, restored)
+			assert.Equal(t, tt.leftOver, leftOver)
+		})
+	}
+}
+
+func TestPointsPolicy_ExpiringSoonPoints(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	lots := []PointLot{
+		{OrderNumber: "1", Remaining: NewMoney(10, 0), EarnedAt: time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)},
+		{OrderNumber: "2", Remaining: 0, EarnedAt: time.Date(2025, 10, 21, 0, 0, 0, 0, time.UTC)},
+		{OrderNumber: "3", Remaining: NewMoney(20, 0), EarnedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	// Act
+	expiring := PointsPolicy{LifetimeMonths: 12, ExpiringSoon: 30 * 24 * time.Hour}.ExpiringSoonPoints(lots, now)
+	forever := PointsPolicy{ExpiringSoon: 30 * 24 * time.Hour}.ExpiringSoonPoints(lots, now)
+
+	// Assert
+	assert.Equal(t, []ExpiringPoints{{Order: "1", Amount: NewMoney(10, 0), ExpiresAt: time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)}}, expiring)
+	assert.Empty(t, forever)
+	assert.NotNil(t, forever)
+}
+
+func TestPointsPolicy_EarnedBefore(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2025, 10, 18, 12, 0, 0, 0, time.UTC), PointsPolicy{LifetimeMonths: 12}.EarnedBefore(now))
+	assert.True(t, PointsPolicy{}.EarnedBefore(now).IsZero())
+	assert.True(t, PointsPolicy{}.ExpiresAt(now).IsZero())
+}
@@ -0,0 +1,21 @@
+package entity
+
+// access level of a user, carried in the access token
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
+// role by its name, tokens issued before roles existed have none
+func ParseRole(s string) (Role, error) {
+	switch role := Role(s); role {
+	case RoleUser, RoleAdmin:
+		return role, nil
+	case "":
+		return RoleUser, nil
+	}
+
+	return "", ErrInvalidRole
+}
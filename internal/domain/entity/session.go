@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// login session behind a pair of access and refresh tokens
+type Session struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"-"`
+	// sha256 of the refresh token, the token itself is never stored
+	RefreshTokenHash string `json:"-"`
+	// device the session was last used from
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// zero while the session is active
+	RevokedAt time.Time `json:"-"`
+	// the session of the request that lists sessions
+	Current bool `json:"current"`
+	// audit action written when the session is opened, none when empty
+	OpenedBy AuditAction `json:"-"`
+}
+
+// last seen time is only written once per interval to save writes
+const SessionTouchInterval = time.Minute
+
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt.IsZero() && now.Before(s.ExpiresAt)
+}
+
+func (s *Session) NeedsTouch(now time.Time) bool {
+	return now.Sub(s.LastSeenAt) >= SessionTouchInterval
+}
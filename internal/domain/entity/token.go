@@ -0,0 +1,13 @@
+package entity
+
+// tokens returned in the body for clients without cookies
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token" binding:"required"`
+	TokenType    string `json:"token_type"`
+	// lifetime of the access token in seconds
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// type of the access token in the Authorization header
+const TokenTypeBearer = "Bearer"
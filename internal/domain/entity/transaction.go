@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+// item of the user's transaction history
+type Transaction struct {
+	ID   int64      `json:"-"`
+	Type LedgerKind `json:"type"`
+	// change of the current balance, negative for withdrawals
+	Amount Money  `json:"amount"`
+	Order  string `json:"order,omitempty"`
+	// current balance right after the transaction
+	Balance   Money     `json:"balance"`
+	CreatedAt time.Time `json:"processed_at"`
+}
+
+// filter for the transaction history
+type TransactionFilter struct {
+	Types []LedgerKind
+	ListFilter
+}
+
+func (f *TransactionFilter) Match(t Transaction) bool {
+	if len(f.Types) > 0 {
+		found := false
+		for _, kind := range f.Types {
+			if kind == t.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return f.ListFilter.Match(t.ID, t.CreatedAt)
+}
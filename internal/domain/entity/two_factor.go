@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// recovery codes handed out when two-factor auth is confirmed
+	RecoveryCodesCount = 10
+	// time to answer the second login step
+	LoginChallengeLifeTime = 5 * time.Minute
+)
+
+// totp settings of the user, the secret is set on enrolment
+// and the user is protected once the first code is confirmed
+type TwoFactor struct {
+	Secret  string
+	Enabled bool
+	// time step of the last accepted code, codes can not be used twice
+	LastStep int64
+}
+
+// answer of the enrolment, the uri is shown to the user as a qr code
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// body with a totp code
+type TwoFactorCode struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// single-use codes to log in without the authenticator
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// recovery codes are compared without case, spaces and dashes
+func NormalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// second login step, only the token hash is stored
+type LoginChallenge struct {
+	ID        int64     `json:"-"`
+	UserID    int64     `json:"-"`
+	Token     string    `json:"challenge"`
+	TokenHash string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// zero until the login is completed
+	UsedAt time.Time `json:"-"`
+}
+
+func (c *LoginChallenge) IsUsable(now time.Time) bool {
+	return c.UsedAt.IsZero() && now.Before(c.ExpiresAt)
+}
+
+// body of the second login step, with a totp code or a recovery code
+type LoginChallengeAnswer struct {
+	Challenge    string `json:"challenge" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// key the failed totp codes of the user are tracked by, see LoginPolicy
+func TwoFactorAttemptsKey(userID int64) string {
+	return fmt.Sprintf("2fa|%d", userID)
+}
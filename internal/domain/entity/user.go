@@ -2,9 +2,15 @@ package entity
 
 // struct for User
 type User struct {
-	Login     string `json:"login" binding:"required"`
-	Password  string `json:"password" binding:"required"`
+	Login    string `json:"login" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	// optional, used to deliver password reset tokens
+	Email     string `json:"email,omitempty" binding:"omitempty,email"`
 	ID        int64
 	IP        string
 	UserAgent string
+	SessionID int64
+	// never bound from requests
+	Role    Role `json:"-"`
+	Blocked bool `json:"-"`
 }
@@ -2,13 +2,90 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	entity "github.com/korovindenis/go-market/internal/domain/entity"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
 
 // Config is an autogenerated mock type for the config type
 type Config struct {
 	mock.Mock
 }
 
+// GetLoginPolicy provides a mock function with given fields:
+func (_m *Config) GetLoginPolicy() entity.LoginPolicy {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLoginPolicy")
+	}
+
+	var r0 entity.LoginPolicy
+	if rf, ok := ret.Get(0).(func() entity.LoginPolicy); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(entity.LoginPolicy)
+	}
+
+	return r0
+}
+
+// GetPasswordPolicy provides a mock function with given fields:
+func (_m *Config) GetPasswordPolicy() entity.PasswordPolicy {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPasswordPolicy")
+	}
+
+	var r0 entity.PasswordPolicy
+	if rf, ok := ret.Get(0).(func() entity.PasswordPolicy); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(entity.PasswordPolicy)
+	}
+
+	return r0
+}
+
+// GetPasswordResetLifeTime provides a mock function with given fields:
+func (_m *Config) GetPasswordResetLifeTime() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPasswordResetLifeTime")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// GetPointsPolicy provides a mock function with given fields:
+func (_m *Config) GetPointsPolicy() entity.PointsPolicy {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPointsPolicy")
+	}
+
+	var r0 entity.PointsPolicy
+	if rf, ok := ret.Get(0).(func() entity.PointsPolicy); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(entity.PointsPolicy)
+	}
+
+	return r0
+}
+
 // GetStorageSalt provides a mock function with given fields:
 func (_m *Config) GetStorageSalt() string {
 	ret := _m.Called()
@@ -27,6 +104,42 @@ func (_m *Config) GetStorageSalt() string {
 	return r0
 }
 
+// GetTOTPWithdrawalThreshold provides a mock function with given fields:
+func (_m *Config) GetTOTPWithdrawalThreshold() entity.Money {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTOTPWithdrawalThreshold")
+	}
+
+	var r0 entity.Money
+	if rf, ok := ret.Get(0).(func() entity.Money); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(entity.Money)
+	}
+
+	return r0
+}
+
+// GetTokenLifeTime provides a mock function with given fields:
+func (_m *Config) GetTokenLifeTime() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTokenLifeTime")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
 // NewConfig creates a new instance of Config. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewConfig(t interface {
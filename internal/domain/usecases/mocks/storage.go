@@ -7,6 +7,8 @@ import (
 
 	entity "github.com/korovindenis/go-market/internal/domain/entity"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // Storage is an autogenerated mock type for the storage type
@@ -14,6 +16,34 @@ type Storage struct {
 	mock.Mock
 }
 
+// AddFailedLogin provides a mock function with given fields: ctx, key, at, forgetBefore
+func (_m *Storage) AddFailedLogin(ctx context.Context, key string, at time.Time, forgetBefore time.Time) (entity.LoginAttempts, error) {
+	ret := _m.Called(ctx, key, at, forgetBefore)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddFailedLogin")
+	}
+
+	var r0 entity.LoginAttempts
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Time) (entity.LoginAttempts, error)); ok {
+		return rf(ctx, key, at, forgetBefore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Time) entity.LoginAttempts); ok {
+		r0 = rf(ctx, key, at, forgetBefore)
+	} else {
+		r0 = ret.Get(0).(entity.LoginAttempts)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time, time.Time) error); ok {
+		r1 = rf(ctx, key, at, forgetBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // AddOrder provides a mock function with given fields: ctx, order, user
 func (_m *Storage) AddOrder(ctx context.Context, order entity.Order, user entity.User) error {
 	ret := _m.Called(ctx, order, user)
@@ -32,9 +62,137 @@ func (_m *Storage) AddOrder(ctx context.Context, order entity.Order, user entity
 	return r0
 }
 
-// GetAllOrders provides a mock function with given fields: ctx, user
-func (_m *Storage) GetAllOrders(ctx context.Context, user entity.User) ([]entity.Order, error) {
-	ret := _m.Called(ctx, user)
+// AdjustBalance provides a mock function with given fields: ctx, user, adjustment
+func (_m *Storage) AdjustBalance(ctx context.Context, user entity.User, adjustment entity.BalanceAdjustment) (entity.Balance, error) {
+	ret := _m.Called(ctx, user, adjustment)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AdjustBalance")
+	}
+
+	var r0 entity.Balance
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.BalanceAdjustment) (entity.Balance, error)); ok {
+		return rf(ctx, user, adjustment)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.BalanceAdjustment) entity.Balance); ok {
+		r0 = rf(ctx, user, adjustment)
+	} else {
+		r0 = ret.Get(0).(entity.Balance)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User, entity.BalanceAdjustment) error); ok {
+		r1 = rf(ctx, user, adjustment)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CompleteIdempotencyKey provides a mock function with given fields: ctx, key
+func (_m *Storage) CompleteIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CompleteIdempotencyKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.IdempotencyKey) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateLoginChallenge provides a mock function with given fields: ctx, challenge
+func (_m *Storage) CreateLoginChallenge(ctx context.Context, challenge entity.LoginChallenge) error {
+	ret := _m.Called(ctx, challenge)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateLoginChallenge")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.LoginChallenge) error); ok {
+		r0 = rf(ctx, challenge)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreatePasswordReset provides a mock function with given fields: ctx, token
+func (_m *Storage) CreatePasswordReset(ctx context.Context, token entity.PasswordResetToken) error {
+	ret := _m.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePasswordReset")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.PasswordResetToken) error); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateSession provides a mock function with given fields: ctx, session
+func (_m *Storage) CreateSession(ctx context.Context, session entity.Session) (int64, error) {
+	ret := _m.Called(ctx, session)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateSession")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Session) (int64, error)); ok {
+		return rf(ctx, session)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Session) int64); ok {
+		r0 = rf(ctx, session)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.Session) error); ok {
+		r1 = rf(ctx, session)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EnableTwoFactor provides a mock function with given fields: ctx, user, step, recoveryCodeHashes
+func (_m *Storage) EnableTwoFactor(ctx context.Context, user entity.User, step int64, recoveryCodeHashes []string) error {
+	ret := _m.Called(ctx, user, step, recoveryCodeHashes)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnableTwoFactor")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, int64, []string) error); ok {
+		r0 = rf(ctx, user, step, recoveryCodeHashes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAllOrders provides a mock function with given fields: ctx, user, filter
+func (_m *Storage) GetAllOrders(ctx context.Context, user entity.User, filter entity.OrderFilter) ([]entity.Order, error) {
+	ret := _m.Called(ctx, user, filter)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetAllOrders")
@@ -42,19 +200,79 @@ func (_m *Storage) GetAllOrders(ctx context.Context, user entity.User) ([]entity
 
 	var r0 []entity.Order
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) ([]entity.Order, error)); ok {
-		return rf(ctx, user)
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.OrderFilter) ([]entity.Order, error)); ok {
+		return rf(ctx, user, filter)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) []entity.Order); ok {
-		r0 = rf(ctx, user)
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.OrderFilter) []entity.Order); ok {
+		r0 = rf(ctx, user, filter)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]entity.Order)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
-		r1 = rf(ctx, user)
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User, entity.OrderFilter) error); ok {
+		r1 = rf(ctx, user, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAuditChain provides a mock function with given fields: ctx, afterID, limit
+func (_m *Storage) GetAuditChain(ctx context.Context, afterID int64, limit int) ([]entity.AuditEvent, error) {
+	ret := _m.Called(ctx, afterID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAuditChain")
+	}
+
+	var r0 []entity.AuditEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) ([]entity.AuditEvent, error)); ok {
+		return rf(ctx, afterID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) []entity.AuditEvent); ok {
+		r0 = rf(ctx, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.AuditEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int) error); ok {
+		r1 = rf(ctx, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAuditEvents provides a mock function with given fields: ctx, filter
+func (_m *Storage) GetAuditEvents(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEvent, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAuditEvents")
+	}
+
+	var r0 []entity.AuditEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AuditFilter) ([]entity.AuditEvent, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AuditFilter) []entity.AuditEvent); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.AuditEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.AuditFilter) error); ok {
+		r1 = rf(ctx, filter)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -90,27 +308,83 @@ func (_m *Storage) GetBalance(ctx context.Context, user entity.User) (entity.Bal
 	return r0, r1
 }
 
-// GetUser provides a mock function with given fields: ctx, userFromReq
-func (_m *Storage) GetUser(ctx context.Context, userFromReq entity.User) (entity.User, error) {
-	ret := _m.Called(ctx, userFromReq)
+// GetLoginAttempts provides a mock function with given fields: ctx, key
+func (_m *Storage) GetLoginAttempts(ctx context.Context, key string) (entity.LoginAttempts, error) {
+	ret := _m.Called(ctx, key)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetUser")
+		panic("no return value specified for GetLoginAttempts")
 	}
 
-	var r0 entity.User
+	var r0 entity.LoginAttempts
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) (entity.User, error)); ok {
-		return rf(ctx, userFromReq)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (entity.LoginAttempts, error)); ok {
+		return rf(ctx, key)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) entity.User); ok {
-		r0 = rf(ctx, userFromReq)
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.LoginAttempts); ok {
+		r0 = rf(ctx, key)
 	} else {
-		r0 = ret.Get(0).(entity.User)
+		r0 = ret.Get(0).(entity.LoginAttempts)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
-		r1 = rf(ctx, userFromReq)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLoginChallenge provides a mock function with given fields: ctx, tokenHash
+func (_m *Storage) GetLoginChallenge(ctx context.Context, tokenHash string) (entity.LoginChallenge, error) {
+	ret := _m.Called(ctx, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLoginChallenge")
+	}
+
+	var r0 entity.LoginChallenge
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (entity.LoginChallenge, error)); ok {
+		return rf(ctx, tokenHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.LoginChallenge); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		r0 = ret.Get(0).(entity.LoginChallenge)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrder provides a mock function with given fields: ctx, number
+func (_m *Storage) GetOrder(ctx context.Context, number string) (entity.OrderDetails, error) {
+	ret := _m.Called(ctx, number)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrder")
+	}
+
+	var r0 entity.OrderDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (entity.OrderDetails, error)); ok {
+		return rf(ctx, number)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.OrderDetails); ok {
+		r0 = rf(ctx, number)
+	} else {
+		r0 = ret.Get(0).(entity.OrderDetails)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, number)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -118,41 +392,53 @@ func (_m *Storage) GetUser(ctx context.Context, userFromReq entity.User) (entity
 	return r0, r1
 }
 
-// UserLogin provides a mock function with given fields: ctx, user
-func (_m *Storage) UserLogin(ctx context.Context, user entity.User) error {
+// GetPendingAccrual provides a mock function with given fields: ctx, user
+func (_m *Storage) GetPendingAccrual(ctx context.Context, user entity.User) (entity.Money, error) {
 	ret := _m.Called(ctx, user)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UserLogin")
+		panic("no return value specified for GetPendingAccrual")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) error); ok {
+	var r0 entity.Money
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) (entity.Money, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) entity.Money); ok {
 		r0 = rf(ctx, user)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(entity.Money)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// UserRegister provides a mock function with given fields: ctx, user
-func (_m *Storage) UserRegister(ctx context.Context, user entity.User) (int64, error) {
+// GetPointLots provides a mock function with given fields: ctx, user
+func (_m *Storage) GetPointLots(ctx context.Context, user entity.User) ([]entity.PointLot, error) {
 	ret := _m.Called(ctx, user)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UserRegister")
+		panic("no return value specified for GetPointLots")
 	}
 
-	var r0 int64
+	var r0 []entity.PointLot
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) (int64, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) ([]entity.PointLot, error)); ok {
 		return rf(ctx, user)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) int64); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) []entity.PointLot); ok {
 		r0 = rf(ctx, user)
 	} else {
-		r0 = ret.Get(0).(int64)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.PointLot)
+		}
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
@@ -164,42 +450,52 @@ func (_m *Storage) UserRegister(ctx context.Context, user entity.User) (int64, e
 	return r0, r1
 }
 
-// WithdrawBalance provides a mock function with given fields: ctx, balance, user
-func (_m *Storage) WithdrawBalance(ctx context.Context, balance entity.BalanceUpdate, user entity.User) error {
-	ret := _m.Called(ctx, balance, user)
+// GetSession provides a mock function with given fields: ctx, sessionID
+func (_m *Storage) GetSession(ctx context.Context, sessionID int64) (entity.Session, error) {
+	ret := _m.Called(ctx, sessionID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for WithdrawBalance")
+		panic("no return value specified for GetSession")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, entity.BalanceUpdate, entity.User) error); ok {
-		r0 = rf(ctx, balance, user)
+	var r0 entity.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (entity.Session, error)); ok {
+		return rf(ctx, sessionID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) entity.Session); ok {
+		r0 = rf(ctx, sessionID)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(entity.Session)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, sessionID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// Withdrawals provides a mock function with given fields: ctx, user
-func (_m *Storage) Withdrawals(ctx context.Context, user entity.User) ([]entity.BalanceUpdate, error) {
+// GetSessions provides a mock function with given fields: ctx, user
+func (_m *Storage) GetSessions(ctx context.Context, user entity.User) ([]entity.Session, error) {
 	ret := _m.Called(ctx, user)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Withdrawals")
+		panic("no return value specified for GetSessions")
 	}
 
-	var r0 []entity.BalanceUpdate
+	var r0 []entity.Session
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) ([]entity.BalanceUpdate, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) ([]entity.Session, error)); ok {
 		return rf(ctx, user)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) []entity.BalanceUpdate); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) []entity.Session); ok {
 		r0 = rf(ctx, user)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]entity.BalanceUpdate)
+			r0 = ret.Get(0).([]entity.Session)
 		}
 	}
 
@@ -212,6 +508,569 @@ func (_m *Storage) Withdrawals(ctx context.Context, user entity.User) ([]entity.
 	return r0, r1
 }
 
+// GetTransactions provides a mock function with given fields: ctx, user, filter
+func (_m *Storage) GetTransactions(ctx context.Context, user entity.User, filter entity.TransactionFilter) ([]entity.Transaction, error) {
+	ret := _m.Called(ctx, user, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTransactions")
+	}
+
+	var r0 []entity.Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.TransactionFilter) ([]entity.Transaction, error)); ok {
+		return rf(ctx, user, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.TransactionFilter) []entity.Transaction); ok {
+		r0 = rf(ctx, user, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User, entity.TransactionFilter) error); ok {
+		r1 = rf(ctx, user, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTwoFactor provides a mock function with given fields: ctx, user
+func (_m *Storage) GetTwoFactor(ctx context.Context, user entity.User) (entity.TwoFactor, error) {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTwoFactor")
+	}
+
+	var r0 entity.TwoFactor
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) (entity.TwoFactor, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) entity.TwoFactor); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Get(0).(entity.TwoFactor)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUser provides a mock function with given fields: ctx, userFromReq
+func (_m *Storage) GetUser(ctx context.Context, userFromReq entity.User) (entity.User, error) {
+	ret := _m.Called(ctx, userFromReq)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUser")
+	}
+
+	var r0 entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) (entity.User, error)); ok {
+		return rf(ctx, userFromReq)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) entity.User); ok {
+		r0 = rf(ctx, userFromReq)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
+		r1 = rf(ctx, userFromReq)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserCredentials provides a mock function with given fields: ctx, userFromReq
+func (_m *Storage) GetUserCredentials(ctx context.Context, userFromReq entity.User) (entity.User, error) {
+	ret := _m.Called(ctx, userFromReq)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserCredentials")
+	}
+
+	var r0 entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) (entity.User, error)); ok {
+		return rf(ctx, userFromReq)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) entity.User); ok {
+		r0 = rf(ctx, userFromReq)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
+		r1 = rf(ctx, userFromReq)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserDetails provides a mock function with given fields: ctx, user
+func (_m *Storage) GetUserDetails(ctx context.Context, user entity.User) (entity.UserDetails, error) {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserDetails")
+	}
+
+	var r0 entity.UserDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) (entity.UserDetails, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) entity.UserDetails); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Get(0).(entity.UserDetails)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReleaseIdempotencyKey provides a mock function with given fields: ctx, key
+func (_m *Storage) ReleaseIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseIdempotencyKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.IdempotencyKey) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RequeueOrder provides a mock function with given fields: ctx, number
+func (_m *Storage) RequeueOrder(ctx context.Context, number string) error {
+	ret := _m.Called(ctx, number)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RequeueOrder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, number)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReserveIdempotencyKey provides a mock function with given fields: ctx, key, expiredBefore
+func (_m *Storage) ReserveIdempotencyKey(ctx context.Context, key entity.IdempotencyKey, expiredBefore time.Time) (entity.IdempotencyKey, bool, error) {
+	ret := _m.Called(ctx, key, expiredBefore)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReserveIdempotencyKey")
+	}
+
+	var r0 entity.IdempotencyKey
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.IdempotencyKey, time.Time) (entity.IdempotencyKey, bool, error)); ok {
+		return rf(ctx, key, expiredBefore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.IdempotencyKey, time.Time) entity.IdempotencyKey); ok {
+		r0 = rf(ctx, key, expiredBefore)
+	} else {
+		r0 = ret.Get(0).(entity.IdempotencyKey)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.IdempotencyKey, time.Time) bool); ok {
+		r1 = rf(ctx, key, expiredBefore)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, entity.IdempotencyKey, time.Time) error); ok {
+		r2 = rf(ctx, key, expiredBefore)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ResetLoginAttempts provides a mock function with given fields: ctx, key
+func (_m *Storage) ResetLoginAttempts(ctx context.Context, key string) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetLoginAttempts")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResetPassword provides a mock function with given fields: ctx, tokenHash, passwordHash, at
+func (_m *Storage) ResetPassword(ctx context.Context, tokenHash string, passwordHash string, at time.Time) (entity.User, error) {
+	ret := _m.Called(ctx, tokenHash, passwordHash, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetPassword")
+	}
+
+	var r0 entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) (entity.User, error)); ok {
+		return rf(ctx, tokenHash, passwordHash, at)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) entity.User); ok {
+		r0 = rf(ctx, tokenHash, passwordHash, at)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, tokenHash, passwordHash, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReverseWithdrawal provides a mock function with given fields: ctx, number
+func (_m *Storage) ReverseWithdrawal(ctx context.Context, number string) (entity.BalanceUpdate, error) {
+	ret := _m.Called(ctx, number)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReverseWithdrawal")
+	}
+
+	var r0 entity.BalanceUpdate
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (entity.BalanceUpdate, error)); ok {
+		return rf(ctx, number)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.BalanceUpdate); ok {
+		r0 = rf(ctx, number)
+	} else {
+		r0 = ret.Get(0).(entity.BalanceUpdate)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, number)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeOtherSessions provides a mock function with given fields: ctx, user, keepSessionID
+func (_m *Storage) RevokeOtherSessions(ctx context.Context, user entity.User, keepSessionID int64) error {
+	ret := _m.Called(ctx, user, keepSessionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeOtherSessions")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, int64) error); ok {
+		r0 = rf(ctx, user, keepSessionID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeSession provides a mock function with given fields: ctx, user, sessionID
+func (_m *Storage) RevokeSession(ctx context.Context, user entity.User, sessionID int64) error {
+	ret := _m.Called(ctx, user, sessionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeSession")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, int64) error); ok {
+		r0 = rf(ctx, user, sessionID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RotateSession provides a mock function with given fields: ctx, oldHash, next
+func (_m *Storage) RotateSession(ctx context.Context, oldHash string, next entity.Session) (entity.Session, error) {
+	ret := _m.Called(ctx, oldHash, next)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotateSession")
+	}
+
+	var r0 entity.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, entity.Session) (entity.Session, error)); ok {
+		return rf(ctx, oldHash, next)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, entity.Session) entity.Session); ok {
+		r0 = rf(ctx, oldHash, next)
+	} else {
+		r0 = ret.Get(0).(entity.Session)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, entity.Session) error); ok {
+		r1 = rf(ctx, oldHash, next)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetTwoFactorSecret provides a mock function with given fields: ctx, user, secret
+func (_m *Storage) SetTwoFactorSecret(ctx context.Context, user entity.User, secret string) error {
+	ret := _m.Called(ctx, user, secret)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetTwoFactorSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, string) error); ok {
+		r0 = rf(ctx, user, secret)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetUserBlocked provides a mock function with given fields: ctx, user, blocked
+func (_m *Storage) SetUserBlocked(ctx context.Context, user entity.User, blocked bool) (entity.User, error) {
+	ret := _m.Called(ctx, user, blocked)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetUserBlocked")
+	}
+
+	var r0 entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, bool) (entity.User, error)); ok {
+		return rf(ctx, user, blocked)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, bool) entity.User); ok {
+		r0 = rf(ctx, user, blocked)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User, bool) error); ok {
+		r1 = rf(ctx, user, blocked)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetUserPassword provides a mock function with given fields: ctx, user, passwordHash
+func (_m *Storage) SetUserPassword(ctx context.Context, user entity.User, passwordHash string) error {
+	ret := _m.Called(ctx, user, passwordHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetUserPassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, string) error); ok {
+		r0 = rf(ctx, user, passwordHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TouchSession provides a mock function with given fields: ctx, sessionID, seenAt
+func (_m *Storage) TouchSession(ctx context.Context, sessionID int64, seenAt time.Time) error {
+	ret := _m.Called(ctx, sessionID, seenAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TouchSession")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) error); ok {
+		r0 = rf(ctx, sessionID, seenAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UseLoginChallenge provides a mock function with given fields: ctx, challengeID, at
+func (_m *Storage) UseLoginChallenge(ctx context.Context, challengeID int64, at time.Time) error {
+	ret := _m.Called(ctx, challengeID, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseLoginChallenge")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) error); ok {
+		r0 = rf(ctx, challengeID, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UseRecoveryCode provides a mock function with given fields: ctx, user, codeHash
+func (_m *Storage) UseRecoveryCode(ctx context.Context, user entity.User, codeHash string) error {
+	ret := _m.Called(ctx, user, codeHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseRecoveryCode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, string) error); ok {
+		r0 = rf(ctx, user, codeHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UseTOTPStep provides a mock function with given fields: ctx, user, step
+func (_m *Storage) UseTOTPStep(ctx context.Context, user entity.User, step int64) error {
+	ret := _m.Called(ctx, user, step)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseTOTPStep")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, int64) error); ok {
+		r0 = rf(ctx, user, step)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserRegister provides a mock function with given fields: ctx, user
+func (_m *Storage) UserRegister(ctx context.Context, user entity.User) (int64, error) {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UserRegister")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) (int64, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) int64); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WithdrawBalance provides a mock function with given fields: ctx, balance, user
+func (_m *Storage) WithdrawBalance(ctx context.Context, balance entity.BalanceUpdate, user entity.User) error {
+	ret := _m.Called(ctx, balance, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithdrawBalance")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.BalanceUpdate, entity.User) error); ok {
+		r0 = rf(ctx, balance, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Withdrawals provides a mock function with given fields: ctx, user, filter
+func (_m *Storage) Withdrawals(ctx context.Context, user entity.User, filter entity.OrderFilter) ([]entity.BalanceUpdate, error) {
+	ret := _m.Called(ctx, user, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Withdrawals")
+	}
+
+	var r0 []entity.BalanceUpdate
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.OrderFilter) ([]entity.BalanceUpdate, error)); ok {
+		return rf(ctx, user, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.OrderFilter) []entity.BalanceUpdate); ok {
+		r0 = rf(ctx, user, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.BalanceUpdate)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User, entity.OrderFilter) error); ok {
+		r1 = rf(ctx, user, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewStorage creates a new instance of Storage. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewStorage(t interface {
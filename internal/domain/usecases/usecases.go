@@ -2,48 +2,134 @@ package usecases
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/korovindenis/go-market/internal/domain/entity"
-	"golang.org/x/crypto/bcrypt"
+	"go.uber.org/zap"
 )
 
 //go:generate mockery --name storage --exported
 type storage interface {
 	UserRegister(ctx context.Context, user entity.User) (int64, error)
-	UserLogin(ctx context.Context, user entity.User) error
+	GetUserCredentials(ctx context.Context, userFromReq entity.User) (entity.User, error)
+	SetUserPassword(ctx context.Context, user entity.User, passwordHash string) error
 	GetUser(ctx context.Context, userFromReq entity.User) (entity.User, error)
 
-	GetAllOrders(ctx context.Context, user entity.User) ([]entity.Order, error)
+	GetAllOrders(ctx context.Context, user entity.User, filter entity.OrderFilter) ([]entity.Order, error)
 	AddOrder(ctx context.Context, order entity.Order, user entity.User) error
 
 	GetBalance(ctx context.Context, user entity.User) (entity.Balance, error)
+	GetPendingAccrual(ctx context.Context, user entity.User) (entity.Money, error)
 	WithdrawBalance(ctx context.Context, balance entity.BalanceUpdate, user entity.User) error
 
-	Withdrawals(ctx context.Context, user entity.User) ([]entity.BalanceUpdate, error)
+	Withdrawals(ctx context.Context, user entity.User, filter entity.OrderFilter) ([]entity.BalanceUpdate, error)
+	ReverseWithdrawal(ctx context.Context, number string) (entity.BalanceUpdate, error)
+	GetTransactions(ctx context.Context, user entity.User, filter entity.TransactionFilter) ([]entity.Transaction, error)
+
+	CreateSession(ctx context.Context, session entity.Session) (int64, error)
+	RotateSession(ctx context.Context, oldHash string, next entity.Session) (entity.Session, error)
+	GetSession(ctx context.Context, sessionID int64) (entity.Session, error)
+	GetSessions(ctx context.Context, user entity.User) ([]entity.Session, error)
+	TouchSession(ctx context.Context, sessionID int64, seenAt time.Time) error
+	RevokeSession(ctx context.Context, user entity.User, sessionID int64) error
+	RevokeOtherSessions(ctx context.Context, user entity.User, keepSessionID int64) error
+
+	GetLoginAttempts(ctx context.Context, key string) (entity.LoginAttempts, error)
+	AddFailedLogin(ctx context.Context, key string, at, forgetBefore time.Time) (entity.LoginAttempts, error)
+	ResetLoginAttempts(ctx context.Context, key string) error
+
+	CreatePasswordReset(ctx context.Context, token entity.PasswordResetToken) error
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string, at time.Time) (entity.User, error)
+
+	GetTwoFactor(ctx context.Context, user entity.User) (entity.TwoFactor, error)
+	SetTwoFactorSecret(ctx context.Context, user entity.User, secret string) error
+	EnableTwoFactor(ctx context.Context, user entity.User, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, user entity.User, step int64) error
+	UseRecoveryCode(ctx context.Context, user entity.User, codeHash string) error
+	CreateLoginChallenge(ctx context.Context, challenge entity.LoginChallenge) error
+	GetLoginChallenge(ctx context.Context, tokenHash string) (entity.LoginChallenge, error)
+	UseLoginChallenge(ctx context.Context, challengeID int64, at time.Time) error
+
+	GetUserDetails(ctx context.Context, user entity.User) (entity.UserDetails, error)
+	SetUserBlocked(ctx context.Context, user entity.User, blocked bool) (entity.User, error)
+	AdjustBalance(ctx context.Context, user entity.User, adjustment entity.BalanceAdjustment) (entity.Balance, error)
+	GetOrder(ctx context.Context, number string) (entity.OrderDetails, error)
+	RequeueOrder(ctx context.Context, number string) error
+
+	GetAuditEvents(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEvent, error)
+	GetAuditChain(ctx context.Context, afterID int64, limit int) ([]entity.AuditEvent, error)
+
+	ReserveIdempotencyKey(ctx context.Context, key entity.IdempotencyKey, expiredBefore time.Time) (entity.IdempotencyKey, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error
+	ReleaseIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error
+
+	GetPointLots(ctx context.Context, user entity.User) ([]entity.PointLot, error)
 }
 
 //go:generate mockery --name config --exported
 type config interface {
 	GetStorageSalt() string
+	GetTokenLifeTime() time.Duration
+	GetLoginPolicy() entity.LoginPolicy
+	GetPasswordPolicy() entity.PasswordPolicy
+	GetPasswordResetLifeTime() time.Duration
+	GetTOTPWithdrawalThreshold() entity.Money
+	GetPointsPolicy() entity.PointsPolicy
+}
+
+//go:generate mockery --name hasher --exported
+type hasher interface {
+	Hash(password string) (string, error)
+	Verify(hash, password string) (bool, error)
+	NeedsRehash(hash string) bool
+}
+
+//go:generate mockery --name notifier --exported
+type notifier interface {
+	SendPasswordReset(ctx context.Context, user entity.User, token string, expiresAt time.Time) error
+}
+
+//go:generate mockery --name otp --exported
+type otp interface {
+	GenerateSecret() (string, error)
+	URI(secret, account string) string
+	Verify(secret, code string, at time.Time) (int64, bool)
+	GenerateRecoveryCodes(n int) ([]string, error)
 }
 
 type Usecases struct {
 	storage
 	config
+	hasher
+	notifier
+	otp
+	logger *zap.Logger
 }
 
-func New(config config, storage storage) (*Usecases, error) {
+func New(config config, storage storage, hasher hasher, notifier notifier, otp otp, logger *zap.Logger) (*Usecases, error) {
 	return &Usecases{
-		storage: storage,
-		config:  config,
+		storage:  storage,
+		config:   config,
+		hasher:   hasher,
+		notifier: notifier,
+		otp:      otp,
+		logger:   logger,
 	}, nil
 }
 
 // auth
 func (u *Usecases) UserRegister(ctx context.Context, user entity.User) (int64, error) {
-	// add salt to password
-	password, err := hashPassword(user.Password, u.config.GetStorageSalt())
+	if err := u.config.GetPasswordPolicy().Validate(user.Password); err != nil {
+		return 0, err
+	}
+
+	password, err := u.hashPassword(user.Password)
 	if err != nil {
 		return 0, err
 	}
@@ -51,40 +137,598 @@ func (u *Usecases) UserRegister(ctx context.Context, user entity.User) (int64, e
 
 	return u.storage.UserRegister(ctx, user)
 }
-func (u *Usecases) UserLogin(ctx context.Context, user entity.User) error {
-	// add salt to password
-	user.Password = fmt.Sprintf("%s%s", user.Password, u.config.GetStorageSalt())
 
-	return u.storage.UserLogin(ctx, user)
+// failed attempts are tracked by login and ip, see entity.LoginPolicy,
+// users with two-factor auth get a challenge for the second step
+func (u *Usecases) UserLogin(ctx context.Context, user entity.User) (*entity.LoginChallenge, error) {
+	key := entity.LoginAttemptsKey(user.Login, user.IP)
+	now := time.Now()
+	if err := u.checkAttempts(ctx, key, now); err != nil {
+		return nil, err
+	}
+
+	userFromStorage, err := u.checkPassword(ctx, entity.User{Login: user.Login}, user.Password)
+	switch {
+	case errors.Is(err, entity.ErrUserLoginUnauthorized):
+		return nil, u.failAttempt(ctx, key, now, err)
+	case err != nil:
+		return nil, err
+	}
+	if userFromStorage.Blocked {
+		return nil, entity.ErrUserBlocked
+	}
+
+	// upgrade old hashes while the plain password is at hand,
+	// a failed upgrade does not fail the login and is retried on the next one
+	if u.hasher.NeedsRehash(userFromStorage.Password) {
+		if err := u.rehashPassword(ctx, userFromStorage, user.Password); err != nil {
+			u.logger.Warn("usecases rehash password", zap.Int64("user", userFromStorage.ID), zap.Error(err))
+		}
+	}
+
+	twoFactor, err := u.storage.GetTwoFactor(ctx, userFromStorage)
+	if err != nil {
+		return nil, err
+	}
+	if !twoFactor.Enabled {
+		return nil, u.storage.ResetLoginAttempts(ctx, key)
+	}
+
+	// attempts are reset only after the second step,
+	// else a known password would allow unlimited totp guesses
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	challenge := entity.LoginChallenge{
+		UserID:    userFromStorage.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(entity.LoginChallengeLifeTime),
+	}
+	if err := u.storage.CreateLoginChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+	challenge.Token = token
+
+	return &challenge, nil
+}
+
+// second login step, device is the user info of the request,
+// returns the user to open the session for
+func (u *Usecases) CompleteLogin(ctx context.Context, answer entity.LoginChallengeAnswer, device entity.User) (entity.User, error) {
+	now := time.Now()
+	challenge, err := u.storage.GetLoginChallenge(ctx, hashToken(answer.Challenge))
+	if err != nil {
+		return entity.User{}, err
+	}
+	if !challenge.IsUsable(now) {
+		return entity.User{}, entity.ErrLoginChallengeInvalid
+	}
+
+	user := entity.User{ID: challenge.UserID}
+	if answer.RecoveryCode != "" {
+		err = u.checkRecoveryCode(ctx, user, answer.RecoveryCode, now)
+	} else {
+		err = u.checkTOTPCode(ctx, user, answer.Code, now)
+	}
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	// the user may be blocked between the two steps
+	userFromStorage, err := u.storage.GetUserCredentials(ctx, user)
+	if err != nil {
+		return entity.User{}, err
+	}
+	if userFromStorage.Blocked {
+		return entity.User{}, entity.ErrUserBlocked
+	}
+
+	if err := u.storage.UseLoginChallenge(ctx, challenge.ID, now); err != nil {
+		return entity.User{}, err
+	}
+
+	// the password step is done as well
+	if err := u.storage.ResetLoginAttempts(ctx, entity.LoginAttemptsKey(userFromStorage.Login, device.IP)); err != nil {
+		return entity.User{}, err
+	}
+	user.Role = userFromStorage.Role
+
+	return user, nil
 }
 func (u *Usecases) GetUser(ctx context.Context, userFromReq entity.User) (entity.User, error) {
 	return u.storage.GetUser(ctx, userFromReq)
 }
-func hashPassword(password, salt string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(fmt.Sprintf("%s%s", password, salt)), bcrypt.DefaultCost)
+
+// change the password and revoke all other sessions of the user
+func (u *Usecases) ChangePassword(ctx context.Context, user entity.User, change entity.PasswordChange) error {
+	if _, err := u.checkPassword(ctx, entity.User{ID: user.ID}, change.OldPassword); err != nil {
+		return err
+	}
+	if err := u.config.GetPasswordPolicy().Validate(change.NewPassword); err != nil {
+		return err
+	}
+
+	password, err := u.hashPassword(change.NewPassword)
+	if err != nil {
+		return err
+	}
+	if err := u.storage.SetUserPassword(ctx, user, password); err != nil {
+		return err
+	}
+
+	return u.storage.RevokeOtherSessions(ctx, user, user.SessionID)
+}
+
+// send a single-use reset token to the email of the user,
+// unknown logins and users without email are ignored to not reveal them
+func (u *Usecases) RequestPasswordReset(ctx context.Context, request entity.PasswordResetRequest) error {
+	user, err := u.storage.GetUserCredentials(ctx, entity.User{Login: request.Login})
+	if errors.Is(err, entity.ErrUserLoginUnauthorized) || (err == nil && user.Email == "") {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(u.config.GetPasswordResetLifeTime())
+	if err := u.storage.CreatePasswordReset(ctx, entity.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return err
+	}
+
+	return u.notifier.SendPasswordReset(ctx, user, token, expiresAt)
+}
+
+// set a new password with a reset token and revoke all sessions of the user
+func (u *Usecases) ResetPassword(ctx context.Context, reset entity.PasswordReset) error {
+	if err := u.config.GetPasswordPolicy().Validate(reset.NewPassword); err != nil {
+		return err
+	}
+
+	password, err := u.hashPassword(reset.NewPassword)
+	if err != nil {
+		return err
+	}
+	user, err := u.storage.ResetPassword(ctx, hashToken(reset.Token), password, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return u.storage.RevokeOtherSessions(ctx, user, 0)
+}
+
+// random token for the user and its hash for the storage
+func newToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(b)
+
+	return token, hashToken(token), nil
+}
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// returns LoginBlockedError while the key has to wait
+func (u *Usecases) checkAttempts(ctx context.Context, key string, now time.Time) error {
+	attempts, err := u.storage.GetLoginAttempts(ctx, key)
+	if err != nil {
+		return err
+	}
+	if wait, err := u.config.GetLoginPolicy().Wait(attempts, now); err != nil {
+		return &entity.LoginBlockedError{Err: err, RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// count the failure of the key, returns err
+func (u *Usecases) failAttempt(ctx context.Context, key string, now time.Time, err error) error {
+	forgetBefore := now.Add(-u.config.GetLoginPolicy().Lockout)
+	if _, trackErr := u.storage.AddFailedLogin(ctx, key, now, forgetBefore); trackErr != nil {
+		return trackErr
+	}
+
+	return err
+}
+
+// returns the stored user or ErrUserLoginUnauthorized
+func (u *Usecases) checkPassword(ctx context.Context, userFromReq entity.User, password string) (entity.User, error) {
+	userFromStorage, err := u.storage.GetUserCredentials(ctx, userFromReq)
 	if err != nil {
-		return "", err
+		return userFromStorage, err
 	}
-	return string(hashedPassword), nil
+
+	ok, err := u.hasher.Verify(userFromStorage.Password, u.saltPassword(password))
+	if err != nil {
+		return userFromStorage, err
+	}
+	if !ok {
+		return userFromStorage, entity.ErrUserLoginUnauthorized
+	}
+
+	return userFromStorage, nil
+}
+func (u *Usecases) rehashPassword(ctx context.Context, user entity.User, password string) error {
+	hash, err := u.hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return u.storage.SetUserPassword(ctx, user, hash)
+}
+func (u *Usecases) hashPassword(password string) (string, error) {
+	return u.hasher.Hash(u.saltPassword(password))
+}
+
+// add salt to password, kept for hashes made before per-hash salts
+func (u *Usecases) saltPassword(password string) string {
+	return fmt.Sprintf("%s%s", password, u.config.GetStorageSalt())
+}
+
+// two-factor auth
+func (u *Usecases) EnrollTwoFactor(ctx context.Context, user entity.User) (entity.TwoFactorEnrollment, error) {
+	twoFactor, err := u.storage.GetTwoFactor(ctx, user)
+	if err != nil {
+		return entity.TwoFactorEnrollment{}, err
+	}
+	if twoFactor.Enabled {
+		return entity.TwoFactorEnrollment{}, entity.ErrTwoFactorAlreadyEnabled
+	}
+
+	userFromStorage, err := u.storage.GetUserCredentials(ctx, entity.User{ID: user.ID})
+	if err != nil {
+		return entity.TwoFactorEnrollment{}, err
+	}
+	secret, err := u.otp.GenerateSecret()
+	if err != nil {
+		return entity.TwoFactorEnrollment{}, err
+	}
+	if err := u.storage.SetTwoFactorSecret(ctx, user, secret); err != nil {
+		return entity.TwoFactorEnrollment{}, err
+	}
+
+	return entity.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    u.otp.URI(secret, userFromStorage.Login),
+	}, nil
+}
+
+// enable two-factor auth with the first code of the authenticator,
+// the recovery codes are only shown once
+func (u *Usecases) ConfirmTwoFactor(ctx context.Context, user entity.User, code string) (entity.RecoveryCodes, error) {
+	twoFactor, err := u.storage.GetTwoFactor(ctx, user)
+	if err != nil {
+		return entity.RecoveryCodes{}, err
+	}
+	if twoFactor.Enabled {
+		return entity.RecoveryCodes{}, entity.ErrTwoFactorAlreadyEnabled
+	}
+	if twoFactor.Secret == "" {
+		return entity.RecoveryCodes{}, entity.ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := u.otp.Verify(twoFactor.Secret, code, time.Now())
+	if !ok {
+		return entity.RecoveryCodes{}, entity.ErrInvalidTOTPCode
+	}
+
+	codes, err := u.otp.GenerateRecoveryCodes(entity.RecoveryCodesCount)
+	if err != nil {
+		return entity.RecoveryCodes{}, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, hashToken(entity.NormalizeRecoveryCode(code)))
+	}
+	if err := u.storage.EnableTwoFactor(ctx, user, step, hashes); err != nil {
+		return entity.RecoveryCodes{}, err
+	}
+
+	return entity.RecoveryCodes{Codes: codes}, nil
+}
+
+// failed codes are tracked by user, see entity.TwoFactorAttemptsKey
+func (u *Usecases) checkTOTPCode(ctx context.Context, user entity.User, code string, now time.Time) error {
+	key := entity.TwoFactorAttemptsKey(user.ID)
+	if err := u.checkAttempts(ctx, key, now); err != nil {
+		return err
+	}
+
+	twoFactor, err := u.storage.GetTwoFactor(ctx, user)
+	if err != nil {
+		return err
+	}
+	if !twoFactor.Enabled {
+		return entity.ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := u.otp.Verify(twoFactor.Secret, code, now)
+	if ok {
+		err = u.storage.UseTOTPStep(ctx, user, step)
+	} else {
+		err = entity.ErrInvalidTOTPCode
+	}
+	if errors.Is(err, entity.ErrInvalidTOTPCode) {
+		return u.failAttempt(ctx, key, now, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	return u.storage.ResetLoginAttempts(ctx, key)
+}
+func (u *Usecases) checkRecoveryCode(ctx context.Context, user entity.User, code string, now time.Time) error {
+	key := entity.TwoFactorAttemptsKey(user.ID)
+	if err := u.checkAttempts(ctx, key, now); err != nil {
+		return err
+	}
+
+	err := u.storage.UseRecoveryCode(ctx, user, hashToken(entity.NormalizeRecoveryCode(code)))
+	if errors.Is(err, entity.ErrInvalidTOTPCode) {
+		return u.failAttempt(ctx, key, now, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	return u.storage.ResetLoginAttempts(ctx, key)
+}
+
+// sessions
+// openedBy is the audit action of the request opening the session
+func (u *Usecases) CreateSession(ctx context.Context, user entity.User, refreshTokenHash string, openedBy entity.AuditAction) (int64, error) {
+	return u.storage.CreateSession(ctx, entity.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: refreshTokenHash,
+		IP:               user.IP,
+		UserAgent:        user.UserAgent,
+		ExpiresAt:        u.sessionExpiresAt(),
+		OpenedBy:         openedBy,
+	})
+}
+
+// device is the user info of the refresh request
+
+// returns the user of the session with its current role,
+// sessions of blocked users are revoked
+func (u *Usecases) RotateSession(ctx context.Context, oldHash, newHash string, device entity.User) (entity.User, error) {
+	session, err := u.storage.RotateSession(ctx, oldHash, entity.Session{
+		RefreshTokenHash: newHash,
+		IP:               device.IP,
+		UserAgent:        device.UserAgent,
+		ExpiresAt:        u.sessionExpiresAt(),
+	})
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	user := entity.User{ID: session.UserID, SessionID: session.ID}
+	userFromStorage, err := u.storage.GetUserCredentials(ctx, user)
+	if err != nil {
+		return entity.User{}, err
+	}
+	if userFromStorage.Blocked {
+		if err := u.storage.RevokeSession(ctx, user, session.ID); err != nil {
+			return entity.User{}, err
+		}
+		return entity.User{}, entity.ErrUserBlocked
+	}
+	user.Role = userFromStorage.Role
+
+	return user, nil
+}
+func (u *Usecases) RevokeSession(ctx context.Context, user entity.User, sessionID int64) error {
+	return u.storage.RevokeSession(ctx, user, sessionID)
+}
+
+// active sessions, the one of the user's token is marked as current
+func (u *Usecases) GetSessions(ctx context.Context, user entity.User) ([]entity.Session, error) {
+	sessions, err := u.storage.GetSessions(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == user.SessionID
+	}
+
+	return sessions, nil
+}
+
+// access tokens are only valid while their session is,
+// the session must belong to the user of the token
+func (u *Usecases) CheckSession(ctx context.Context, user entity.User) error {
+	session, err := u.storage.GetSession(ctx, user.SessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != user.ID {
+		return entity.ErrSessionNotFound
+	}
+
+	now := time.Now()
+	if !session.IsActive(now) {
+		return entity.ErrSessionRevoked
+	}
+	if session.NeedsTouch(now) {
+		return u.storage.TouchSession(ctx, session.ID, now)
+	}
+
+	return nil
+}
+func (u *Usecases) sessionExpiresAt() time.Time {
+	return time.Now().Add(u.config.GetTokenLifeTime() * time.Hour)
 }
 
 // orders
 func (u *Usecases) AddOrder(ctx context.Context, order entity.Order, user entity.User) error {
 	return u.storage.AddOrder(ctx, order, user)
 }
-func (u *Usecases) GetAllOrders(ctx context.Context, user entity.User) ([]entity.Order, error) {
-	return u.storage.GetAllOrders(ctx, user)
+func (u *Usecases) GetAllOrders(ctx context.Context, user entity.User, filter entity.OrderFilter) ([]entity.Order, error) {
+	filter.Page.Clamp()
+	return u.storage.GetAllOrders(ctx, user, filter)
 }
 
-// balance
-func (u *Usecases) GetBalance(ctx context.Context, user entity.User) (entity.Balance, error) {
-	return u.storage.GetBalance(ctx, user)
+// balance with the pending accrual and the points that expire soon
+func (u *Usecases) GetBalance(ctx context.Context, user entity.User) (entity.BalanceDetails, error) {
+	balance, err := u.storage.GetBalance(ctx, user)
+	if err != nil {
+		return entity.BalanceDetails{}, err
+	}
+	details := entity.BalanceDetails{Balance: balance, ExpiringSoon: []entity.ExpiringPoints{}}
+	if details.Pending, err = u.storage.GetPendingAccrual(ctx, user); err != nil {
+		return details, err
+	}
+
+	policy := u.config.GetPointsPolicy()
+	if policy.LifetimeMonths <= 0 {
+		return details, nil
+	}
+	lots, err := u.storage.GetPointLots(ctx, user)
+	if err != nil {
+		return details, err
+	}
+	details.ExpiringSoon = policy.ExpiringSoonPoints(lots, time.Now())
+
+	return details, nil
 }
+
+// withdrawals above the threshold need a totp code from users with two-factor auth
 func (u *Usecases) WithdrawBalance(ctx context.Context, balance entity.BalanceUpdate, user entity.User) error {
+	if threshold := u.config.GetTOTPWithdrawalThreshold(); threshold > 0 && balance.Sum > threshold {
+		twoFactor, err := u.storage.GetTwoFactor(ctx, user)
+		if err != nil {
+			return err
+		}
+		if twoFactor.Enabled {
+			if balance.TOTPCode == "" {
+				return entity.ErrTOTPRequired
+			}
+			if err := u.checkTOTPCode(ctx, user, balance.TOTPCode, time.Now()); err != nil {
+				return err
+			}
+		}
+	}
+
 	return u.storage.WithdrawBalance(ctx, balance, user)
 }
 
 // Withdrawals
-func (u *Usecases) Withdrawals(ctx context.Context, user entity.User) ([]entity.BalanceUpdate, error) {
-	return u.storage.Withdrawals(ctx, user)
+func (u *Usecases) Withdrawals(ctx context.Context, user entity.User, filter entity.OrderFilter) ([]entity.BalanceUpdate, error) {
+	filter.Page.Clamp()
+	return u.storage.Withdrawals(ctx, user, filter)
+}
+
+// Transactions
+func (u *Usecases) GetTransactions(ctx context.Context, user entity.User, filter entity.TransactionFilter) ([]entity.Transaction, error) {
+	filter.Page.Normalize()
+	return u.storage.GetTransactions(ctx, user, filter)
+}
+
+// admin
+func (u *Usecases) GetUserDetails(ctx context.Context, login string) (entity.UserDetails, error) {
+	return u.storage.GetUserDetails(ctx, entity.User{Login: login})
+}
+
+// blocked users lose all their sessions at once
+func (u *Usecases) SetUserBlocked(ctx context.Context, login string, blocked bool) error {
+	user, err := u.storage.SetUserBlocked(ctx, entity.User{Login: login}, blocked)
+	if err != nil {
+		return err
+	}
+	if !blocked {
+		return nil
+	}
+
+	return u.storage.RevokeOtherSessions(ctx, user, 0)
+}
+func (u *Usecases) AdjustBalance(ctx context.Context, login string, adjustment entity.BalanceAdjustment) (entity.Balance, error) {
+	adjustment.Reason = strings.TrimSpace(adjustment.Reason)
+	if adjustment.Reason == "" {
+		return entity.Balance{}, entity.ErrAdjustmentReasonRequired
+	}
+
+	return u.storage.AdjustBalance(ctx, entity.User{Login: login}, adjustment)
+}
+func (u *Usecases) GetOrder(ctx context.Context, number string) (entity.OrderDetails, error) {
+	return u.storage.GetOrder(ctx, number)
+}
+func (u *Usecases) RequeueOrder(ctx context.Context, number string) error {
+	return u.storage.RequeueOrder(ctx, number)
+}
+
+// purchase cancelled by the store, the sum goes back to the user
+func (u *Usecases) ReverseWithdrawal(ctx context.Context, number string) (entity.BalanceUpdate, error) {
+	return u.storage.ReverseWithdrawal(ctx, number)
+}
+
+// audit
+func (u *Usecases) GetAuditEvents(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEvent, error) {
+	filter.Page.Normalize()
+	return u.storage.GetAuditEvents(ctx, filter)
+}
+
+// walk the whole hash chain, oldest first
+func (u *Usecases) VerifyAuditLog(ctx context.Context) (entity.AuditVerification, error) {
+	var verification entity.AuditVerification
+	var afterID int64
+	for {
+		events, err := u.storage.GetAuditChain(ctx, afterID, entity.MaxPageLimit)
+		if err != nil {
+			return verification, err
+		}
+		if !verification.Check(events) || len(events) < entity.MaxPageLimit {
+			return verification, nil
+		}
+		afterID = events[len(events)-1].ID
+	}
+}
+
+// idempotency
+// Returns the stored result when the request was already handled,
+// nil when the caller holds the key now and must complete it
+func (u *Usecases) ReserveIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) (*entity.IdempotencyKey, error) {
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	key.CreatedAt = now
+	key.LockedUntil = now.Add(entity.IdempotencyLockTimeout)
+	stored, reserved, err := u.storage.ReserveIdempotencyKey(ctx, key, now.Add(-entity.IdempotencyKeyLifeTime))
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	if stored.Fingerprint != key.Fingerprint {
+		return nil, entity.ErrIdempotencyKeyReused
+	}
+	if stored.StatusCode == 0 {
+		return nil, entity.ErrIdempotencyKeyInProgress
+	}
+
+	return &stored, nil
+}
+
+// retryable answers are not stored, the client repeats the request with the same key
+func (u *Usecases) CompleteIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	if key.Retryable() {
+		return u.storage.ReleaseIdempotencyKey(ctx, key)
+	}
+
+	return u.storage.CompleteIdempotencyKey(ctx, key)
 }
@@ -3,19 +3,27 @@ package usecases
 import (
 	"context"
 	"errors"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/korovindenis/go-market/internal/domain/entity"
 	"github.com/korovindenis/go-market/internal/domain/usecases/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestUsecases_UserRegister(t *testing.T) {
 	config := mocks.NewConfig(t)
 	storage := mocks.NewStorage(t)
-	usecases, _ := New(config, storage)
+	hasher := mocks.NewHasher(t)
+	usecases, _ := New(config, storage, hasher, mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
 	config.On("GetStorageSalt").Return("xxxxxxxx", nil).Maybe()
+	config.On("GetPasswordPolicy").Return(entity.PasswordPolicy{}, nil).Maybe()
+	hasher.On("Hash", "xxxxxxxx").Return("hash", nil).Maybe()
 
 	tests := []struct {
 		ctx  context.Context
@@ -59,55 +67,94 @@ func TestUsecases_UserRegister(t *testing.T) {
 	}
 }
 func TestUsecases_UserLogin(t *testing.T) {
-	config := mocks.NewConfig(t)
-	storage := mocks.NewStorage(t)
-	usecases, _ := New(config, storage)
-	config.On("GetStorageSalt").Return("xxxxxxxx", nil).Maybe()
+	policy := entity.LoginPolicy{MaxFailures: 5, BaseDelay: time.Second, Lockout: 15 * time.Minute}
+	user := entity.User{Login: "user1", Password: "root", IP: "127.0.0.1"}
+	key := entity.LoginAttemptsKey(user.Login, user.IP)
+	verifyErr := errors.New("")
 
+	type calls struct {
+		userLogin int
+		addFailed int
+		reset     int
+	}
 	tests := []struct {
-		ctx  context.Context
-		name string
-		want int64
-		err  error
-		u    *Usecases
-		user entity.User
+		name      string
+		attempts  entity.LoginAttempts
+		loginErr  error
+		verifyErr error
+		err       error
+		calls     calls
 	}{
 		{
-			name: "positive",
-			want: 0,
-			u:    usecases,
-			ctx:  context.Background(),
+			name:  "positive",
+			calls: calls{userLogin: 1, reset: 1},
 		},
 		{
-			name: "negative",
-			want: 0,
-			u:    usecases,
-			ctx:  context.Background(),
-			err:  errors.New(""),
+			name:     "wrong password",
+			loginErr: entity.ErrUserLoginUnauthorized,
+			err:      entity.ErrUserLoginUnauthorized,
+			calls:    calls{userLogin: 1, addFailed: 1},
+		},
+		{
+			name:      "negative",
+			loginErr:  verifyErr,
+			verifyErr: verifyErr,
+			calls:     calls{userLogin: 1},
+		},
+		{
+			name:     "too soon after a failure",
+			attempts: entity.LoginAttempts{Failures: 3, LastFailureAt: time.Now()},
+			err:      entity.ErrTooManyLoginAttempts,
+		},
+		{
+			name:     "locked",
+			attempts: entity.LoginAttempts{Failures: 5, LastFailureAt: time.Now()},
+			err:      entity.ErrLoginLocked,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Arrange
-			userLogin := storage.On("UserLogin", mock.Anything, mock.Anything).Return(tt.err)
+			config := mocks.NewConfig(t)
+			storage := mocks.NewStorage(t)
+			hasher := mocks.NewHasher(t)
+			usecases, _ := New(config, storage, hasher, mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+			config.On("GetStorageSalt").Return("xxxxxxxx", nil).Maybe()
+			config.On("GetLoginPolicy").Return(policy, nil)
+			storage.On("GetLoginAttempts", mock.Anything, key).Return(tt.attempts, nil)
+			if tt.calls.userLogin > 0 {
+				storage.On("GetUserCredentials", mock.Anything, entity.User{Login: user.Login}).Return(entity.User{ID: 1, Password: "hash"}, nil).Times(tt.calls.userLogin)
+				hasher.On("Verify", "hash", "rootxxxxxxxx").Return(tt.loginErr == nil, tt.verifyErr).Times(tt.calls.userLogin)
+				hasher.On("NeedsRehash", "hash").Return(false).Maybe()
+			}
+			if tt.calls.addFailed > 0 {
+				storage.On("AddFailedLogin", mock.Anything, key, mock.Anything, mock.Anything).Return(entity.LoginAttempts{Failures: 1}, nil).Times(tt.calls.addFailed)
+			}
+			if tt.calls.reset > 0 {
+				storage.On("GetTwoFactor", mock.Anything, mock.Anything).Return(entity.TwoFactor{}, nil).Times(tt.calls.reset)
+				storage.On("ResetLoginAttempts", mock.Anything, key).Return(nil).Times(tt.calls.reset)
+			}
 
 			// Act
-			err := tt.u.UserLogin(tt.ctx, tt.user)
+			_, err := usecases.UserLogin(context.Background(), user)
 
 			// Assert
-			if err != nil && !errors.Is(err, tt.err) {
-				t.Fatal(err)
+			if tt.loginErr != nil && tt.err == nil {
+				assert.ErrorIs(t, err, tt.loginErr)
+				return
+			}
+			assert.ErrorIs(t, err, tt.err)
+			var blocked *entity.LoginBlockedError
+			if errors.As(err, &blocked) {
+				assert.Greater(t, blocked.RetryAfter, time.Duration(0))
 			}
-
-			// Unset
-			userLogin.Unset()
 		})
 	}
 }
 func TestUsecases_GetUser(t *testing.T) {
 	config := mocks.NewConfig(t)
 	storage := mocks.NewStorage(t)
-	usecases, _ := New(config, storage)
+	usecases, _ := New(config, storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
 	config.On("GetStorageSalt").Return("xxxxxxxx", nil).Maybe()
 
 	tests := []struct {
@@ -154,7 +201,7 @@ func TestUsecases_GetUser(t *testing.T) {
 func TestUsecases_AddOrder(t *testing.T) {
 	config := mocks.NewConfig(t)
 	storage := mocks.NewStorage(t)
-	usecases, _ := New(config, storage)
+	usecases, _ := New(config, storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
 
 	tests := []struct {
 		ctx   context.Context
@@ -200,16 +247,18 @@ func TestUsecases_AddOrder(t *testing.T) {
 func TestUsecases_GetAllOrders(t *testing.T) {
 	config := mocks.NewConfig(t)
 	storage := mocks.NewStorage(t)
-	usecases, _ := New(config, storage)
+	usecases, _ := New(config, storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
 
 	tests := []struct {
-		ctx   context.Context
-		name  string
-		want  int64
-		err   error
-		u     *Usecases
-		user  entity.User
-		order []entity.Order
+		ctx        context.Context
+		name       string
+		want       int64
+		err        error
+		u          *Usecases
+		user       entity.User
+		order      []entity.Order
+		filter     entity.OrderFilter
+		wantFilter entity.OrderFilter
 	}{
 		{
 			name:  "positive",
@@ -226,14 +275,22 @@ func TestUsecases_GetAllOrders(t *testing.T) {
 			err:   errors.New(""),
 			order: []entity.Order{},
 		},
+		{
+			name:       "positive - max limit",
+			u:          usecases,
+			ctx:        context.Background(),
+			filter:     entity.OrderFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: entity.MaxPageLimit + 1}}},
+			wantFilter: entity.OrderFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: entity.MaxPageLimit}}},
+			order:      []entity.Order{},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Arrange
-			getAllOrders := storage.On("GetAllOrders", mock.Anything, mock.Anything).Return([]entity.Order{}, tt.err)
+			getAllOrders := storage.On("GetAllOrders", mock.Anything, mock.Anything, tt.wantFilter).Return([]entity.Order{}, tt.err)
 
 			// Act
-			order, err := tt.u.GetAllOrders(tt.ctx, tt.user)
+			order, err := tt.u.GetAllOrders(tt.ctx, tt.user, tt.filter)
 
 			// Assert
 			if err != nil && !errors.Is(err, tt.err) {
@@ -250,7 +307,9 @@ func TestUsecases_GetAllOrders(t *testing.T) {
 func TestUsecases_GetBalance(t *testing.T) {
 	config := mocks.NewConfig(t)
 	storage := mocks.NewStorage(t)
-	usecases, _ := New(config, storage)
+	usecases, _ := New(config, storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+	config.On("GetPointsPolicy").Return(entity.PointsPolicy{}).Maybe()
+	storage.On("GetPendingAccrual", mock.Anything, mock.Anything).Return(entity.Money(0), nil).Maybe()
 
 	tests := []struct {
 		ctx     context.Context
@@ -259,14 +318,14 @@ func TestUsecases_GetBalance(t *testing.T) {
 		err     error
 		u       *Usecases
 		user    entity.User
-		balance entity.Balance
+		balance entity.BalanceDetails
 	}{
 		{
 			name:    "positive",
 			want:    0,
 			u:       usecases,
 			ctx:     context.Background(),
-			balance: entity.Balance{},
+			balance: entity.BalanceDetails{ExpiringSoon: []entity.ExpiringPoints{}},
 		},
 		{
 			name:    "negative",
@@ -274,7 +333,7 @@ func TestUsecases_GetBalance(t *testing.T) {
 			u:       usecases,
 			ctx:     context.Background(),
 			err:     errors.New(""),
-			balance: entity.Balance{},
+			balance: entity.BalanceDetails{},
 		},
 	}
 	for _, tt := range tests {
@@ -296,10 +355,36 @@ func TestUsecases_GetBalance(t *testing.T) {
 		})
 	}
 }
+func TestUsecases_GetBalanceExpiringSoon(t *testing.T) {
+	// Arrange
+	config := mocks.NewConfig(t)
+	storage := mocks.NewStorage(t)
+	usecases, _ := New(config, storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+	user := entity.User{ID: 1}
+	now := time.Now()
+	config.On("GetPointsPolicy").Return(entity.PointsPolicy{LifetimeMonths: 12, ExpiringSoon: 30 * 24 * time.Hour})
+	storage.On("GetBalance", mock.Anything, user).Return(entity.Balance{Current: entity.NewMoney(150, 0)}, nil)
+	storage.On("GetPendingAccrual", mock.Anything, user).Return(entity.NewMoney(25, 0), nil)
+	soon := now.AddDate(-1, 0, 10)
+	storage.On("GetPointLots", mock.Anything, user).Return([]entity.PointLot{
+		{OrderNumber: "9278923470", Amount: entity.NewMoney(100, 0), Remaining: entity.NewMoney(50, 0), EarnedAt: soon},
+		{OrderNumber: "12345678903", Amount: entity.NewMoney(100, 0), Remaining: entity.NewMoney(100, 0), EarnedAt: now.AddDate(0, -1, 0)},
+	}, nil)
+
+	// Act
+	balance, err := usecases.GetBalance(context.Background(), user)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, entity.NewMoney(150, 0), balance.Current)
+	assert.Equal(t, entity.NewMoney(25, 0), balance.Pending)
+	assert.Equal(t, []entity.ExpiringPoints{{Order: "9278923470", Amount: entity.NewMoney(50, 0), ExpiresAt: soon.AddDate(0, 12, 0)}}, balance.ExpiringSoon)
+}
 func TestUsecases_WithdrawBalance(t *testing.T) {
 	config := mocks.NewConfig(t)
 	storage := mocks.NewStorage(t)
-	usecases, _ := New(config, storage)
+	usecases, _ := New(config, storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+	config.On("GetTOTPWithdrawalThreshold").Return(entity.Money(0), nil)
 
 	tests := []struct {
 		ctx     context.Context
@@ -347,16 +432,18 @@ func TestUsecases_WithdrawBalance(t *testing.T) {
 func TestUsecases_Withdrawals(t *testing.T) {
 	config := mocks.NewConfig(t)
 	storage := mocks.NewStorage(t)
-	usecases, _ := New(config, storage)
+	usecases, _ := New(config, storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
 
 	tests := []struct {
-		ctx     context.Context
-		name    string
-		want    int64
-		err     error
-		u       *Usecases
-		user    entity.User
-		balance []entity.BalanceUpdate
+		ctx        context.Context
+		name       string
+		want       int64
+		err        error
+		u          *Usecases
+		user       entity.User
+		balance    []entity.BalanceUpdate
+		filter     entity.OrderFilter
+		wantFilter entity.OrderFilter
 	}{
 		{
 			name:    "positive",
@@ -373,14 +460,22 @@ func TestUsecases_Withdrawals(t *testing.T) {
 			err:     errors.New(""),
 			balance: []entity.BalanceUpdate{},
 		},
+		{
+			name:       "positive - max limit",
+			u:          usecases,
+			ctx:        context.Background(),
+			filter:     entity.OrderFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: entity.MaxPageLimit + 1}}},
+			wantFilter: entity.OrderFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: entity.MaxPageLimit}}},
+			balance:    []entity.BalanceUpdate{},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Arrange
-			withdrawBalance := storage.On("Withdrawals", mock.Anything, mock.Anything).Return([]entity.BalanceUpdate{}, tt.err)
+			withdrawBalance := storage.On("Withdrawals", mock.Anything, mock.Anything, tt.wantFilter).Return([]entity.BalanceUpdate{}, tt.err)
 
 			// Act
-			balance, err := tt.u.Withdrawals(tt.ctx, tt.user)
+			balance, err := tt.u.Withdrawals(tt.ctx, tt.user, tt.filter)
 
 			// Assert
 			if err != nil && !errors.Is(err, tt.err) {
@@ -393,3 +488,865 @@ func TestUsecases_Withdrawals(t *testing.T) {
 		})
 	}
 }
+func TestUsecases_GetTransactions(t *testing.T) {
+	config := mocks.NewConfig(t)
+	storage := mocks.NewStorage(t)
+	usecases, _ := New(config, storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+
+	tests := []struct {
+		ctx    context.Context
+		name   string
+		err    error
+		u      *Usecases
+		user   entity.User
+		filter entity.TransactionFilter
+		want   entity.TransactionFilter
+	}{
+		{
+			name: "positive - default limit",
+			u:    usecases,
+			ctx:  context.Background(),
+			want: entity.TransactionFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: entity.DefaultPageLimit}}},
+		},
+		{
+			name:   "positive - max limit",
+			u:      usecases,
+			ctx:    context.Background(),
+			filter: entity.TransactionFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: entity.MaxPageLimit + 1}}},
+			want:   entity.TransactionFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: entity.MaxPageLimit}}},
+		},
+		{
+			name: "negative",
+			u:    usecases,
+			ctx:  context.Background(),
+			err:  errors.New(""),
+			want: entity.TransactionFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: entity.DefaultPageLimit}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			getTransactions := storage.On("GetTransactions", mock.Anything, mock.Anything, tt.want).Return([]entity.Transaction{}, tt.err)
+
+			// Act
+			transactions, err := tt.u.GetTransactions(tt.ctx, tt.user, tt.filter)
+
+			// Assert
+			if err != nil && !errors.Is(err, tt.err) {
+				t.Fatal(err)
+			}
+			assert.Equal(t, []entity.Transaction{}, transactions)
+
+			// Unset
+			getTransactions.Unset()
+		})
+	}
+}
+func TestUsecases_CheckSession(t *testing.T) {
+	user := entity.User{ID: 7, SessionID: 1}
+
+	tests := []struct {
+		name       string
+		session    entity.Session
+		storageErr error
+		touch      int
+		err        error
+	}{
+		{
+			name:    "active",
+			session: entity.Session{ID: 1, UserID: 7, LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)},
+		},
+		{
+			name:    "active not seen for a while",
+			session: entity.Session{ID: 1, UserID: 7, LastSeenAt: time.Now().Add(-time.Hour), ExpiresAt: time.Now().Add(time.Hour)},
+			touch:   1,
+		},
+		{
+			name:    "other user",
+			session: entity.Session{ID: 1, UserID: 8, LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)},
+			err:     entity.ErrSessionNotFound,
+		},
+		{
+			name:    "revoked",
+			session: entity.Session{ID: 1, UserID: 7, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: time.Now()},
+			err:     entity.ErrSessionRevoked,
+		},
+		{
+			name:    "expired",
+			session: entity.Session{ID: 1, UserID: 7, ExpiresAt: time.Now().Add(-time.Hour)},
+			err:     entity.ErrSessionRevoked,
+		},
+		{
+			name:       "not found",
+			storageErr: entity.ErrSessionNotFound,
+			err:        entity.ErrSessionNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			storage := mocks.NewStorage(t)
+			usecases, _ := New(mocks.NewConfig(t), storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+			storage.On("GetSession", mock.Anything, int64(1)).Return(tt.session, tt.storageErr)
+			if tt.touch > 0 {
+				storage.On("TouchSession", mock.Anything, int64(1), mock.Anything).Return(nil).Times(tt.touch)
+			}
+
+			// Act
+			err := usecases.CheckSession(context.Background(), user)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestUsecases_GetSessions(t *testing.T) {
+	config := mocks.NewConfig(t)
+	storage := mocks.NewStorage(t)
+	usecases, _ := New(config, storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+	user := entity.User{ID: 7, SessionID: 2}
+
+	// Arrange
+	storage.On("GetSessions", mock.Anything, user).Return([]entity.Session{{ID: 3}, {ID: 2}, {ID: 1}}, nil).Once()
+
+	// Act
+	sessions, err := usecases.GetSessions(context.Background(), user)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, true, false}, []bool{sessions[0].Current, sessions[1].Current, sessions[2].Current})
+}
+
+func TestUsecases_UserLoginRehash(t *testing.T) {
+	tests := []struct {
+		name   string
+		setErr error
+		logs   int
+	}{
+		{
+			name: "hash upgraded",
+		},
+		{
+			name:   "failed upgrade is logged",
+			setErr: errors.New("db is down"),
+			logs:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			config := mocks.NewConfig(t)
+			storage := mocks.NewStorage(t)
+			hasher := mocks.NewHasher(t)
+			core, logs := observer.New(zap.WarnLevel)
+			usecases, _ := New(config, storage, hasher, mocks.NewNotifier(t), mocks.NewOtp(t), zap.New(core))
+			user := entity.User{Login: "user1", Password: "root", IP: "127.0.0.1"}
+			config.On("GetStorageSalt").Return("xxxxxxxx", nil)
+			config.On("GetLoginPolicy").Return(entity.LoginPolicy{MaxFailures: 5, BaseDelay: time.Second, Lockout: time.Minute}, nil)
+			storage.On("GetLoginAttempts", mock.Anything, mock.Anything).Return(entity.LoginAttempts{}, nil)
+			storage.On("GetUserCredentials", mock.Anything, entity.User{Login: "user1"}).Return(entity.User{ID: 1, Password: "$2a$10$bcrypt"}, nil)
+			hasher.On("Verify", "$2a$10$bcrypt", "rootxxxxxxxx").Return(true, nil)
+			hasher.On("NeedsRehash", "$2a$10$bcrypt").Return(true)
+			hasher.On("Hash", "rootxxxxxxxx").Return("$argon2id$new", nil)
+			storage.On("SetUserPassword", mock.Anything, entity.User{ID: 1, Password: "$2a$10$bcrypt"}, "$argon2id$new").Return(tt.setErr).Once()
+			storage.On("GetTwoFactor", mock.Anything, mock.Anything).Return(entity.TwoFactor{}, nil)
+			storage.On("ResetLoginAttempts", mock.Anything, mock.Anything).Return(nil)
+
+			// Act
+			_, err := usecases.UserLogin(context.Background(), user)
+
+			// Assert
+			assert.NoError(t, err, "the login does not fail with the upgrade")
+			assert.Equal(t, tt.logs, logs.FilterMessage("usecases rehash password").Len())
+		})
+	}
+}
+
+func TestUsecases_ChangePassword(t *testing.T) {
+	user := entity.User{ID: 1, SessionID: 3}
+
+	tests := []struct {
+		name   string
+		change entity.PasswordChange
+		valid  bool
+		err    error
+	}{
+		{
+			name:   "positive",
+			change: entity.PasswordChange{OldPassword: "root", NewPassword: "Gophermart1"},
+			valid:  true,
+		},
+		{
+			name:   "wrong old password",
+			change: entity.PasswordChange{OldPassword: "toor", NewPassword: "Gophermart1"},
+			err:    entity.ErrUserLoginUnauthorized,
+		},
+		{
+			name:   "weak new password",
+			change: entity.PasswordChange{OldPassword: "root", NewPassword: "root"},
+			valid:  true,
+			err:    entity.ErrWeakPassword,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			config := mocks.NewConfig(t)
+			storage := mocks.NewStorage(t)
+			hasher := mocks.NewHasher(t)
+			usecases, _ := New(config, storage, hasher, mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+			config.On("GetStorageSalt").Return("", nil)
+			config.On("GetPasswordPolicy").Return(entity.PasswordPolicy{MinLength: 8}, nil).Maybe()
+			storage.On("GetUserCredentials", mock.Anything, entity.User{ID: 1}).Return(entity.User{ID: 1, Password: "hash"}, nil)
+			hasher.On("Verify", "hash", tt.change.OldPassword).Return(tt.valid, nil)
+			if tt.err == nil {
+				hasher.On("Hash", tt.change.NewPassword).Return("newHash", nil)
+				storage.On("SetUserPassword", mock.Anything, user, "newHash").Return(nil).Once()
+				storage.On("RevokeOtherSessions", mock.Anything, user, int64(3)).Return(nil).Once()
+			}
+
+			// Act
+			err := usecases.ChangePassword(context.Background(), user, tt.change)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestUsecases_RequestPasswordReset(t *testing.T) {
+	tests := []struct {
+		name string
+		user entity.User
+		err  error
+		sent bool
+	}{
+		{
+			name: "positive",
+			user: entity.User{ID: 1, Login: "root", Email: "root@example.com"},
+			sent: true,
+		},
+		{
+			name: "user without email",
+			user: entity.User{ID: 1, Login: "root"},
+		},
+		{
+			name: "unknown login",
+			err:  entity.ErrUserLoginUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			config := mocks.NewConfig(t)
+			storage := mocks.NewStorage(t)
+			notifier := mocks.NewNotifier(t)
+			usecases, _ := New(config, storage, mocks.NewHasher(t), notifier, mocks.NewOtp(t), zap.NewNop())
+			storage.On("GetUserCredentials", mock.Anything, entity.User{Login: "root"}).Return(tt.user, tt.err)
+
+			var token, tokenHash string
+			if tt.sent {
+				config.On("GetPasswordResetLifeTime").Return(30*time.Minute, nil)
+				storage.On("CreatePasswordReset", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					tokenHash = args.Get(1).(entity.PasswordResetToken).TokenHash
+				}).Return(nil)
+				notifier.On("SendPasswordReset", mock.Anything, tt.user, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					token = args.String(2)
+				}).Return(nil)
+			}
+
+			// Act
+			err := usecases.RequestPasswordReset(context.Background(), entity.PasswordResetRequest{Login: "root"})
+
+			// Assert
+			assert.NoError(t, err)
+			if tt.sent {
+				assert.NotEmpty(t, token)
+				assert.Equal(t, hashToken(token), tokenHash, "only the hash is stored")
+			}
+		})
+	}
+}
+
+func TestUsecases_ResetPassword(t *testing.T) {
+	user := entity.User{ID: 1, Login: "root"}
+
+	tests := []struct {
+		name  string
+		reset entity.PasswordReset
+		err   error
+	}{
+		{
+			name:  "positive",
+			reset: entity.PasswordReset{Token: "token", NewPassword: "Gophermart1"},
+		},
+		{
+			name:  "used or expired token",
+			reset: entity.PasswordReset{Token: "token", NewPassword: "Gophermart1"},
+			err:   entity.ErrResetTokenInvalid,
+		},
+		{
+			name:  "weak new password",
+			reset: entity.PasswordReset{Token: "token", NewPassword: "root"},
+			err:   entity.ErrWeakPassword,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			config := mocks.NewConfig(t)
+			storage := mocks.NewStorage(t)
+			hasher := mocks.NewHasher(t)
+			usecases, _ := New(config, storage, hasher, mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+			config.On("GetPasswordPolicy").Return(entity.PasswordPolicy{MinLength: 8}, nil)
+			if !errors.Is(tt.err, entity.ErrWeakPassword) {
+				config.On("GetStorageSalt").Return("", nil)
+				hasher.On("Hash", tt.reset.NewPassword).Return("newHash", nil)
+				storage.On("ResetPassword", mock.Anything, hashToken(tt.reset.Token), "newHash", mock.Anything).Return(user, tt.err)
+			}
+			if tt.err == nil {
+				storage.On("RevokeOtherSessions", mock.Anything, user, int64(0)).Return(nil).Once()
+			}
+
+			// Act
+			err := usecases.ResetPassword(context.Background(), tt.reset)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestUsecases_WithdrawBalanceTOTP(t *testing.T) {
+	user := entity.User{ID: 1}
+	policy := entity.LoginPolicy{MaxFailures: 5, BaseDelay: time.Second, Lockout: 15 * time.Minute}
+	twoFactor := entity.TwoFactor{Secret: "secret", Enabled: true, LastStep: 10}
+
+	tests := []struct {
+		name      string
+		balance   entity.BalanceUpdate
+		twoFactor entity.TwoFactor
+		valid     bool
+		stepErr   error
+		err       error
+		withdrawn bool
+	}{
+		{
+			name:      "below threshold",
+			balance:   entity.BalanceUpdate{Sum: entity.NewMoney(1000, 0)},
+			twoFactor: twoFactor,
+			withdrawn: true,
+		},
+		{
+			name:      "above threshold without two-factor auth",
+			balance:   entity.BalanceUpdate{Sum: entity.NewMoney(5000, 0)},
+			withdrawn: true,
+		},
+		{
+			name:      "above threshold without code",
+			balance:   entity.BalanceUpdate{Sum: entity.NewMoney(5000, 0)},
+			twoFactor: twoFactor,
+			err:       entity.ErrTOTPRequired,
+		},
+		{
+			name:      "above threshold with code",
+			balance:   entity.BalanceUpdate{Sum: entity.NewMoney(5000, 0), TOTPCode: "123456"},
+			twoFactor: twoFactor,
+			valid:     true,
+			withdrawn: true,
+		},
+		{
+			name:      "above threshold with wrong code",
+			balance:   entity.BalanceUpdate{Sum: entity.NewMoney(5000, 0), TOTPCode: "654321"},
+			twoFactor: twoFactor,
+			err:       entity.ErrInvalidTOTPCode,
+		},
+		{
+			name:      "above threshold with used code",
+			balance:   entity.BalanceUpdate{Sum: entity.NewMoney(5000, 0), TOTPCode: "123456"},
+			twoFactor: twoFactor,
+			valid:     true,
+			stepErr:   entity.ErrInvalidTOTPCode,
+			err:       entity.ErrInvalidTOTPCode,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			config := mocks.NewConfig(t)
+			storage := mocks.NewStorage(t)
+			otp := mocks.NewOtp(t)
+			usecases, _ := New(config, storage, mocks.NewHasher(t), mocks.NewNotifier(t), otp, zap.NewNop())
+			key := entity.TwoFactorAttemptsKey(user.ID)
+			config.On("GetTOTPWithdrawalThreshold").Return(entity.NewMoney(1000, 0), nil)
+			config.On("GetLoginPolicy").Return(policy, nil).Maybe()
+			storage.On("GetTwoFactor", mock.Anything, user).Return(tt.twoFactor, nil).Maybe()
+			if tt.balance.TOTPCode != "" {
+				storage.On("GetLoginAttempts", mock.Anything, key).Return(entity.LoginAttempts{}, nil)
+				otp.On("Verify", "secret", tt.balance.TOTPCode, mock.Anything).Return(int64(11), tt.valid)
+				if tt.valid {
+					storage.On("UseTOTPStep", mock.Anything, user, int64(11)).Return(tt.stepErr)
+				}
+				if tt.err == nil {
+					storage.On("ResetLoginAttempts", mock.Anything, key).Return(nil).Once()
+				} else {
+					storage.On("AddFailedLogin", mock.Anything, key, mock.Anything, mock.Anything).Return(entity.LoginAttempts{Failures: 1}, nil).Once()
+				}
+			}
+			if tt.withdrawn {
+				storage.On("WithdrawBalance", mock.Anything, tt.balance, user).Return(nil).Once()
+			}
+
+			// Act
+			err := usecases.WithdrawBalance(context.Background(), tt.balance, user)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestUsecases_UserLoginTwoFactor(t *testing.T) {
+	config := mocks.NewConfig(t)
+	storage := mocks.NewStorage(t)
+	hasher := mocks.NewHasher(t)
+	usecases, _ := New(config, storage, hasher, mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+	user := entity.User{Login: "user1", Password: "root", IP: "127.0.0.1"}
+
+	// Arrange
+	config.On("GetStorageSalt").Return("", nil)
+	config.On("GetLoginPolicy").Return(entity.LoginPolicy{MaxFailures: 5, BaseDelay: time.Second, Lockout: time.Minute}, nil)
+	storage.On("GetLoginAttempts", mock.Anything, mock.Anything).Return(entity.LoginAttempts{}, nil)
+	storage.On("GetUserCredentials", mock.Anything, entity.User{Login: "user1"}).Return(entity.User{ID: 1, Password: "hash"}, nil)
+	hasher.On("Verify", "hash", "root").Return(true, nil)
+	hasher.On("NeedsRehash", "hash").Return(false)
+	storage.On("GetTwoFactor", mock.Anything, entity.User{ID: 1, Password: "hash"}).Return(entity.TwoFactor{Secret: "secret", Enabled: true}, nil)
+	var stored entity.LoginChallenge
+	storage.On("CreateLoginChallenge", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(entity.LoginChallenge)
+	}).Return(nil).Once()
+
+	// Act
+	challenge, err := usecases.UserLogin(context.Background(), user)
+
+	// Assert
+	assert.NoError(t, err)
+	if assert.NotNil(t, challenge) {
+		assert.NotEmpty(t, challenge.Token)
+		assert.Equal(t, hashToken(challenge.Token), stored.TokenHash, "only the hash is stored")
+		assert.Equal(t, int64(1), stored.UserID)
+	}
+	storage.AssertNotCalled(t, "ResetLoginAttempts", mock.Anything, mock.Anything)
+}
+
+func TestUsecases_CompleteLogin(t *testing.T) {
+	policy := entity.LoginPolicy{MaxFailures: 5, BaseDelay: time.Second, Lockout: 15 * time.Minute}
+	device := entity.User{IP: "127.0.0.1"}
+	key := entity.TwoFactorAttemptsKey(1)
+	usable := entity.LoginChallenge{ID: 3, UserID: 1, ExpiresAt: time.Now().Add(time.Minute)}
+
+	tests := []struct {
+		name      string
+		answer    entity.LoginChallengeAnswer
+		challenge entity.LoginChallenge
+		valid     bool
+		blocked   bool
+		err       error
+	}{
+		{
+			name:      "totp code",
+			answer:    entity.LoginChallengeAnswer{Challenge: "challenge", Code: "123456"},
+			challenge: usable,
+			valid:     true,
+		},
+		{
+			name:      "recovery code",
+			answer:    entity.LoginChallengeAnswer{Challenge: "challenge", RecoveryCode: "ABCDE-FGHIJ"},
+			challenge: usable,
+			valid:     true,
+		},
+		{
+			name:      "wrong totp code",
+			answer:    entity.LoginChallengeAnswer{Challenge: "challenge", Code: "654321"},
+			challenge: usable,
+			err:       entity.ErrInvalidTOTPCode,
+		},
+		{
+			name:      "used recovery code",
+			answer:    entity.LoginChallengeAnswer{Challenge: "challenge", RecoveryCode: "abcde-fghij"},
+			challenge: usable,
+			err:       entity.ErrInvalidTOTPCode,
+		},
+		{
+			name:      "expired challenge",
+			answer:    entity.LoginChallengeAnswer{Challenge: "challenge", Code: "123456"},
+			challenge: entity.LoginChallenge{ID: 3, UserID: 1, ExpiresAt: time.Now().Add(-time.Minute)},
+			err:       entity.ErrLoginChallengeInvalid,
+		},
+		{
+			name:      "blocked between the steps",
+			answer:    entity.LoginChallengeAnswer{Challenge: "challenge", Code: "123456"},
+			challenge: usable,
+			valid:     true,
+			blocked:   true,
+			err:       entity.ErrUserBlocked,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			config := mocks.NewConfig(t)
+			storage := mocks.NewStorage(t)
+			otp := mocks.NewOtp(t)
+			usecases, _ := New(config, storage, mocks.NewHasher(t), mocks.NewNotifier(t), otp, zap.NewNop())
+			config.On("GetLoginPolicy").Return(policy, nil).Maybe()
+			storage.On("GetLoginChallenge", mock.Anything, hashToken("challenge")).Return(tt.challenge, nil)
+			storage.On("GetLoginAttempts", mock.Anything, key).Return(entity.LoginAttempts{}, nil).Maybe()
+			if tt.answer.Code != "" {
+				storage.On("GetTwoFactor", mock.Anything, entity.User{ID: 1}).Return(entity.TwoFactor{Secret: "secret", Enabled: true}, nil).Maybe()
+				otp.On("Verify", "secret", tt.answer.Code, mock.Anything).Return(int64(11), tt.valid).Maybe()
+				storage.On("UseTOTPStep", mock.Anything, entity.User{ID: 1}, int64(11)).Return(nil).Maybe()
+			}
+			if tt.answer.RecoveryCode != "" {
+				var useErr error
+				if !tt.valid {
+					useErr = entity.ErrInvalidTOTPCode
+				}
+				storage.On("UseRecoveryCode", mock.Anything, entity.User{ID: 1}, hashToken("abcdefghij")).Return(useErr)
+			}
+			if tt.valid {
+				storage.On("ResetLoginAttempts", mock.Anything, key).Return(nil).Once()
+				storage.On("GetUserCredentials", mock.Anything, entity.User{ID: 1}).Return(entity.User{ID: 1, Login: "user1", Role: entity.RoleAdmin, Blocked: tt.blocked}, nil)
+			}
+			if tt.valid && !tt.blocked {
+				storage.On("UseLoginChallenge", mock.Anything, int64(3), mock.Anything).Return(nil).Once()
+				storage.On("ResetLoginAttempts", mock.Anything, entity.LoginAttemptsKey("user1", device.IP)).Return(nil).Once()
+			}
+			if errors.Is(tt.err, entity.ErrInvalidTOTPCode) {
+				storage.On("AddFailedLogin", mock.Anything, key, mock.Anything, mock.Anything).Return(entity.LoginAttempts{Failures: 1}, nil).Once()
+			}
+
+			// Act
+			user, err := usecases.CompleteLogin(context.Background(), tt.answer, device)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err == nil {
+				assert.Equal(t, int64(1), user.ID)
+				assert.Equal(t, entity.RoleAdmin, user.Role)
+			}
+		})
+	}
+}
+
+func TestUsecases_ConfirmTwoFactor(t *testing.T) {
+	user := entity.User{ID: 1}
+
+	tests := []struct {
+		name      string
+		twoFactor entity.TwoFactor
+		valid     bool
+		err       error
+	}{
+		{
+			name:      "positive",
+			twoFactor: entity.TwoFactor{Secret: "secret"},
+			valid:     true,
+		},
+		{
+			name:      "wrong code",
+			twoFactor: entity.TwoFactor{Secret: "secret"},
+			err:       entity.ErrInvalidTOTPCode,
+		},
+		{
+			name: "not enrolled",
+			err:  entity.ErrTwoFactorNotEnrolled,
+		},
+		{
+			name:      "already enabled",
+			twoFactor: entity.TwoFactor{Secret: "secret", Enabled: true},
+			err:       entity.ErrTwoFactorAlreadyEnabled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			storage := mocks.NewStorage(t)
+			otp := mocks.NewOtp(t)
+			usecases, _ := New(mocks.NewConfig(t), storage, mocks.NewHasher(t), mocks.NewNotifier(t), otp, zap.NewNop())
+			storage.On("GetTwoFactor", mock.Anything, user).Return(tt.twoFactor, nil)
+			otp.On("Verify", "secret", "123456", mock.Anything).Return(int64(11), tt.valid).Maybe()
+			if tt.valid {
+				otp.On("GenerateRecoveryCodes", entity.RecoveryCodesCount).Return([]string{"abcde-fghij"}, nil)
+				storage.On("EnableTwoFactor", mock.Anything, user, int64(11), []string{hashToken("abcdefghij")}).Return(nil).Once()
+			}
+
+			// Act
+			codes, err := usecases.ConfirmTwoFactor(context.Background(), user, "123456")
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err == nil {
+				assert.Equal(t, []string{"abcde-fghij"}, codes.Codes)
+			}
+		})
+	}
+}
+
+func TestUsecases_UserLoginBlocked(t *testing.T) {
+	config := mocks.NewConfig(t)
+	storage := mocks.NewStorage(t)
+	hasher := mocks.NewHasher(t)
+	usecases, _ := New(config, storage, hasher, mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+	user := entity.User{Login: "user1", Password: "root", IP: "127.0.0.1"}
+
+	// Arrange
+	config.On("GetStorageSalt").Return("xxxxxxxx", nil)
+	config.On("GetLoginPolicy").Return(entity.LoginPolicy{MaxFailures: 5, BaseDelay: time.Second, Lockout: time.Minute}, nil)
+	storage.On("GetLoginAttempts", mock.Anything, mock.Anything).Return(entity.LoginAttempts{}, nil)
+	storage.On("GetUserCredentials", mock.Anything, entity.User{Login: user.Login}).Return(entity.User{ID: 1, Password: "hash", Blocked: true}, nil)
+	hasher.On("Verify", "hash", "rootxxxxxxxx").Return(true, nil)
+
+	// Act
+	challenge, err := usecases.UserLogin(context.Background(), user)
+
+	// Assert
+	assert.ErrorIs(t, err, entity.ErrUserBlocked)
+	assert.Nil(t, challenge)
+}
+
+func TestUsecases_RotateSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		blocked bool
+		err     error
+	}{
+		{
+			name: "positive",
+		},
+		{
+			name:    "blocked user",
+			blocked: true,
+			err:     entity.ErrUserBlocked,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			config := mocks.NewConfig(t)
+			storage := mocks.NewStorage(t)
+			usecases, _ := New(config, storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+			config.On("GetTokenLifeTime").Return(time.Duration(6), nil)
+			storage.On("RotateSession", mock.Anything, "oldHash", mock.Anything).Return(entity.Session{ID: 3, UserID: 7}, nil).Once()
+			storage.On("GetUserCredentials", mock.Anything, entity.User{ID: 7, SessionID: 3}).Return(entity.User{ID: 7, Role: entity.RoleAdmin, Blocked: tt.blocked}, nil).Once()
+			if tt.blocked {
+				storage.On("RevokeSession", mock.Anything, entity.User{ID: 7, SessionID: 3}, int64(3)).Return(nil).Once()
+			}
+
+			// Act
+			user, err := usecases.RotateSession(context.Background(), "oldHash", "newHash", entity.User{})
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err == nil {
+				assert.Equal(t, entity.User{ID: 7, SessionID: 3, Role: entity.RoleAdmin}, user)
+			}
+		})
+	}
+}
+
+func TestUsecases_SetUserBlocked(t *testing.T) {
+	tests := []struct {
+		name    string
+		blocked bool
+		revoke  int
+	}{
+		{
+			name:    "block",
+			blocked: true,
+			revoke:  1,
+		},
+		{
+			name: "unblock",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			storage := mocks.NewStorage(t)
+			usecases, _ := New(mocks.NewConfig(t), storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+			user := entity.User{ID: 7, Login: "user1", Blocked: tt.blocked}
+			storage.On("SetUserBlocked", mock.Anything, entity.User{Login: "user1"}, tt.blocked).Return(user, nil).Once()
+			if tt.revoke > 0 {
+				storage.On("RevokeOtherSessions", mock.Anything, user, int64(0)).Return(nil).Times(tt.revoke)
+			}
+
+			// Act
+			err := usecases.SetUserBlocked(context.Background(), "user1", tt.blocked)
+
+			// Assert
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestUsecases_AdjustBalance(t *testing.T) {
+	storage := mocks.NewStorage(t)
+	usecases, _ := New(mocks.NewConfig(t), storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+
+	// Arrange
+	storage.On("AdjustBalance", mock.Anything, entity.User{Login: "user1"}, entity.BalanceAdjustment{Amount: entity.NewMoney(100, 0), Reason: "lost order"}).Return(entity.Balance{Current: entity.NewMoney(100, 0)}, nil).Once()
+
+	// Act
+	balance, err := usecases.AdjustBalance(context.Background(), "user1", entity.BalanceAdjustment{Amount: entity.NewMoney(100, 0), Reason: " lost order "})
+	_, blankErr := usecases.AdjustBalance(context.Background(), "user1", entity.BalanceAdjustment{Amount: entity.NewMoney(100, 0), Reason: "  "})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, entity.NewMoney(100, 0), balance.Current)
+	assert.ErrorIs(t, blankErr, entity.ErrAdjustmentReasonRequired)
+}
+
+func TestUsecases_VerifyAuditLog(t *testing.T) {
+	storage := mocks.NewStorage(t)
+	usecases, _ := New(mocks.NewConfig(t), storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+
+	// Arrange
+	events := make([]entity.AuditEvent, entity.MaxPageLimit+1)
+	var prevHash string
+	for i := range events {
+		events[i] = entity.AuditEvent{ID: int64(i + 1), Action: entity.AuditLoginSucceeded, Target: entity.AuditTargetUser(1)}
+		events[i].Seal(prevHash)
+		prevHash = events[i].Hash
+	}
+	storage.On("GetAuditChain", mock.Anything, int64(0), entity.MaxPageLimit).Return(events[:entity.MaxPageLimit], nil).Once()
+	storage.On("GetAuditChain", mock.Anything, int64(entity.MaxPageLimit), entity.MaxPageLimit).Return(events[entity.MaxPageLimit:], nil).Once()
+
+	// Act
+	verification, err := usecases.VerifyAuditLog(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, verification.Valid)
+	assert.Equal(t, int64(entity.MaxPageLimit+1), verification.Checked)
+}
+
+func TestUsecases_ReserveIdempotencyKey(t *testing.T) {
+	key := entity.IdempotencyKey{UserID: 7, Key: "key1", Fingerprint: "first"}
+
+	tests := []struct {
+		name     string
+		key      entity.IdempotencyKey
+		stored   entity.IdempotencyKey
+		reserved bool
+		replay   bool
+		err      error
+	}{
+		{
+			name:     "new key",
+			key:      key,
+			reserved: true,
+		},
+		{
+			name:   "repeated request",
+			key:    key,
+			stored: entity.IdempotencyKey{UserID: 7, Key: "key1", Fingerprint: "first", StatusCode: http.StatusOK},
+			replay: true,
+		},
+		{
+			name:   "another request",
+			key:    key,
+			stored: entity.IdempotencyKey{UserID: 7, Key: "key1", Fingerprint: "second", StatusCode: http.StatusOK},
+			err:    entity.ErrIdempotencyKeyReused,
+		},
+		{
+			name:   "first request in progress",
+			key:    key,
+			stored: entity.IdempotencyKey{UserID: 7, Key: "key1", Fingerprint: "first"},
+			err:    entity.ErrIdempotencyKeyInProgress,
+		},
+		{
+			name: "too long key",
+			key:  entity.IdempotencyKey{UserID: 7, Key: strings.Repeat("k", entity.MaxIdempotencyKeyLength+1)},
+			err:  entity.ErrInvalidIdempotencyKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			storage := mocks.NewStorage(t)
+			usecases, _ := New(mocks.NewConfig(t), storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+			if !errors.Is(tt.err, entity.ErrInvalidIdempotencyKey) {
+				storage.On("ReserveIdempotencyKey", mock.Anything, mock.MatchedBy(func(k entity.IdempotencyKey) bool { return k.Key == "key1" && !k.LockedUntil.IsZero() }), mock.Anything).Return(tt.stored, tt.reserved, nil).Once()
+			}
+
+			// Act
+			stored, err := usecases.ReserveIdempotencyKey(context.Background(), tt.key)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.replay, stored != nil)
+		})
+	}
+}
+
+func TestUsecases_CompleteIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		released   bool
+	}{
+		{
+			name:       "ok",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "bad request",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "insufficient balance",
+			statusCode: http.StatusPaymentRequired,
+		},
+		{
+			name:       "unauthorized",
+			statusCode: http.StatusUnauthorized,
+			released:   true,
+		},
+		{
+			name:       "totp code required",
+			statusCode: http.StatusForbidden,
+			released:   true,
+		},
+		{
+			name:       "login blocked",
+			statusCode: http.StatusTooManyRequests,
+			released:   true,
+		},
+		{
+			name:       "server error",
+			statusCode: http.StatusInternalServerError,
+			released:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			storage := mocks.NewStorage(t)
+			usecases, _ := New(mocks.NewConfig(t), storage, mocks.NewHasher(t), mocks.NewNotifier(t), mocks.NewOtp(t), zap.NewNop())
+			key := entity.IdempotencyKey{UserID: 7, Key: "key1", StatusCode: tt.statusCode}
+			if tt.released {
+				storage.On("ReleaseIdempotencyKey", mock.Anything, key).Return(nil).Once()
+			} else {
+				storage.On("CompleteIdempotencyKey", mock.Anything, key).Return(nil).Once()
+			}
+
+			// Act
+			err := usecases.CompleteIdempotencyKey(context.Background(), key)
+
+			// Assert
+			assert.NoError(t, err)
+		})
+	}
+}
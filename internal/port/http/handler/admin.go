@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// user by login, returns entity.UserDetails
+func (h *Handler) AdminGetUser(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	user, err := h.usecase.GetUserDetails(ctx, c.Param("login"))
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler AdminGetUser usecase.GetUserDetails", err))
+		abortAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// manual balance correction, returns the new entity.Balance
+func (h *Handler) AdminAdjustBalance(c *gin.Context) {
+	ctx := c.Request.Context()
+	var adjustment entity.BalanceAdjustment
+
+	// check input data
+	if err := c.ShouldBindJSON(&adjustment); err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler AdminAdjustBalance ShouldBindJSON", err))
+		c.AbortWithError(http.StatusBadRequest, entity.ErrStatusBadRequest)
+		return
+	}
+
+	balance, err := h.usecase.AdjustBalance(ctx, c.Param("login"), adjustment)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler AdminAdjustBalance usecase.AdjustBalance", err))
+		abortAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, balance)
+}
+
+// the user can not log in and loses all sessions
+func (h *Handler) AdminBlockUser(c *gin.Context) {
+	h.setUserBlocked(c, true)
+}
+func (h *Handler) AdminUnblockUser(c *gin.Context) {
+	h.setUserBlocked(c, false)
+}
+func (h *Handler) setUserBlocked(c *gin.Context, blocked bool) {
+	ctx := c.Request.Context()
+
+	if err := h.usecase.SetUserBlocked(ctx, c.Param("login"), blocked); err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler setUserBlocked usecase.SetUserBlocked", err))
+		abortAdminError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// order by number with its owner, returns entity.OrderDetails
+func (h *Handler) AdminGetOrder(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	order, err := h.usecase.GetOrder(ctx, c.Param("number"))
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler AdminGetOrder usecase.GetOrder", err))
+		abortAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// ask the accrual system about an unfinished order again
+func (h *Handler) AdminRequeueOrder(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if err := h.usecase.RequeueOrder(ctx, c.Param("number")); err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler AdminRequeueOrder usecase.RequeueOrder", err))
+		abortAdminError(c, err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// Reverse a withdrawal whose purchase the store cancelled.
+// The sum goes back to the user, returns the reversed withdrawal
+func (h *Handler) AdminReverseWithdrawal(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	withdrawal, err := h.usecase.ReverseWithdrawal(ctx, c.Param("order"))
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler AdminReverseWithdrawal usecase.ReverseWithdrawal", err))
+		abortAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, withdrawal)
+}
+
+func abortAdminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, entity.ErrUserNotFound), errors.Is(err, entity.ErrOrderNotFound), errors.Is(err, entity.ErrWithdrawalNotFound):
+		c.AbortWithError(http.StatusNotFound, err)
+	case errors.Is(err, entity.ErrInsufficientBalance), errors.Is(err, entity.ErrOrderNotRequeueable), errors.Is(err, entity.ErrWithdrawalAlreadyReversed):
+		c.AbortWithError(http.StatusConflict, err)
+	case errors.Is(err, entity.ErrAdjustmentReasonRequired):
+		c.AbortWithError(http.StatusBadRequest, err)
+	default:
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+	}
+}
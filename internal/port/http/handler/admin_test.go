@@ -0,0 +1,297 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/korovindenis/go-market/internal/port/http/handler/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_AdminGetUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "user positive",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "user not found",
+			err:        entity.ErrUserNotFound,
+			statusCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			usecase := mocks.NewUsecase(t)
+			handler, _ := New(mocks.NewConfig(t), usecase, mocks.NewAuth(t), mocks.NewCtxinfo(t))
+			router := gin.Default()
+			router.GET("/users/:login", handler.AdminGetUser)
+
+			usecase.On("GetUserDetails", mock.Anything, "user10").Return(entity.UserDetails{ID: 7, Login: "user10", Role: entity.RoleUser}, tt.err).Once()
+
+			// Act
+			req, _ := http.NewRequest(http.MethodGet, "/users/user10", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+			if tt.statusCode == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"login":"user10"`)
+			}
+		})
+	}
+}
+
+func TestHandler_AdminAdjustBalance(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "adjust positive",
+			body:       `{"amount":100.5,"reason":"lost order"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "adjust without reason",
+			body:       `{"amount":100}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "adjust below zero",
+			body:       `{"amount":-100,"reason":"fraud"}`,
+			err:        entity.ErrInsufficientBalance,
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "adjust unknown user",
+			body:       `{"amount":100,"reason":"lost order"}`,
+			err:        entity.ErrUserNotFound,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "adjust storage error",
+			body:       `{"amount":100,"reason":"lost order"}`,
+			err:        errors.New("db is down"),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			usecase := mocks.NewUsecase(t)
+			handler, _ := New(mocks.NewConfig(t), usecase, mocks.NewAuth(t), mocks.NewCtxinfo(t))
+			router := gin.Default()
+			router.POST("/users/:login/balance/adjust", handler.AdminAdjustBalance)
+
+			if tt.statusCode != http.StatusBadRequest {
+				usecase.On("AdjustBalance", mock.Anything, "user10", mock.AnythingOfType("entity.BalanceAdjustment")).Return(entity.Balance{Current: entity.NewMoney(100, 50)}, tt.err).Once()
+			}
+
+			// Act
+			req, _ := http.NewRequest(http.MethodPost, "/users/user10/balance/adjust", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
+
+func TestHandler_AdminBlockUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		blocked    bool
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "block",
+			path:       "/users/user10/block",
+			blocked:    true,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "unblock",
+			path:       "/users/user10/unblock",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "block unknown user",
+			path:       "/users/user10/block",
+			blocked:    true,
+			err:        entity.ErrUserNotFound,
+			statusCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			usecase := mocks.NewUsecase(t)
+			handler, _ := New(mocks.NewConfig(t), usecase, mocks.NewAuth(t), mocks.NewCtxinfo(t))
+			router := gin.Default()
+			router.POST("/users/:login/block", handler.AdminBlockUser)
+			router.POST("/users/:login/unblock", handler.AdminUnblockUser)
+
+			usecase.On("SetUserBlocked", mock.Anything, "user10", tt.blocked).Return(tt.err).Once()
+
+			// Act
+			req, _ := http.NewRequest(http.MethodPost, tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
+
+func TestHandler_AdminGetOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "order positive",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "order not found",
+			err:        entity.ErrOrderNotFound,
+			statusCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			usecase := mocks.NewUsecase(t)
+			handler, _ := New(mocks.NewConfig(t), usecase, mocks.NewAuth(t), mocks.NewCtxinfo(t))
+			router := gin.Default()
+			router.GET("/orders/:number", handler.AdminGetOrder)
+
+			order := entity.OrderDetails{Order: entity.Order{Number: "12345678903", Status: entity.StatusProcessed}, UserID: 7, UserLogin: "user10"}
+			usecase.On("GetOrder", mock.Anything, "12345678903").Return(order, tt.err).Once()
+
+			// Act
+			req, _ := http.NewRequest(http.MethodGet, "/orders/12345678903", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+			if tt.statusCode == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"user_login":"user10"`)
+			}
+		})
+	}
+}
+
+func TestHandler_AdminRequeueOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "requeue positive",
+			statusCode: http.StatusAccepted,
+		},
+		{
+			name:       "requeue processed order",
+			err:        entity.ErrOrderNotRequeueable,
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "requeue unknown order",
+			err:        entity.ErrOrderNotFound,
+			statusCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			usecase := mocks.NewUsecase(t)
+			handler, _ := New(mocks.NewConfig(t), usecase, mocks.NewAuth(t), mocks.NewCtxinfo(t))
+			router := gin.Default()
+			router.POST("/orders/:number/requeue", handler.AdminRequeueOrder)
+
+			usecase.On("RequeueOrder", mock.Anything, "12345678903").Return(tt.err).Once()
+
+			// Act
+			req, _ := http.NewRequest(http.MethodPost, "/orders/12345678903/requeue", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
+
+func TestHandler_AdminReverseWithdrawal(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "reverse positive",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "reverse unknown withdrawal",
+			err:        entity.ErrWithdrawalNotFound,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "reverse twice",
+			err:        entity.ErrWithdrawalAlreadyReversed,
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "reverse storage error",
+			err:        errors.New("db is down"),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			usecase := mocks.NewUsecase(t)
+			handler, _ := New(mocks.NewConfig(t), usecase, mocks.NewAuth(t), mocks.NewCtxinfo(t))
+			router := gin.Default()
+			router.POST("/withdrawals/:order/reverse", handler.AdminReverseWithdrawal)
+
+			withdrawal := entity.BalanceUpdate{Order: "2377225624", Sum: entity.NewMoney(40, 0), Status: entity.WithdrawalReversed}
+			usecase.On("ReverseWithdrawal", mock.Anything, "2377225624").Return(withdrawal, tt.err).Once()
+
+			// Act
+			req, _ := http.NewRequest(http.MethodPost, "/withdrawals/2377225624/reverse", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+			if tt.statusCode == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"status":"REVERSED"`)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// Returns the audit log, newest first.
+// Supports limit, cursor, actor, action, target, from and to query parameters
+func (h *Handler) AdminGetAuditEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// check input data
+	var filter entity.AuditFilter
+	var err error
+	if filter.ListFilter, err = parseListFilter(c); err != nil {
+		abortBadQuery(c, "Handler AdminGetAuditEvents parseListFilter", err)
+		return
+	}
+	// the log is always paginated
+	filter.Page.Normalize()
+	if actor := c.Query("actor"); actor != "" {
+		if filter.ActorID, err = strconv.ParseInt(actor, 10, 64); err != nil {
+			abortBadQuery(c, "Handler AdminGetAuditEvents actor", err)
+			return
+		}
+	}
+	for _, value := range queryList(c, "action") {
+		action, err := entity.ParseAuditAction(value)
+		if err != nil {
+			abortBadQuery(c, "Handler AdminGetAuditEvents ParseAuditAction", err)
+			return
+		}
+		filter.Actions = append(filter.Actions, action)
+	}
+	filter.Target = c.Query("target")
+
+	events, err := h.usecase.GetAuditEvents(ctx, filter)
+	if err != nil {
+		if errors.Is(err, entity.ErrNoContent) {
+			c.Error(fmt.Errorf("%s %w", "Handler AdminGetAuditEvents usecase.GetAuditEvents ErrNoContent", err))
+			c.AbortWithError(http.StatusNoContent, entity.ErrNoContent)
+			return
+		}
+		c.Error(fmt.Errorf("%s %w", "Handler AdminGetAuditEvents usecase.GetAuditEvents", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+
+	if len(events) > 0 {
+		setNextPage(c, filter.Page, len(events), events[len(events)-1].ID)
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// check the hash chain of the audit log, returns entity.AuditVerification
+func (h *Handler) AdminVerifyAuditLog(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	verification, err := h.usecase.VerifyAuditLog(ctx)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler AdminVerifyAuditLog usecase.VerifyAuditLog", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, verification)
+}
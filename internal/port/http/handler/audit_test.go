@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/korovindenis/go-market/internal/port/http/handler/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_AdminGetAuditEvents(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		filter     entity.AuditFilter
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "audit positive",
+			filter:     entity.AuditFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: entity.DefaultPageLimit}}},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:  "audit with filter",
+			query: "?actor=7&action=login.failed,login.succeeded&target=user:7&limit=10",
+			filter: entity.AuditFilter{
+				ActorID:    7,
+				Actions:    []entity.AuditAction{entity.AuditLoginFailed, entity.AuditLoginSucceeded},
+				Target:     "user:7",
+				ListFilter: entity.ListFilter{Page: entity.Page{Limit: 10}},
+			},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "audit unknown action",
+			query:      "?action=user.deleted",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "audit wrong actor",
+			query:      "?actor=admin",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "audit no content",
+			filter:     entity.AuditFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: entity.DefaultPageLimit}}},
+			err:        entity.ErrNoContent,
+			statusCode: http.StatusNoContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			usecase := mocks.NewUsecase(t)
+			handler, _ := New(mocks.NewConfig(t), usecase, mocks.NewAuth(t), mocks.NewCtxinfo(t))
+			router := gin.Default()
+			router.GET("/audit", handler.AdminGetAuditEvents)
+
+			if tt.statusCode != http.StatusBadRequest {
+				usecase.On("GetAuditEvents", mock.Anything, tt.filter).Return([]entity.AuditEvent{{ID: 3, Action: entity.AuditLoginSucceeded}}, tt.err).Once()
+			}
+
+			// Act
+			req, _ := http.NewRequest(http.MethodGet, "/audit"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
+
+func TestHandler_AdminVerifyAuditLog(t *testing.T) {
+	usecase := mocks.NewUsecase(t)
+	handler, _ := New(mocks.NewConfig(t), usecase, mocks.NewAuth(t), mocks.NewCtxinfo(t))
+	router := gin.Default()
+	router.GET("/audit/verify", handler.AdminVerifyAuditLog)
+
+	// Arrange
+	usecase.On("VerifyAuditLog", mock.Anything).Return(entity.AuditVerification{Checked: 3, BrokenAt: 2}, nil).Once()
+
+	// Act
+	req, _ := http.NewRequest(http.MethodGet, "/audit/verify", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"checked":3,"valid":false,"broken_at":2}`, w.Body.String())
+}
@@ -3,7 +3,10 @@ package handler
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -36,29 +39,28 @@ func (h *Handler) Register(c *gin.Context) {
 			c.AbortWithError(http.StatusConflict, entity.ErrUserLoginNotUnique)
 			return
 		}
-		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
-		return
-	}
-	user.ID = userID
-
-	// generation token
-	token, err := h.auth.GenerateToken(user)
-	if err != nil {
-		c.Error(fmt.Errorf("%s %w", "Handler Register GenerateToken", err))
+		if errors.Is(err, entity.ErrWeakPassword) {
+			c.AbortWithError(http.StatusUnprocessableEntity, err)
+			return
+		}
 		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
 		return
 	}
 
-	// create and set cookie
-	cookie, err := h.createCookie(token)
+	// only the session info goes into the token, never the credentials
+	token, refreshToken, err := h.startSession(c, entity.User{
+		ID:        userID,
+		IP:        user.IP,
+		UserAgent: user.UserAgent,
+		Role:      entity.RoleUser,
+	}, "")
 	if err != nil {
-		c.Error(fmt.Errorf("%s %w", "Handler Register createCookie", err))
+		c.Error(fmt.Errorf("%s %w", "Handler Register startSession", err))
 		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
 		return
 	}
-	http.SetCookie(c.Writer, cookie)
 
-	c.Status(http.StatusOK)
+	h.respondTokens(c, token, refreshToken)
 }
 
 func (h *Handler) Login(c *gin.Context) {
@@ -78,56 +80,326 @@ func (h *Handler) Login(c *gin.Context) {
 		UserAgent: c.GetHeader("User-Agent"),
 	}
 
+	// attempt auth user, failures are counted per login and ip,
+	// an unknown login is a failure too
+	userFromReq.IP = user.IP
+	challenge, err := h.usecase.UserLogin(ctx, userFromReq)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler Login UserLogin", err))
+
+		if abortLoginBlocked(c, err) {
+			return
+		}
+		if errors.Is(err, entity.ErrUserLoginUnauthorized) {
+			c.AbortWithError(http.StatusUnauthorized, entity.ErrUserLoginUnauthorized)
+			return
+		}
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+
+	// two-factor auth, tokens are handed out by LoginTwoFactor
+	if challenge != nil {
+		c.JSON(http.StatusAccepted, challenge)
+		return
+	}
+
 	// get user from storage
 	userFromStorage, err := h.usecase.GetUser(ctx, userFromReq)
 	if err != nil {
-		c.Error(fmt.Errorf("%s %w", "Handler Register GetUser", err))
+		c.Error(fmt.Errorf("%s %w", "Handler Login GetUser", err))
 		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
 		return
 	}
 	user.ID = userFromStorage.ID
+	user.Role = userFromStorage.Role
 
-	// generation token
-	token, err := h.auth.GenerateToken(user)
+	// open session and hand out tokens
+	token, refreshToken, err := h.startSession(c, user, entity.AuditLoginSucceeded)
 	if err != nil {
-		c.Error(fmt.Errorf("%s %w", "Handler Login GenerateToken", err))
+		c.Error(fmt.Errorf("%s %w", "Handler Login startSession", err))
 		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
 		return
 	}
 
-	// attempt auth user
-	if err := h.usecase.UserLogin(ctx, userFromReq); err != nil {
-		c.Error(fmt.Errorf("%s %w", "Handler Login UserLogin", err))
+	h.respondTokens(c, token, refreshToken)
+}
 
-		if errors.Is(err, entity.ErrUserLoginUnauthorized) {
+// exchange a refresh token for a new pair of tokens
+func (h *Handler) RefreshToken(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// clients without cookies send the refresh token in the body
+	refreshToken, err := c.Cookie(h.GetRefreshTokenName())
+	if err != nil {
+		var body entity.Token
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.Error(fmt.Errorf("%s %w", "Handler RefreshToken get refresh token", err))
 			c.AbortWithError(http.StatusUnauthorized, entity.ErrUserLoginUnauthorized)
 			return
 		}
+		refreshToken = body.RefreshToken
+	}
+
+	newRefreshToken, newRefreshTokenHash, err := h.auth.GenerateRefreshToken()
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler RefreshToken GenerateRefreshToken", err))
 		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
 		return
 	}
 
-	// create and set cookie
-	cookie, err := h.createCookie(token)
+	// get user device info
+	device := entity.User{
+		IP:        c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	}
+
+	// the old refresh token stops working
+	user, err := h.usecase.RotateSession(ctx, h.auth.HashRefreshToken(refreshToken), newRefreshTokenHash, device)
 	if err != nil {
-		c.Error(fmt.Errorf("%s %w", "Handler Login createCookie", err))
+		c.Error(fmt.Errorf("%s %w", "Handler RefreshToken RotateSession", err))
+
+		if errors.Is(err, entity.ErrUserBlocked) {
+			c.AbortWithError(http.StatusForbidden, entity.ErrUserBlocked)
+			return
+		}
+		if errors.Is(err, entity.ErrSessionNotFound) {
+			c.AbortWithError(http.StatusUnauthorized, entity.ErrUserLoginUnauthorized)
+			return
+		}
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+
+	device.ID = user.ID
+	device.SessionID = user.SessionID
+	device.Role = user.Role
+	token, err := h.auth.GenerateToken(device)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler RefreshToken GenerateToken", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+	h.respondTokens(c, token, newRefreshToken)
+}
+
+// change the password, other sessions of the user are revoked
+func (h *Handler) ChangePassword(c *gin.Context) {
+	ctx := c.Request.Context()
+	var change entity.PasswordChange
+
+	// check input data
+	if err := c.ShouldBindJSON(&change); err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler ChangePassword ShouldBindJSON", err))
+		c.AbortWithError(http.StatusBadRequest, entity.ErrStatusBadRequest)
+		return
+	}
+
+	userID, err := h.ctxinfo.GetUserIDFromCtx(c)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler ChangePassword GetUserIDFromCtx", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+	sessionID, err := h.ctxinfo.GetSessionIDFromCtx(c)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler ChangePassword GetSessionIDFromCtx", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+	user := entity.User{
+		ID:        userID,
+		SessionID: sessionID,
+	}
+
+	if err := h.usecase.ChangePassword(ctx, user, change); err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler ChangePassword usecase.ChangePassword", err))
+
+		// the user is logged in, a wrong old password is not an auth failure
+		if errors.Is(err, entity.ErrUserLoginUnauthorized) {
+			c.AbortWithError(http.StatusForbidden, entity.ErrWrongPassword)
+			return
+		}
+		if errors.Is(err, entity.ErrWeakPassword) {
+			c.AbortWithError(http.StatusUnprocessableEntity, err)
+			return
+		}
 		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
 		return
 	}
-	http.SetCookie(c.Writer, cookie)
 
 	c.Status(http.StatusOK)
 }
 
-// Used during user authentication
-func (h *Handler) createCookie(token string) (*http.Cookie, error) {
-	return &http.Cookie{
-		Name:     h.GetTokenName(),
-		Value:    token,
-		Expires:  time.Now().Add(h.GetTokenLifeTime() * time.Hour),
+// send a password reset token, the answer does not tell whether the login exists
+func (h *Handler) RequestPasswordReset(c *gin.Context) {
+	ctx := c.Request.Context()
+	var request entity.PasswordResetRequest
+
+	// check input data
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler RequestPasswordReset ShouldBindJSON", err))
+		c.AbortWithError(http.StatusBadRequest, entity.ErrStatusBadRequest)
+		return
+	}
+
+	// failures are only logged, they would reveal that the login exists
+	if err := h.usecase.RequestPasswordReset(ctx, request); err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler RequestPasswordReset usecase.RequestPasswordReset", err))
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// set a new password with the token from the reset notification
+func (h *Handler) ResetPassword(c *gin.Context) {
+	ctx := c.Request.Context()
+	var reset entity.PasswordReset
+
+	// check input data
+	if err := c.ShouldBindJSON(&reset); err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler ResetPassword ShouldBindJSON", err))
+		c.AbortWithError(http.StatusBadRequest, entity.ErrStatusBadRequest)
+		return
+	}
+
+	if err := h.usecase.ResetPassword(ctx, reset); err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler ResetPassword usecase.ResetPassword", err))
+
+		if errors.Is(err, entity.ErrResetTokenInvalid) {
+			c.AbortWithError(http.StatusBadRequest, entity.ErrResetTokenInvalid)
+			return
+		}
+		if errors.Is(err, entity.ErrWeakPassword) {
+			c.AbortWithError(http.StatusUnprocessableEntity, err)
+			return
+		}
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// revoke the current session
+func (h *Handler) Logout(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, err := h.ctxinfo.GetUserIDFromCtx(c)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler Logout GetUserIDFromCtx", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+	sessionID, err := h.ctxinfo.GetSessionIDFromCtx(c)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler Logout GetSessionIDFromCtx", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+
+	if err := h.usecase.RevokeSession(ctx, entity.User{ID: userID}, sessionID); err != nil && !errors.Is(err, entity.ErrSessionNotFound) {
+		c.Error(fmt.Errorf("%s %w", "Handler Logout RevokeSession", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+
+	// drop both cookies
+	http.SetCookie(c.Writer, h.createCookie(h.GetTokenName(), "", "/", -1))
+	http.SetCookie(c.Writer, h.createCookie(h.GetRefreshTokenName(), "", refreshCookiePath, -1))
+
+	c.Status(http.StatusOK)
+}
+
+// public keys to verify access tokens, cached by verifiers for a while
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.auth.JWKS())
+}
+
+// answers blocked users and throttled logins with Retry-After,
+// returns false for other errors
+func abortLoginBlocked(c *gin.Context, err error) bool {
+	if errors.Is(err, entity.ErrUserBlocked) {
+		c.AbortWithError(http.StatusForbidden, entity.ErrUserBlocked)
+		return true
+	}
+
+	var blocked *entity.LoginBlockedError
+	if !errors.As(err, &blocked) {
+		return false
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+	if errors.Is(err, entity.ErrLoginLocked) {
+		c.AbortWithError(http.StatusLocked, entity.ErrLoginLocked)
+		return true
+	}
+	c.AbortWithError(http.StatusTooManyRequests, entity.ErrTooManyLoginAttempts)
+	return true
+}
+
+// create session for the user, returns access and refresh tokens
+// openedBy is written to the audit log with the session, registration is logged on its own
+func (h *Handler) startSession(c *gin.Context, user entity.User, openedBy entity.AuditAction) (string, string, error) {
+	refreshToken, refreshTokenHash, err := h.auth.GenerateRefreshToken()
+	if err != nil {
+		return "", "", fmt.Errorf("GenerateRefreshToken %w", err)
+	}
+
+	sessionID, err := h.usecase.CreateSession(c.Request.Context(), user, refreshTokenHash, openedBy)
+	if err != nil {
+		return "", "", fmt.Errorf("CreateSession %w", err)
+	}
+	user.SessionID = sessionID
+
+	token, err := h.auth.GenerateToken(user)
+	if err != nil {
+		return "", "", fmt.Errorf("GenerateToken %w", err)
+	}
+
+	return token, refreshToken, nil
+}
+
+// cookies are always set, the body only when the client asks for json
+func (h *Handler) respondTokens(c *gin.Context, token, refreshToken string) {
+	h.setTokenCookies(c, token, refreshToken)
+
+	if !strings.Contains(c.GetHeader("Accept"), "application/json") {
+		c.Status(http.StatusOK)
+		return
+	}
+	c.JSON(http.StatusOK, entity.Token{
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		TokenType:    entity.TokenTypeBearer,
+		ExpiresIn:    int64(h.GetAccessTokenLifeTime() / time.Second),
+	})
+}
+
+// refresh token is only sent to the token refresh endpoint,
+// logout finds the session by the access token and only drops it
+const refreshCookiePath = "/api/user/token"
+
+func (h *Handler) setTokenCookies(c *gin.Context, token, refreshToken string) {
+	http.SetCookie(c.Writer, h.createCookie(h.GetTokenName(), token, "/", h.GetAccessTokenLifeTime()))
+	http.SetCookie(c.Writer, h.createCookie(h.GetRefreshTokenName(), refreshToken, refreshCookiePath, h.GetTokenLifeTime()*time.Hour))
+}
+
+// Used during user authentication, negative lifetime removes the cookie
+func (h *Handler) createCookie(name, value, path string, lifetime time.Duration) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Expires:  time.Now().Add(lifetime),
 		HttpOnly: true,
 		SameSite: http.SameSiteStrictMode,
 		Secure:   false,
-		Path:     "/",
-	}, nil
+		Path:     path,
+	}
+	if lifetime < 0 {
+		cookie.MaxAge = -1
+	}
+
+	return cookie
 }
@@ -9,7 +9,7 @@ import (
 	"github.com/korovindenis/go-market/internal/domain/entity"
 )
 
-// Displays the user's balance, returned as entity.Balance
+// Displays the user's balance, returned as entity.BalanceDetails
 func (h *Handler) GetBalance(c *gin.Context) {
 	userID, err := h.GetUserIDFromCtx(c)
 	if err != nil {
@@ -47,7 +47,13 @@ func (h *Handler) WithdrawBalance(c *gin.Context) {
 	var balance entity.BalanceUpdate
 	if err := c.ShouldBindJSON(&balance); err != nil {
 		c.Error(fmt.Errorf("%s %w", "Handler WithdrawBalance Json", err))
-		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+
+		// the sum is well formed but can not be stored exactly
+		if errors.Is(err, entity.ErrMoneyPrecision) {
+			c.AbortWithError(http.StatusUnprocessableEntity, entity.ErrMoneyPrecision)
+			return
+		}
+		c.AbortWithError(http.StatusBadRequest, entity.ErrStatusBadRequest)
 		return
 	}
 
@@ -57,14 +63,34 @@ func (h *Handler) WithdrawBalance(c *gin.Context) {
 		c.AbortWithError(http.StatusUnprocessableEntity, entity.ErrUnprocessableEntity)
 		return
 	}
+	if err := balance.IsValidSum(); err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler WithdrawBalance IsValidSum", err))
+		c.AbortWithError(http.StatusUnprocessableEntity, entity.ErrUnprocessableEntity)
+		return
+	}
 
+	// a stored withdrawal answers 200, also to a retry
+	ctx = entity.WithIdempotencyStatus(ctx, http.StatusOK)
 	if err := h.usecase.WithdrawBalance(ctx, balance, user); err != nil {
 		if errors.Is(err, entity.ErrInsufficientBalance) {
 			c.Error(fmt.Errorf("%s %w", "Handler WithdrawBalance WithdrawBalance Insufficient Balance", err))
 			c.AbortWithError(http.StatusPaymentRequired, entity.ErrInsufficientBalance)
 			return
 		}
+		if errors.Is(err, entity.ErrWithdrawalNotUnique) {
+			c.Error(fmt.Errorf("%s %w", "Handler WithdrawBalance WithdrawBalance Not Unique", err))
+			c.AbortWithError(http.StatusConflict, entity.ErrWithdrawalNotUnique)
+			return
+		}
+		if errors.Is(err, entity.ErrTOTPRequired) || errors.Is(err, entity.ErrInvalidTOTPCode) {
+			c.Error(fmt.Errorf("%s %w", "Handler WithdrawBalance WithdrawBalance totp", err))
+			c.AbortWithError(http.StatusForbidden, err)
+			return
+		}
 		c.Error(fmt.Errorf("%s %w", "Handler WithdrawBalance WithdrawBalance", err))
+		if abortLoginBlocked(c, err) {
+			return
+		}
 		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
 		return
 	}
@@ -7,7 +7,9 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/korovindenis/go-market/internal/domain/entity"
@@ -27,14 +29,22 @@ func TestHandler_GetBalance(t *testing.T) {
 
 	tests := []struct {
 		name       string
-		balance    entity.Balance
+		balance    entity.BalanceDetails
 		statusCode int
 		err        error
 		user       entity.User
 	}{
 		{
 			name:       "get balance",
-			balance:    entity.Balance{Current: 100, Withdrawn: 100},
+			balance:    entity.BalanceDetails{Balance: entity.Balance{Current: 100, Withdrawn: 100}, Pending: 50, ExpiringSoon: []entity.ExpiringPoints{}},
+			statusCode: http.StatusOK,
+		},
+		{
+			name: "get balance expiring soon",
+			balance: entity.BalanceDetails{
+				Balance:      entity.Balance{Current: 100, Withdrawn: 100},
+				ExpiringSoon: []entity.ExpiringPoints{{Order: "9278923470", Amount: 40, ExpiresAt: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)}},
+			},
 			statusCode: http.StatusOK,
 		},
 		{
@@ -93,12 +103,22 @@ func TestHandler_WithdrawBalance(t *testing.T) {
 		{
 			name:          "withdraw balance - positive",
 			statusCode:    http.StatusOK,
-			balanceUpdate: entity.BalanceUpdate{Order: "2377225624", Sum: float64(100)},
+			balanceUpdate: entity.BalanceUpdate{Order: "2377225624", Sum: entity.NewMoney(100, 0)},
 		},
 		{
 			name:          "check Luhn",
 			statusCode:    http.StatusUnprocessableEntity,
-			balanceUpdate: entity.BalanceUpdate{Order: "1", Sum: float64(100)},
+			balanceUpdate: entity.BalanceUpdate{Order: "1", Sum: entity.NewMoney(100, 0)},
+		},
+		{
+			name:          "withdraw balance - fractional sum",
+			statusCode:    http.StatusOK,
+			balanceUpdate: entity.BalanceUpdate{Order: "2377225624", Sum: entity.NewMoney(500, 50)},
+		},
+		{
+			name:          "check sum",
+			statusCode:    http.StatusUnprocessableEntity,
+			balanceUpdate: entity.BalanceUpdate{Order: "2377225624", Sum: entity.NewMoney(-1, 0)},
 		},
 	}
 	for _, tt := range tests {
@@ -126,3 +146,114 @@ func TestHandler_WithdrawBalance(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_WithdrawBalanceTOTP(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "code required",
+			err:        entity.ErrTOTPRequired,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "wrong code",
+			err:        entity.ErrInvalidTOTPCode,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "too many wrong codes",
+			err:        &entity.LoginBlockedError{Err: entity.ErrTooManyLoginAttempts, RetryAfter: time.Second},
+			statusCode: http.StatusTooManyRequests,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			usecase := mocks.NewUsecase(t)
+			ctxInf := mocks.NewCtxinfo(t)
+			handler, _ := New(mocks.NewConfig(t), usecase, mocks.NewAuth(t), ctxInf)
+			router := gin.Default()
+			router.POST("/balance/withdraw", handler.WithdrawBalance)
+
+			balanceUpdate := entity.BalanceUpdate{Order: "2377225624", Sum: entity.NewMoney(5000, 0), TOTPCode: "123456"}
+			ctxInf.On("GetUserIDFromCtx", mock.Anything).Return(int64(1), nil)
+			usecase.On("WithdrawBalance", mock.Anything, balanceUpdate, entity.User{ID: 1}).Return(tt.err)
+
+			// Act
+			args, _ := json.Marshal(balanceUpdate)
+			req, _ := http.NewRequest(http.MethodPost, "/balance/withdraw", bytes.NewReader(args))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
+
+func TestHandler_WithdrawBalanceNotUnique(t *testing.T) {
+	// Arrange
+	usecase := mocks.NewUsecase(t)
+	ctxInf := mocks.NewCtxinfo(t)
+	handler, _ := New(mocks.NewConfig(t), usecase, mocks.NewAuth(t), ctxInf)
+	router := gin.Default()
+	router.POST("/balance/withdraw", handler.WithdrawBalance)
+
+	balanceUpdate := entity.BalanceUpdate{Order: "2377225624", Sum: entity.NewMoney(100, 0)}
+	ctxInf.On("GetUserIDFromCtx", mock.Anything).Return(int64(1), nil)
+	usecase.On("WithdrawBalance", mock.Anything, balanceUpdate, entity.User{ID: 1}).Return(entity.ErrWithdrawalNotUnique)
+
+	// Act
+	args, _ := json.Marshal(balanceUpdate)
+	req, _ := http.NewRequest(http.MethodPost, "/balance/withdraw", bytes.NewReader(args))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestHandler_WithdrawBalanceInvalidBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		statusCode int
+	}{
+		{
+			name:       "too precise sum",
+			body:       `{"order":"2377225624","sum":1.005}`,
+			statusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "malformed sum",
+			body:       `{"order":"2377225624","sum":"1,5"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid json",
+			body:       `{"order":`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ctxInf := mocks.NewCtxinfo(t)
+			handler, _ := New(mocks.NewConfig(t), mocks.NewUsecase(t), mocks.NewAuth(t), ctxInf)
+			router := gin.Default()
+			router.POST("/balance/withdraw", handler.WithdrawBalance)
+			ctxInf.On("GetUserIDFromCtx", mock.Anything).Return(int64(1), nil)
+
+			// Act
+			req, _ := http.NewRequest(http.MethodPost, "/balance/withdraw", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
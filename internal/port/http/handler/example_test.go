@@ -3,8 +3,10 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -20,6 +22,9 @@ type callTimes struct {
 	generateToken int
 	userLogin     int
 	getUser       int
+	createSession int
+	rotateSession int
+	revokeSession int
 }
 
 func Example() {
@@ -40,6 +45,8 @@ func TestHandler_AuthRegister(t *testing.T) {
 
 	config.On("GetTokenName").Return("gomarket_auth", nil)
 	config.On("GetTokenLifeTime").Return(time.Duration(6), nil)
+	config.On("GetAccessTokenLifeTime").Return(15*time.Minute, nil)
+	config.On("GetRefreshTokenName").Return("gomarket_refresh", nil)
 	router.POST("/register", handler.Register)
 
 	tests := []struct {
@@ -56,6 +63,7 @@ func TestHandler_AuthRegister(t *testing.T) {
 			callTimes: callTimes{
 				userRegister:  1,
 				generateToken: 1,
+				createSession: 1,
 			},
 		},
 		{
@@ -68,7 +76,13 @@ func TestHandler_AuthRegister(t *testing.T) {
 			// Arrange
 			args, _ := json.Marshal(tt.args)
 			userRegister := usecase.On("UserRegister", mock.Anything, tt.args).Return(int64(0), nil).Times(tt.callTimes.userRegister)
-			generateToken := auth.On("GenerateToken", tt.args).Return("newToken", nil).Times(tt.callTimes.generateToken)
+			generateRefreshToken := auth.On("GenerateRefreshToken").Return("refreshToken", "refreshHash", nil).Times(tt.callTimes.createSession)
+			// the credentials from the request never reach the session or the token
+			session := entity.User{Role: entity.RoleUser}
+			createSession := usecase.On("CreateSession", mock.Anything, session, "refreshHash", entity.AuditAction("")).Return(int64(1), nil).Times(tt.callTimes.createSession)
+			withSession := session
+			withSession.SessionID = 1
+			generateToken := auth.On("GenerateToken", withSession).Return("newToken", nil).Times(tt.callTimes.generateToken)
 
 			// Act
 			req, err := http.NewRequest(http.MethodPost, "/register", bytes.NewBuffer([]byte(args)))
@@ -84,6 +98,8 @@ func TestHandler_AuthRegister(t *testing.T) {
 
 			// Unset
 			userRegister.Unset()
+			generateRefreshToken.Unset()
+			createSession.Unset()
 			generateToken.Unset()
 		})
 	}
@@ -99,6 +115,8 @@ func TestHandler_AuthLogin(t *testing.T) {
 
 	config.On("GetTokenName").Return("gomarket_auth", nil).Maybe()
 	config.On("GetTokenLifeTime").Return(time.Duration(6), nil).Maybe()
+	config.On("GetAccessTokenLifeTime").Return(15*time.Minute, nil).Maybe()
+	config.On("GetRefreshTokenName").Return("gomarket_refresh", nil).Maybe()
 	router.POST("/login", handler.Login)
 
 	tests := []struct {
@@ -116,6 +134,7 @@ func TestHandler_AuthLogin(t *testing.T) {
 				userLogin:     1,
 				generateToken: 1,
 				getUser:       1,
+				createSession: 1,
 			},
 		},
 		{
@@ -129,7 +148,9 @@ func TestHandler_AuthLogin(t *testing.T) {
 			args, _ := json.Marshal(tt.args)
 			getUser := usecase.On("GetUser", mock.Anything, tt.args).Return(tt.args, nil).Times(tt.callTimes.getUser)
 			generateToken := auth.On("GenerateToken", mock.Anything).Return("newToken", nil).Times(tt.callTimes.generateToken)
-			userLogin := usecase.On("UserLogin", mock.Anything, tt.args).Return(nil).Maybe().Times(tt.callTimes.userLogin)
+			userLogin := usecase.On("UserLogin", mock.Anything, tt.args).Return(nil, nil).Maybe().Times(tt.callTimes.userLogin)
+			generateRefreshToken := auth.On("GenerateRefreshToken").Return("refreshToken", "refreshHash", nil).Times(tt.callTimes.createSession)
+			createSession := usecase.On("CreateSession", mock.Anything, mock.Anything, "refreshHash", entity.AuditLoginSucceeded).Return(int64(1), nil).Times(tt.callTimes.createSession)
 
 			// Act
 			req, err := http.NewRequest(http.MethodPost, "/login", bytes.NewReader(args))
@@ -145,8 +166,448 @@ func TestHandler_AuthLogin(t *testing.T) {
 
 			// Unset
 			userLogin.Unset()
+			generateRefreshToken.Unset()
+			createSession.Unset()
 			generateToken.Unset()
 			getUser.Unset()
 		})
 	}
 }
+
+func TestHandler_AuthLoginJSON(t *testing.T) {
+	config := mocks.NewConfig(t)
+	usecase := mocks.NewUsecase(t)
+	auth := mocks.NewAuth(t)
+	ctxInf := mocks.NewCtxinfo(t)
+	handler, _ := New(config, usecase, auth, ctxInf)
+	router := gin.Default()
+
+	config.On("GetTokenName").Return("gomarket_auth", nil)
+	config.On("GetTokenLifeTime").Return(time.Duration(6), nil)
+	config.On("GetAccessTokenLifeTime").Return(15*time.Minute, nil)
+	config.On("GetRefreshTokenName").Return("gomarket_refresh", nil)
+	router.POST("/login", handler.Login)
+
+	// Arrange
+	user := entity.User{Login: "user10", Password: "root"}
+	usecase.On("GetUser", mock.Anything, user).Return(entity.User{ID: 7}, nil).Once()
+	usecase.On("UserLogin", mock.Anything, user).Return(nil, nil).Once()
+	auth.On("GenerateRefreshToken").Return("refreshToken", "refreshHash", nil).Once()
+	usecase.On("CreateSession", mock.Anything, mock.Anything, "refreshHash", entity.AuditLoginSucceeded).Return(int64(1), nil).Once()
+	auth.On("GenerateToken", mock.Anything).Return("newToken", nil).Once()
+
+	// Act
+	args, _ := json.Marshal(user)
+	req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewReader(args))
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	var token entity.Token
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &token))
+	assert.Equal(t, entity.Token{AccessToken: "newToken", RefreshToken: "refreshToken", TokenType: "Bearer", ExpiresIn: 900}, token)
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 2)
+	for _, cookie := range cookies {
+		if cookie.Name == "gomarket_refresh" {
+			assert.Equal(t, "/api/user/token", cookie.Path)
+		}
+	}
+}
+
+func TestHandler_AuthLoginBlocked(t *testing.T) {
+	config := mocks.NewConfig(t)
+	usecase := mocks.NewUsecase(t)
+	auth := mocks.NewAuth(t)
+	ctxInf := mocks.NewCtxinfo(t)
+	handler, _ := New(config, usecase, auth, ctxInf)
+	router := gin.Default()
+	router.POST("/login", handler.Login)
+
+	// credentials are checked before the user is looked up
+	user := entity.User{Login: "user10", Password: "root"}
+
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+		retryAfter string
+	}{
+		{
+			name:       "login throttled",
+			err:        &entity.LoginBlockedError{Err: entity.ErrTooManyLoginAttempts, RetryAfter: 1500 * time.Millisecond},
+			statusCode: http.StatusTooManyRequests,
+			retryAfter: "2",
+		},
+		{
+			name:       "login locked",
+			err:        &entity.LoginBlockedError{Err: entity.ErrLoginLocked, RetryAfter: 15 * time.Minute},
+			statusCode: http.StatusLocked,
+			retryAfter: "900",
+		},
+		{
+			name:       "login wrong password",
+			err:        entity.ErrUserLoginUnauthorized,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "login blocked user",
+			err:        entity.ErrUserBlocked,
+			statusCode: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			userLogin := usecase.On("UserLogin", mock.Anything, user).Return(nil, tt.err).Once()
+
+			// Act
+			args, _ := json.Marshal(user)
+			req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewReader(args))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Equal(t, tt.retryAfter, w.Header().Get("Retry-After"))
+
+			// Unset
+			userLogin.Unset()
+		})
+	}
+}
+
+func TestHandler_RefreshToken(t *testing.T) {
+	config := mocks.NewConfig(t)
+	usecase := mocks.NewUsecase(t)
+	auth := mocks.NewAuth(t)
+	ctxInf := mocks.NewCtxinfo(t)
+	handler, _ := New(config, usecase, auth, ctxInf)
+	router := gin.Default()
+
+	config.On("GetTokenName").Return("gomarket_auth", nil).Maybe()
+	config.On("GetTokenLifeTime").Return(time.Duration(6), nil).Maybe()
+	config.On("GetAccessTokenLifeTime").Return(15*time.Minute, nil).Maybe()
+	config.On("GetRefreshTokenName").Return("gomarket_refresh", nil)
+	router.POST("/token/refresh", handler.RefreshToken)
+
+	tests := []struct {
+		name       string
+		cookie     *http.Cookie
+		body       string
+		rotateErr  error
+		statusCode int
+		callTimes  callTimes
+	}{
+		{
+			name:       "refresh positive",
+			cookie:     &http.Cookie{Name: "gomarket_refresh", Value: "oldRefreshToken"},
+			statusCode: http.StatusOK,
+			callTimes: callTimes{
+				rotateSession: 1,
+				generateToken: 1,
+			},
+		},
+		{
+			name:       "refresh revoked session",
+			cookie:     &http.Cookie{Name: "gomarket_refresh", Value: "oldRefreshToken"},
+			rotateErr:  entity.ErrSessionNotFound,
+			statusCode: http.StatusUnauthorized,
+			callTimes: callTimes{
+				rotateSession: 1,
+			},
+		},
+		{
+			name:       "refresh blocked user",
+			cookie:     &http.Cookie{Name: "gomarket_refresh", Value: "oldRefreshToken"},
+			rotateErr:  entity.ErrUserBlocked,
+			statusCode: http.StatusForbidden,
+			callTimes: callTimes{
+				rotateSession: 1,
+			},
+		},
+		{
+			name:       "refresh token in body",
+			body:       `{"refresh_token":"oldRefreshToken"}`,
+			statusCode: http.StatusOK,
+			callTimes: callTimes{
+				rotateSession: 1,
+				generateToken: 1,
+			},
+		},
+		{
+			name:       "refresh without cookie",
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			generateRefreshToken := auth.On("GenerateRefreshToken").Return("newRefreshToken", "newRefreshHash", nil).Times(tt.callTimes.rotateSession)
+			hashRefreshToken := auth.On("HashRefreshToken", "oldRefreshToken").Return("oldRefreshHash").Times(tt.callTimes.rotateSession)
+			rotateSession := usecase.On("RotateSession", mock.Anything, "oldRefreshHash", "newRefreshHash", entity.User{}).Return(entity.User{ID: 7, SessionID: 3, Role: entity.RoleAdmin}, tt.rotateErr).Times(tt.callTimes.rotateSession)
+			generateToken := auth.On("GenerateToken", entity.User{ID: 7, SessionID: 3, Role: entity.RoleAdmin}).Return("newToken", nil).Times(tt.callTimes.generateToken)
+
+			// Act
+			req, _ := http.NewRequest(http.MethodPost, "/token/refresh", strings.NewReader(tt.body))
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+			if tt.statusCode == http.StatusOK {
+				assert.Len(t, w.Result().Cookies(), 2)
+			}
+
+			// Unset
+			generateRefreshToken.Unset()
+			hashRefreshToken.Unset()
+			rotateSession.Unset()
+			generateToken.Unset()
+		})
+	}
+}
+
+func TestHandler_Logout(t *testing.T) {
+	config := mocks.NewConfig(t)
+	usecase := mocks.NewUsecase(t)
+	auth := mocks.NewAuth(t)
+	ctxInf := mocks.NewCtxinfo(t)
+	handler, _ := New(config, usecase, auth, ctxInf)
+	router := gin.Default()
+
+	config.On("GetTokenName").Return("gomarket_auth", nil)
+	config.On("GetRefreshTokenName").Return("gomarket_refresh", nil)
+	ctxInf.On("GetUserIDFromCtx", mock.Anything).Return(int64(7), nil)
+	ctxInf.On("GetSessionIDFromCtx", mock.Anything).Return(int64(3), nil)
+	router.POST("/logout", handler.Logout)
+
+	// Arrange
+	usecase.On("RevokeSession", mock.Anything, entity.User{ID: 7}, int64(3)).Return(nil).Once()
+
+	// Act
+	req, _ := http.NewRequest(http.MethodPost, "/logout", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	for _, cookie := range w.Result().Cookies() {
+		assert.Empty(t, cookie.Value)
+		assert.Equal(t, -1, cookie.MaxAge)
+		if cookie.Name == "gomarket_refresh" {
+			assert.Equal(t, "/api/user/token", cookie.Path)
+		}
+	}
+}
+
+func TestHandler_JWKS(t *testing.T) {
+	config := mocks.NewConfig(t)
+	usecase := mocks.NewUsecase(t)
+	auth := mocks.NewAuth(t)
+	ctxInf := mocks.NewCtxinfo(t)
+	handler, _ := New(config, usecase, auth, ctxInf)
+	router := gin.Default()
+	router.GET("/.well-known/jwks.json", handler.JWKS)
+
+	// Arrange
+	auth.On("JWKS").Return(entity.JWKS{Keys: []entity.JWK{{KeyType: "OKP", KID: "2026-10", Use: "sig", Algorithm: "EdDSA", Curve: "Ed25519", X: "x"}}}).Once()
+
+	// Act
+	req, _ := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"keys":[{"kty":"OKP","kid":"2026-10","use":"sig","alg":"EdDSA","crv":"Ed25519","x":"x"}]}`, w.Body.String())
+}
+
+func TestHandler_ChangePassword(t *testing.T) {
+	config := mocks.NewConfig(t)
+	usecase := mocks.NewUsecase(t)
+	auth := mocks.NewAuth(t)
+	ctxInf := mocks.NewCtxinfo(t)
+	handler, _ := New(config, usecase, auth, ctxInf)
+	router := gin.Default()
+	router.POST("/password", handler.ChangePassword)
+
+	ctxInf.On("GetUserIDFromCtx", mock.Anything).Return(int64(7), nil)
+	ctxInf.On("GetSessionIDFromCtx", mock.Anything).Return(int64(3), nil)
+
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		calls      int
+		statusCode int
+	}{
+		{
+			name:       "change positive",
+			body:       `{"old_password":"root","new_password":"Gophermart1"}`,
+			calls:      1,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "change wrong old password",
+			body:       `{"old_password":"toor","new_password":"Gophermart1"}`,
+			err:        entity.ErrUserLoginUnauthorized,
+			calls:      1,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "change weak password",
+			body:       `{"old_password":"root","new_password":"root"}`,
+			err:        entity.ErrWeakPassword,
+			calls:      1,
+			statusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "change wrong param",
+			body:       `{"old_password":"root"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			var change entity.PasswordChange
+			json.Unmarshal([]byte(tt.body), &change)
+			changePassword := usecase.On("ChangePassword", mock.Anything, entity.User{ID: 7, SessionID: 3}, change).Return(tt.err).Times(tt.calls)
+
+			// Act
+			req, _ := http.NewRequest(http.MethodPost, "/password", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			// Unset
+			changePassword.Unset()
+		})
+	}
+}
+
+func TestHandler_RequestPasswordReset(t *testing.T) {
+	config := mocks.NewConfig(t)
+	usecase := mocks.NewUsecase(t)
+	auth := mocks.NewAuth(t)
+	ctxInf := mocks.NewCtxinfo(t)
+	handler, _ := New(config, usecase, auth, ctxInf)
+	router := gin.Default()
+	router.POST("/password/reset-request", handler.RequestPasswordReset)
+
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		calls      int
+		statusCode int
+	}{
+		{
+			name:       "reset request positive",
+			body:       `{"login":"root"}`,
+			calls:      1,
+			statusCode: http.StatusAccepted,
+		},
+		{
+			name:       "reset request failure is not revealed",
+			body:       `{"login":"root"}`,
+			err:        errors.New("smtp is down"),
+			calls:      1,
+			statusCode: http.StatusAccepted,
+		},
+		{
+			name:       "reset request wrong param",
+			body:       `{}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			requestReset := usecase.On("RequestPasswordReset", mock.Anything, entity.PasswordResetRequest{Login: "root"}).Return(tt.err).Times(tt.calls)
+
+			// Act
+			req, _ := http.NewRequest(http.MethodPost, "/password/reset-request", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			// Unset
+			requestReset.Unset()
+		})
+	}
+}
+
+func TestHandler_ResetPassword(t *testing.T) {
+	config := mocks.NewConfig(t)
+	usecase := mocks.NewUsecase(t)
+	auth := mocks.NewAuth(t)
+	ctxInf := mocks.NewCtxinfo(t)
+	handler, _ := New(config, usecase, auth, ctxInf)
+	router := gin.Default()
+	router.POST("/password/reset", handler.ResetPassword)
+
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		calls      int
+		statusCode int
+	}{
+		{
+			name:       "reset positive",
+			body:       `{"token":"token","new_password":"Gophermart1"}`,
+			calls:      1,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "reset used token",
+			body:       `{"token":"token","new_password":"Gophermart1"}`,
+			err:        entity.ErrResetTokenInvalid,
+			calls:      1,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "reset weak password",
+			body:       `{"token":"token","new_password":"root"}`,
+			err:        entity.ErrWeakPassword,
+			calls:      1,
+			statusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "reset wrong param",
+			body:       `{"token":"token"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			var reset entity.PasswordReset
+			json.Unmarshal([]byte(tt.body), &reset)
+			resetPassword := usecase.On("ResetPassword", mock.Anything, reset).Return(tt.err).Times(tt.calls)
+
+			// Act
+			req, _ := http.NewRequest(http.MethodPost, "/password/reset", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			// Unset
+			resetPassword.Unset()
+		})
+	}
+}
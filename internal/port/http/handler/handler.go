@@ -11,32 +11,61 @@ import (
 //go:generate mockery --name usecase --exported
 type usecase interface {
 	UserRegister(ctx context.Context, user entity.User) (int64, error)
-	UserLogin(ctx context.Context, user entity.User) error
+	UserLogin(ctx context.Context, user entity.User) (*entity.LoginChallenge, error)
+	CompleteLogin(ctx context.Context, answer entity.LoginChallengeAnswer, device entity.User) (entity.User, error)
 	GetUser(ctx context.Context, userFromReq entity.User) (entity.User, error)
+	ChangePassword(ctx context.Context, user entity.User, change entity.PasswordChange) error
+	RequestPasswordReset(ctx context.Context, request entity.PasswordResetRequest) error
+	ResetPassword(ctx context.Context, reset entity.PasswordReset) error
+	EnrollTwoFactor(ctx context.Context, user entity.User) (entity.TwoFactorEnrollment, error)
+	ConfirmTwoFactor(ctx context.Context, user entity.User, code string) (entity.RecoveryCodes, error)
 
-	GetAllOrders(ctx context.Context, user entity.User) ([]entity.Order, error)
+	GetAllOrders(ctx context.Context, user entity.User, filter entity.OrderFilter) ([]entity.Order, error)
 	AddOrder(ctx context.Context, order entity.Order, user entity.User) error
 
-	GetBalance(ctx context.Context, user entity.User) (entity.Balance, error)
+	GetBalance(ctx context.Context, user entity.User) (entity.BalanceDetails, error)
 	WithdrawBalance(ctx context.Context, balance entity.BalanceUpdate, user entity.User) error
 
-	Withdrawals(ctx context.Context, user entity.User) ([]entity.BalanceUpdate, error)
+	Withdrawals(ctx context.Context, user entity.User, filter entity.OrderFilter) ([]entity.BalanceUpdate, error)
+	ReverseWithdrawal(ctx context.Context, number string) (entity.BalanceUpdate, error)
+
+	GetTransactions(ctx context.Context, user entity.User, filter entity.TransactionFilter) ([]entity.Transaction, error)
+
+	CreateSession(ctx context.Context, user entity.User, refreshTokenHash string, openedBy entity.AuditAction) (int64, error)
+	RotateSession(ctx context.Context, oldHash, newHash string, device entity.User) (entity.User, error)
+	RevokeSession(ctx context.Context, user entity.User, sessionID int64) error
+	GetSessions(ctx context.Context, user entity.User) ([]entity.Session, error)
+
+	GetUserDetails(ctx context.Context, login string) (entity.UserDetails, error)
+	SetUserBlocked(ctx context.Context, login string, blocked bool) error
+	AdjustBalance(ctx context.Context, login string, adjustment entity.BalanceAdjustment) (entity.Balance, error)
+	GetOrder(ctx context.Context, number string) (entity.OrderDetails, error)
+	RequeueOrder(ctx context.Context, number string) error
+
+	GetAuditEvents(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEvent, error)
+	VerifyAuditLog(ctx context.Context) (entity.AuditVerification, error)
 }
 
 //go:generate mockery --name auth --exported
 type auth interface {
 	GenerateToken(user entity.User) (string, error)
+	GenerateRefreshToken() (string, string, error)
+	HashRefreshToken(token string) string
+	JWKS() entity.JWKS
 }
 
 //go:generate mockery --name config --exported
 type config interface {
 	GetTokenName() string
 	GetTokenLifeTime() time.Duration
+	GetAccessTokenLifeTime() time.Duration
+	GetRefreshTokenName() string
 }
 
 //go:generate mockery --name ctxinfo --exported
 type ctxinfo interface {
 	GetUserIDFromCtx(c *gin.Context) (int64, error)
+	GetSessionIDFromCtx(c *gin.Context) (int64, error)
 }
 
 type Handler struct {
@@ -13,6 +13,41 @@ type Auth struct {
 	mock.Mock
 }
 
+// GenerateRefreshToken provides a mock function with given fields:
+func (_m *Auth) GenerateRefreshToken() (string, string, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GenerateRefreshToken")
+	}
+
+	var r0 string
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func() (string, string, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func() string); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func() error); ok {
+		r2 = rf()
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // GenerateToken provides a mock function with given fields: user
 func (_m *Auth) GenerateToken(user entity.User) (string, error) {
 	ret := _m.Called(user)
@@ -41,6 +76,42 @@ func (_m *Auth) GenerateToken(user entity.User) (string, error) {
 	return r0, r1
 }
 
+// HashRefreshToken provides a mock function with given fields: token
+func (_m *Auth) HashRefreshToken(token string) string {
+	ret := _m.Called(token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HashRefreshToken")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(token)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// JWKS provides a mock function with given fields:
+func (_m *Auth) JWKS() entity.JWKS {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for JWKS")
+	}
+
+	var r0 entity.JWKS
+	if rf, ok := ret.Get(0).(func() entity.JWKS); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(entity.JWKS)
+	}
+
+	return r0
+}
+
 // NewAuth creates a new instance of Auth. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewAuth(t interface {
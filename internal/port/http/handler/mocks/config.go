@@ -13,6 +13,42 @@ type Config struct {
 	mock.Mock
 }
 
+// GetAccessTokenLifeTime provides a mock function with given fields:
+func (_m *Config) GetAccessTokenLifeTime() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccessTokenLifeTime")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// GetRefreshTokenName provides a mock function with given fields:
+func (_m *Config) GetRefreshTokenName() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRefreshTokenName")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // GetTokenLifeTime provides a mock function with given fields:
 func (_m *Config) GetTokenLifeTime() time.Duration {
 	ret := _m.Called()
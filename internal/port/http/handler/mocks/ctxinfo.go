@@ -13,6 +13,34 @@ type Ctxinfo struct {
 	mock.Mock
 }
 
+// GetSessionIDFromCtx provides a mock function with given fields: c
+func (_m *Ctxinfo) GetSessionIDFromCtx(c *gin.Context) (int64, error) {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSessionIDFromCtx")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*gin.Context) (int64, error)); ok {
+		return rf(c)
+	}
+	if rf, ok := ret.Get(0).(func(*gin.Context) int64); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(*gin.Context) error); ok {
+		r1 = rf(c)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUserIDFromCtx provides a mock function with given fields: c
 func (_m *Ctxinfo) GetUserIDFromCtx(c *gin.Context) (int64, error) {
 	ret := _m.Called(c)
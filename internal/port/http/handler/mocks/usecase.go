@@ -33,29 +33,217 @@ func (_m *Usecase) AddOrder(ctx context.Context, order entity.Order, user entity
 	return r0
 }
 
-// GetAllOrders provides a mock function with given fields: ctx, user
-func (_m *Usecase) GetAllOrders(ctx context.Context, user entity.User) ([]entity.Order, error) {
+// AdjustBalance provides a mock function with given fields: ctx, login, adjustment
+func (_m *Usecase) AdjustBalance(ctx context.Context, login string, adjustment entity.BalanceAdjustment) (entity.Balance, error) {
+	ret := _m.Called(ctx, login, adjustment)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AdjustBalance")
+	}
+
+	var r0 entity.Balance
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, entity.BalanceAdjustment) (entity.Balance, error)); ok {
+		return rf(ctx, login, adjustment)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, entity.BalanceAdjustment) entity.Balance); ok {
+		r0 = rf(ctx, login, adjustment)
+	} else {
+		r0 = ret.Get(0).(entity.Balance)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, entity.BalanceAdjustment) error); ok {
+		r1 = rf(ctx, login, adjustment)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChangePassword provides a mock function with given fields: ctx, user, change
+func (_m *Usecase) ChangePassword(ctx context.Context, user entity.User, change entity.PasswordChange) error {
+	ret := _m.Called(ctx, user, change)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChangePassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.PasswordChange) error); ok {
+		r0 = rf(ctx, user, change)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CompleteLogin provides a mock function with given fields: ctx, answer, device
+func (_m *Usecase) CompleteLogin(ctx context.Context, answer entity.LoginChallengeAnswer, device entity.User) (entity.User, error) {
+	ret := _m.Called(ctx, answer, device)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CompleteLogin")
+	}
+
+	var r0 entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.LoginChallengeAnswer, entity.User) (entity.User, error)); ok {
+		return rf(ctx, answer, device)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.LoginChallengeAnswer, entity.User) entity.User); ok {
+		r0 = rf(ctx, answer, device)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.LoginChallengeAnswer, entity.User) error); ok {
+		r1 = rf(ctx, answer, device)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ConfirmTwoFactor provides a mock function with given fields: ctx, user, code
+func (_m *Usecase) ConfirmTwoFactor(ctx context.Context, user entity.User, code string) (entity.RecoveryCodes, error) {
+	ret := _m.Called(ctx, user, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmTwoFactor")
+	}
+
+	var r0 entity.RecoveryCodes
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, string) (entity.RecoveryCodes, error)); ok {
+		return rf(ctx, user, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, string) entity.RecoveryCodes); ok {
+		r0 = rf(ctx, user, code)
+	} else {
+		r0 = ret.Get(0).(entity.RecoveryCodes)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User, string) error); ok {
+		r1 = rf(ctx, user, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateSession provides a mock function with given fields: ctx, user, refreshTokenHash, openedBy
+func (_m *Usecase) CreateSession(ctx context.Context, user entity.User, refreshTokenHash string, openedBy entity.AuditAction) (int64, error) {
+	ret := _m.Called(ctx, user, refreshTokenHash, openedBy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateSession")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, string, entity.AuditAction) (int64, error)); ok {
+		return rf(ctx, user, refreshTokenHash, openedBy)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, string, entity.AuditAction) int64); ok {
+		r0 = rf(ctx, user, refreshTokenHash, openedBy)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User, string, entity.AuditAction) error); ok {
+		r1 = rf(ctx, user, refreshTokenHash, openedBy)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EnrollTwoFactor provides a mock function with given fields: ctx, user
+func (_m *Usecase) EnrollTwoFactor(ctx context.Context, user entity.User) (entity.TwoFactorEnrollment, error) {
 	ret := _m.Called(ctx, user)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetAllOrders")
+		panic("no return value specified for EnrollTwoFactor")
 	}
 
-	var r0 []entity.Order
+	var r0 entity.TwoFactorEnrollment
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) ([]entity.Order, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) (entity.TwoFactorEnrollment, error)); ok {
 		return rf(ctx, user)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) []entity.Order); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) entity.TwoFactorEnrollment); ok {
 		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Get(0).(entity.TwoFactorEnrollment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAllOrders provides a mock function with given fields: ctx, user, filter
+func (_m *Usecase) GetAllOrders(ctx context.Context, user entity.User, filter entity.OrderFilter) ([]entity.Order, error) {
+	ret := _m.Called(ctx, user, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAllOrders")
+	}
+
+	var r0 []entity.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.OrderFilter) ([]entity.Order, error)); ok {
+		return rf(ctx, user, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.OrderFilter) []entity.Order); ok {
+		r0 = rf(ctx, user, filter)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]entity.Order)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
-		r1 = rf(ctx, user)
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User, entity.OrderFilter) error); ok {
+		r1 = rf(ctx, user, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAuditEvents provides a mock function with given fields: ctx, filter
+func (_m *Usecase) GetAuditEvents(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEvent, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAuditEvents")
+	}
+
+	var r0 []entity.AuditEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AuditFilter) ([]entity.AuditEvent, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AuditFilter) []entity.AuditEvent); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.AuditEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.AuditFilter) error); ok {
+		r1 = rf(ctx, filter)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -64,22 +252,80 @@ func (_m *Usecase) GetAllOrders(ctx context.Context, user entity.User) ([]entity
 }
 
 // GetBalance provides a mock function with given fields: ctx, user
-func (_m *Usecase) GetBalance(ctx context.Context, user entity.User) (entity.Balance, error) {
+func (_m *Usecase) GetBalance(ctx context.Context, user entity.User) (entity.BalanceDetails, error) {
 	ret := _m.Called(ctx, user)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetBalance")
 	}
 
-	var r0 entity.Balance
+	var r0 entity.BalanceDetails
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) (entity.Balance, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) (entity.BalanceDetails, error)); ok {
 		return rf(ctx, user)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) entity.Balance); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) entity.BalanceDetails); ok {
 		r0 = rf(ctx, user)
 	} else {
-		r0 = ret.Get(0).(entity.Balance)
+		r0 = ret.Get(0).(entity.BalanceDetails)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrder provides a mock function with given fields: ctx, number
+func (_m *Usecase) GetOrder(ctx context.Context, number string) (entity.OrderDetails, error) {
+	ret := _m.Called(ctx, number)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrder")
+	}
+
+	var r0 entity.OrderDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (entity.OrderDetails, error)); ok {
+		return rf(ctx, number)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.OrderDetails); ok {
+		r0 = rf(ctx, number)
+	} else {
+		r0 = ret.Get(0).(entity.OrderDetails)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, number)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSessions provides a mock function with given fields: ctx, user
+func (_m *Usecase) GetSessions(ctx context.Context, user entity.User) ([]entity.Session, error) {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSessions")
+	}
+
+	var r0 []entity.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) ([]entity.Session, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) []entity.Session); ok {
+		r0 = rf(ctx, user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Session)
+		}
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
@@ -91,6 +337,36 @@ func (_m *Usecase) GetBalance(ctx context.Context, user entity.User) (entity.Bal
 	return r0, r1
 }
 
+// GetTransactions provides a mock function with given fields: ctx, user, filter
+func (_m *Usecase) GetTransactions(ctx context.Context, user entity.User, filter entity.TransactionFilter) ([]entity.Transaction, error) {
+	ret := _m.Called(ctx, user, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTransactions")
+	}
+
+	var r0 []entity.Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.TransactionFilter) ([]entity.Transaction, error)); ok {
+		return rf(ctx, user, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.TransactionFilter) []entity.Transaction); ok {
+		r0 = rf(ctx, user, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User, entity.TransactionFilter) error); ok {
+		r1 = rf(ctx, user, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUser provides a mock function with given fields: ctx, userFromReq
 func (_m *Usecase) GetUser(ctx context.Context, userFromReq entity.User) (entity.User, error) {
 	ret := _m.Called(ctx, userFromReq)
@@ -119,22 +395,208 @@ func (_m *Usecase) GetUser(ctx context.Context, userFromReq entity.User) (entity
 	return r0, r1
 }
 
+// GetUserDetails provides a mock function with given fields: ctx, login
+func (_m *Usecase) GetUserDetails(ctx context.Context, login string) (entity.UserDetails, error) {
+	ret := _m.Called(ctx, login)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserDetails")
+	}
+
+	var r0 entity.UserDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (entity.UserDetails, error)); ok {
+		return rf(ctx, login)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.UserDetails); ok {
+		r0 = rf(ctx, login)
+	} else {
+		r0 = ret.Get(0).(entity.UserDetails)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, login)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RequestPasswordReset provides a mock function with given fields: ctx, request
+func (_m *Usecase) RequestPasswordReset(ctx context.Context, request entity.PasswordResetRequest) error {
+	ret := _m.Called(ctx, request)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RequestPasswordReset")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.PasswordResetRequest) error); ok {
+		r0 = rf(ctx, request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RequeueOrder provides a mock function with given fields: ctx, number
+func (_m *Usecase) RequeueOrder(ctx context.Context, number string) error {
+	ret := _m.Called(ctx, number)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RequeueOrder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, number)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResetPassword provides a mock function with given fields: ctx, reset
+func (_m *Usecase) ResetPassword(ctx context.Context, reset entity.PasswordReset) error {
+	ret := _m.Called(ctx, reset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetPassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.PasswordReset) error); ok {
+		r0 = rf(ctx, reset)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReverseWithdrawal provides a mock function with given fields: ctx, number
+func (_m *Usecase) ReverseWithdrawal(ctx context.Context, number string) (entity.BalanceUpdate, error) {
+	ret := _m.Called(ctx, number)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReverseWithdrawal")
+	}
+
+	var r0 entity.BalanceUpdate
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (entity.BalanceUpdate, error)); ok {
+		return rf(ctx, number)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.BalanceUpdate); ok {
+		r0 = rf(ctx, number)
+	} else {
+		r0 = ret.Get(0).(entity.BalanceUpdate)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, number)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeSession provides a mock function with given fields: ctx, user, sessionID
+func (_m *Usecase) RevokeSession(ctx context.Context, user entity.User, sessionID int64) error {
+	ret := _m.Called(ctx, user, sessionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeSession")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, int64) error); ok {
+		r0 = rf(ctx, user, sessionID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RotateSession provides a mock function with given fields: ctx, oldHash, newHash, device
+func (_m *Usecase) RotateSession(ctx context.Context, oldHash string, newHash string, device entity.User) (entity.User, error) {
+	ret := _m.Called(ctx, oldHash, newHash, device)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotateSession")
+	}
+
+	var r0 entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, entity.User) (entity.User, error)); ok {
+		return rf(ctx, oldHash, newHash, device)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, entity.User) entity.User); ok {
+		r0 = rf(ctx, oldHash, newHash, device)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, entity.User) error); ok {
+		r1 = rf(ctx, oldHash, newHash, device)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetUserBlocked provides a mock function with given fields: ctx, login, blocked
+func (_m *Usecase) SetUserBlocked(ctx context.Context, login string, blocked bool) error {
+	ret := _m.Called(ctx, login, blocked)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetUserBlocked")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) error); ok {
+		r0 = rf(ctx, login, blocked)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UserLogin provides a mock function with given fields: ctx, user
-func (_m *Usecase) UserLogin(ctx context.Context, user entity.User) error {
+func (_m *Usecase) UserLogin(ctx context.Context, user entity.User) (*entity.LoginChallenge, error) {
 	ret := _m.Called(ctx, user)
 
 	if len(ret) == 0 {
 		panic("no return value specified for UserLogin")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) error); ok {
+	var r0 *entity.LoginChallenge
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) (*entity.LoginChallenge, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) *entity.LoginChallenge); ok {
 		r0 = rf(ctx, user)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.LoginChallenge)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // UserRegister provides a mock function with given fields: ctx, user
@@ -165,6 +627,34 @@ func (_m *Usecase) UserRegister(ctx context.Context, user entity.User) (int64, e
 	return r0, r1
 }
 
+// VerifyAuditLog provides a mock function with given fields: ctx
+func (_m *Usecase) VerifyAuditLog(ctx context.Context) (entity.AuditVerification, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyAuditLog")
+	}
+
+	var r0 entity.AuditVerification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (entity.AuditVerification, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) entity.AuditVerification); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(entity.AuditVerification)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // WithdrawBalance provides a mock function with given fields: ctx, balance, user
 func (_m *Usecase) WithdrawBalance(ctx context.Context, balance entity.BalanceUpdate, user entity.User) error {
 	ret := _m.Called(ctx, balance, user)
@@ -183,9 +673,9 @@ func (_m *Usecase) WithdrawBalance(ctx context.Context, balance entity.BalanceUp
 	return r0
 }
 
-// Withdrawals provides a mock function with given fields: ctx, user
-func (_m *Usecase) Withdrawals(ctx context.Context, user entity.User) ([]entity.BalanceUpdate, error) {
-	ret := _m.Called(ctx, user)
+// Withdrawals provides a mock function with given fields: ctx, user, filter
+func (_m *Usecase) Withdrawals(ctx context.Context, user entity.User, filter entity.OrderFilter) ([]entity.BalanceUpdate, error) {
+	ret := _m.Called(ctx, user, filter)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Withdrawals")
@@ -193,19 +683,19 @@ func (_m *Usecase) Withdrawals(ctx context.Context, user entity.User) ([]entity.
 
 	var r0 []entity.BalanceUpdate
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) ([]entity.BalanceUpdate, error)); ok {
-		return rf(ctx, user)
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.OrderFilter) ([]entity.BalanceUpdate, error)); ok {
+		return rf(ctx, user, filter)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, entity.User) []entity.BalanceUpdate); ok {
-		r0 = rf(ctx, user)
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User, entity.OrderFilter) []entity.BalanceUpdate); ok {
+		r0 = rf(ctx, user, filter)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]entity.BalanceUpdate)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
-		r1 = rf(ctx, user)
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User, entity.OrderFilter) error); ok {
+		r1 = rf(ctx, user, filter)
 	} else {
 		r1 = ret.Error(1)
 	}
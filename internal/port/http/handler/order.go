@@ -41,6 +41,8 @@ func (h *Handler) SetOrder(c *gin.Context) {
 		ID: userID,
 	}
 
+	// a stored order answers 202, also to a retry
+	ctx = entity.WithIdempotencyStatus(ctx, http.StatusAccepted)
 	if err := h.usecase.AddOrder(ctx, order, user); err != nil {
 		c.Error(fmt.Errorf("%s %w", "Handler SetOrder AddOrder", err))
 
@@ -62,7 +64,8 @@ func (h *Handler) SetOrder(c *gin.Context) {
 	c.Status(http.StatusAccepted)
 }
 
-// Returns a list of all purchases
+// Returns a list of all purchases.
+// Supports limit, cursor, status, from and to query parameters, without limit the whole list is returned
 func (h *Handler) GetAllOrders(c *gin.Context) {
 	ctx := c.Request.Context()
 	userID, err := h.GetUserIDFromCtx(c)
@@ -75,7 +78,14 @@ func (h *Handler) GetAllOrders(c *gin.Context) {
 		ID: userID,
 	}
 
-	orders, err := h.usecase.GetAllOrders(ctx, user)
+	// check input data
+	filter, err := parseOrderFilter(c)
+	if err != nil {
+		abortBadQuery(c, "Handler GetAllOrders parseOrderFilter", err)
+		return
+	}
+
+	orders, err := h.usecase.GetAllOrders(ctx, user, filter)
 	if err != nil {
 		if errors.Is(err, entity.ErrNoContent) {
 			c.Error(fmt.Errorf("%s %w", "Handler GetAllOrders usecase.GetAllOrders ErrNoContent", err))
@@ -88,5 +98,8 @@ func (h *Handler) GetAllOrders(c *gin.Context) {
 		return
 	}
 
+	if len(orders) > 0 {
+		setNextPage(c, filter.Page, len(orders), orders[len(orders)-1].ID)
+	}
 	c.JSON(http.StatusOK, orders)
 }
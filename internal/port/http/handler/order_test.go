@@ -102,14 +102,53 @@ func TestHandler_GetAllOrders(t *testing.T) {
 	}
 	tests := []struct {
 		name       string
+		query      string
+		filter     entity.OrderFilter
 		orders     []entity.Order
 		statusCode int
+		nextCursor string
 		err        CustErr
 	}{
 		{
 			name:       "orders positive",
 			statusCode: http.StatusOK,
 		},
+		{
+			name:  "orders paginated",
+			query: "?limit=2&status=NEW,PROCESSING",
+			filter: entity.OrderFilter{
+				Statuses:   []string{string(entity.StatusNew), string(entity.StatusProcessing)},
+				ListFilter: entity.ListFilter{Page: entity.Page{Limit: 2}},
+			},
+			orders:     []entity.Order{{ID: 5, Number: "9278923470"}, {ID: 3, Number: "12345678903"}},
+			statusCode: http.StatusOK,
+			nextCursor: entity.EncodeCursor(3),
+		},
+		{
+			name:       "orders last page",
+			query:      "?limit=2&cursor=" + entity.EncodeCursor(3),
+			filter:     entity.OrderFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: 2, After: 3}}},
+			orders:     []entity.Order{{ID: 1, Number: "49927398716"}},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "orders limit above the maximum",
+			query:      "?limit=5000",
+			filter:     entity.OrderFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: entity.MaxPageLimit}}},
+			orders:     fullPageOfOrders(),
+			statusCode: http.StatusOK,
+			nextCursor: entity.EncodeCursor(1),
+		},
+		{
+			name:       "orders wrong status",
+			query:      "?status=DONE",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "orders wrong date",
+			query:      "?from=yesterday",
+			statusCode: http.StatusBadRequest,
+		},
 		{
 			name:       "orders err GetAllOrders",
 			statusCode: http.StatusInternalServerError,
@@ -128,11 +167,11 @@ func TestHandler_GetAllOrders(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Arrange
-			GetAllOrders := usecase.On("GetAllOrders", mock.Anything, mock.Anything, mock.Anything).Return(tt.orders, tt.err.getOrder).Maybe()
+			GetAllOrders := usecase.On("GetAllOrders", mock.Anything, mock.Anything, tt.filter).Return(tt.orders, tt.err.getOrder).Maybe()
 			GetUserIDFromCtx := ctxInf.On("GetUserIDFromCtx", mock.Anything).Return(int64(0), tt.err.getUserFromCtx)
 
 			// Act
-			req, err := http.NewRequest(http.MethodGet, "/orders", nil)
+			req, err := http.NewRequest(http.MethodGet, "/orders"+tt.query, nil)
 			if err != tt.err.request {
 				t.Fatal(err)
 			}
@@ -142,6 +181,7 @@ func TestHandler_GetAllOrders(t *testing.T) {
 
 			// Assert
 			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Equal(t, tt.nextCursor, w.Header().Get(nextCursorHeader))
 
 			// Unset
 			GetUserIDFromCtx.Unset()
@@ -149,3 +189,12 @@ func TestHandler_GetAllOrders(t *testing.T) {
 		})
 	}
 }
+
+// a page of the maximum size, newest first
+func fullPageOfOrders() []entity.Order {
+	orders := make([]entity.Order, 0, entity.MaxPageLimit)
+	for id := entity.MaxPageLimit; id > 0; id-- {
+		orders = append(orders, entity.Order{ID: int64(id), Number: "9278923470"})
+	}
+	return orders
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// header with the cursor of the next page
+const nextCursorHeader = "X-Next-Cursor"
+
+// read limit and cursor from the query string
+func parsePage(c *gin.Context) (entity.Page, error) {
+	var page entity.Page
+
+	if limit := c.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 {
+			return page, fmt.Errorf("invalid limit %q", limit)
+		}
+		page.Limit = value
+	}
+	// the next page is built from the limit the storage applies
+	page.Clamp()
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := entity.DecodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		page.After = after
+	}
+
+	return page, nil
+}
+
+// read page and date range from the query string
+func parseListFilter(c *gin.Context) (entity.ListFilter, error) {
+	var filter entity.ListFilter
+	var err error
+
+	if filter.Page, err = parsePage(c); err != nil {
+		return filter, err
+	}
+	if filter.From, filter.To, err = parseDateRange(c); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// read page, date range and order status from the query string
+func parseOrderFilter(c *gin.Context) (entity.OrderFilter, error) {
+	return parseStatusFilter(c, func(s string) (string, error) {
+		status, err := entity.ParseOrderStatus(s)
+		return string(status), err
+	})
+}
+
+// read page, date range and withdrawal status from the query string
+func parseWithdrawalFilter(c *gin.Context) (entity.OrderFilter, error) {
+	return parseStatusFilter(c, entity.ParseWithdrawalStatus)
+}
+
+func parseStatusFilter(c *gin.Context, parseStatus func(string) (string, error)) (entity.OrderFilter, error) {
+	var filter entity.OrderFilter
+	var err error
+
+	if filter.ListFilter, err = parseListFilter(c); err != nil {
+		return filter, err
+	}
+	for _, value := range queryList(c, "status") {
+		status, err := parseStatus(value)
+		if err != nil {
+			return filter, err
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	return filter, nil
+}
+
+// read from and to (RFC 3339) from the query string
+func parseDateRange(c *gin.Context) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			return from, to, fmt.Errorf("invalid from %q", value)
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			return from, to, fmt.Errorf("invalid to %q", value)
+		}
+	}
+
+	return from, to, nil
+}
+
+// read repeated or comma separated values of a query parameter
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, value := range c.QueryArray(key) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+
+	return values
+}
+
+// a full page means there may be more, point the client to it
+func setNextPage(c *gin.Context, page entity.Page, count int, lastID int64) {
+	if !page.Full(count) {
+		return
+	}
+
+	cursor := entity.EncodeCursor(lastID)
+	query := c.Request.URL.Query()
+	query.Set("cursor", cursor)
+	next := *c.Request.URL
+	next.RawQuery = query.Encode()
+
+	c.Header(nextCursorHeader, cursor)
+	c.Header("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+}
+
+// used for malformed list parameters
+func abortBadQuery(c *gin.Context, handlerName string, err error) {
+	c.Error(fmt.Errorf("%s %w", handlerName, err))
+	c.AbortWithError(http.StatusBadRequest, entity.ErrStatusBadRequest)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// handler for get active sessions of the user
+func (h *Handler) GetSessions(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, err := h.GetUserIDFromCtx(c)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler GetSessions GetUserIDFromCtx", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+	sessionID, err := h.GetSessionIDFromCtx(c)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler GetSessions GetSessionIDFromCtx", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+	user := entity.User{
+		ID:        userID,
+		SessionID: sessionID,
+	}
+
+	sessions, err := h.usecase.GetSessions(ctx, user)
+	if err != nil {
+		if errors.Is(err, entity.ErrNoContent) {
+			c.Error(fmt.Errorf("%s %w", "Handler GetSessions usecase.GetSessions", err))
+			c.AbortWithError(http.StatusNoContent, entity.ErrNoContent)
+			return
+		}
+		c.Error(fmt.Errorf("%s %w", "Handler GetSessions usecase.GetSessions", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// handler for revoke a session of the user, e.g. on a lost device
+func (h *Handler) DeleteSession(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, err := h.GetUserIDFromCtx(c)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler DeleteSession GetUserIDFromCtx", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+	user := entity.User{
+		ID: userID,
+	}
+
+	// check input data
+	sessionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || sessionID <= 0 {
+		c.Error(fmt.Errorf("%s %q", "Handler DeleteSession invalid id", c.Param("id")))
+		c.AbortWithError(http.StatusBadRequest, entity.ErrStatusBadRequest)
+		return
+	}
+
+	if err := h.usecase.RevokeSession(ctx, user, sessionID); err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler DeleteSession usecase.RevokeSession", err))
+
+		// sessions of other users are reported as missing
+		if errors.Is(err, entity.ErrSessionNotFound) {
+			c.AbortWithError(http.StatusNotFound, entity.ErrSessionNotFound)
+			return
+		}
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
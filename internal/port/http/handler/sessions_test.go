@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/korovindenis/go-market/internal/port/http/handler/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_GetSessions(t *testing.T) {
+	config := mocks.NewConfig(t)
+	usecase := mocks.NewUsecase(t)
+	auth := mocks.NewAuth(t)
+	ctxInf := mocks.NewCtxinfo(t)
+	handler, _ := New(config, usecase, auth, ctxInf)
+	router := gin.Default()
+	router.GET("/sessions", handler.GetSessions)
+
+	ctxInf.On("GetUserIDFromCtx", mock.Anything).Return(int64(7), nil)
+	ctxInf.On("GetSessionIDFromCtx", mock.Anything).Return(int64(2), nil)
+
+	tests := []struct {
+		name       string
+		sessions   []entity.Session
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "sessions positive",
+			sessions:   []entity.Session{{ID: 2, UserAgent: "phone", Current: true}, {ID: 1, UserAgent: "laptop"}},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "sessions storage error",
+			err:        errors.New("get sessions was failed"),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			getSessions := usecase.On("GetSessions", mock.Anything, entity.User{ID: 7, SessionID: 2}).Return(tt.sessions, tt.err)
+
+			// Act
+			req, _ := http.NewRequest(http.MethodGet, "/sessions", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+			if tt.statusCode == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"current":true`)
+			}
+
+			// Unset
+			getSessions.Unset()
+		})
+	}
+}
+
+func TestHandler_DeleteSession(t *testing.T) {
+	config := mocks.NewConfig(t)
+	usecase := mocks.NewUsecase(t)
+	auth := mocks.NewAuth(t)
+	ctxInf := mocks.NewCtxinfo(t)
+	handler, _ := New(config, usecase, auth, ctxInf)
+	router := gin.Default()
+	router.DELETE("/sessions/:id", handler.DeleteSession)
+
+	ctxInf.On("GetUserIDFromCtx", mock.Anything).Return(int64(7), nil)
+
+	tests := []struct {
+		name       string
+		id         string
+		revoke     int
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "delete positive",
+			id:         "3",
+			revoke:     1,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "delete unknown or foreign session",
+			id:         "3",
+			revoke:     1,
+			err:        entity.ErrSessionNotFound,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "delete wrong id",
+			id:         "abc",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			revokeSession := usecase.On("RevokeSession", mock.Anything, entity.User{ID: 7}, int64(3)).Return(tt.err).Times(tt.revoke)
+
+			// Act
+			req, _ := http.NewRequest(http.MethodDelete, "/sessions/"+tt.id, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			// Unset
+			revokeSession.Unset()
+		})
+	}
+}
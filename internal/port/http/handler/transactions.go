@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// Returns accruals, withdrawals and adjustments as one feed, newest first.
+// Supports limit, cursor, type, from and to query parameters
+func (h *Handler) GetTransactions(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, err := h.GetUserIDFromCtx(c)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler GetTransactions GetUserIDFromCtx", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+	user := entity.User{
+		ID: userID,
+	}
+
+	// check input data
+	var filter entity.TransactionFilter
+	if filter.ListFilter, err = parseListFilter(c); err != nil {
+		abortBadQuery(c, "Handler GetTransactions parseListFilter", err)
+		return
+	}
+	// the feed is always paginated
+	filter.Page.Normalize()
+	for _, value := range queryList(c, "type") {
+		kind, err := entity.ParseLedgerKind(value)
+		if err != nil {
+			abortBadQuery(c, "Handler GetTransactions ParseLedgerKind", err)
+			return
+		}
+		filter.Types = append(filter.Types, kind)
+	}
+
+	transactions, err := h.usecase.GetTransactions(ctx, user, filter)
+	if err != nil {
+		if errors.Is(err, entity.ErrNoContent) {
+			c.Error(fmt.Errorf("%s %w", "Handler GetTransactions usecase.GetTransactions ErrNoContent", err))
+			c.AbortWithError(http.StatusNoContent, entity.ErrNoContent)
+			return
+		}
+		c.Error(fmt.Errorf("%s %w", "Handler GetTransactions usecase.GetTransactions", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+
+	if len(transactions) > 0 {
+		setNextPage(c, filter.Page, len(transactions), transactions[len(transactions)-1].ID)
+	}
+	c.JSON(http.StatusOK, transactions)
+}
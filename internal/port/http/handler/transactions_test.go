@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/korovindenis/go-market/internal/port/http/handler/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_GetTransactions(t *testing.T) {
+	config := mocks.NewConfig(t)
+	usecase := mocks.NewUsecase(t)
+	auth := mocks.NewAuth(t)
+	ctxInf := mocks.NewCtxinfo(t)
+	handler, _ := New(config, usecase, auth, ctxInf)
+	router := gin.Default()
+	router.GET("/transactions", handler.GetTransactions)
+
+	from, _ := time.Parse(time.RFC3339, "2023-12-01T00:00:00Z")
+
+	type CustErr struct {
+		getTransactions error
+		getUserFromCtx  error
+	}
+	tests := []struct {
+		name         string
+		query        string
+		filter       entity.TransactionFilter
+		transactions []entity.Transaction
+		statusCode   int
+		nextCursor   string
+		err          CustErr
+	}{
+		{
+			name:       "transactions positive",
+			query:      "",
+			filter:     entity.TransactionFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: entity.DefaultPageLimit}}},
+			statusCode: http.StatusOK,
+			transactions: []entity.Transaction{
+				{ID: 2, Type: entity.LedgerWithdrawal, Amount: entity.NewMoney(-100, 0), Balance: entity.NewMoney(400, 0)},
+				{ID: 1, Type: entity.LedgerAccrual, Amount: entity.NewMoney(500, 0), Balance: entity.NewMoney(500, 0)},
+			},
+		},
+		{
+			name:  "transactions with filter and next page",
+			query: "?limit=1&type=ACCRUAL,ADJUSTMENT&from=2023-12-01T00:00:00Z&cursor=" + entity.EncodeCursor(10),
+			filter: entity.TransactionFilter{
+				Types: []entity.LedgerKind{entity.LedgerAccrual, entity.LedgerAdjustment},
+				ListFilter: entity.ListFilter{
+					From: from,
+					Page: entity.Page{Limit: 1, After: 10},
+				},
+			},
+			statusCode: http.StatusOK,
+			transactions: []entity.Transaction{
+				{ID: 7, Type: entity.LedgerAccrual, Amount: entity.NewMoney(500, 0), Balance: entity.NewMoney(500, 0)},
+			},
+			nextCursor: entity.EncodeCursor(7),
+		},
+		{
+			name:       "transactions wrong type",
+			query:      "?type=GIFT",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "transactions wrong cursor",
+			query:      "?cursor=not-a-cursor",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "transactions no content",
+			filter:     entity.TransactionFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: entity.DefaultPageLimit}}},
+			statusCode: http.StatusNoContent,
+			err: CustErr{
+				getTransactions: entity.ErrNoContent,
+			},
+		},
+		{
+			name:       "transactions err GetUserIDFromCtx",
+			statusCode: http.StatusInternalServerError,
+			err: CustErr{
+				getUserFromCtx: errors.New("get id was failed"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			getTransactions := usecase.On("GetTransactions", mock.Anything, mock.Anything, tt.filter).Return(tt.transactions, tt.err.getTransactions).Maybe()
+			getUserIDFromCtx := ctxInf.On("GetUserIDFromCtx", mock.Anything).Return(int64(0), tt.err.getUserFromCtx)
+
+			// Act
+			req, err := http.NewRequest(http.MethodGet, "/transactions"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Equal(t, tt.nextCursor, w.Header().Get(nextCursorHeader))
+
+			// Unset
+			getTransactions.Unset()
+			getUserIDFromCtx.Unset()
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+)
+
+// start the totp enrolment, returns entity.TwoFactorEnrollment
+func (h *Handler) EnrollTwoFactor(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, err := h.ctxinfo.GetUserIDFromCtx(c)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler EnrollTwoFactor GetUserIDFromCtx", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+
+	enrollment, err := h.usecase.EnrollTwoFactor(ctx, entity.User{ID: userID})
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler EnrollTwoFactor usecase.EnrollTwoFactor", err))
+
+		if errors.Is(err, entity.ErrTwoFactorAlreadyEnabled) {
+			c.AbortWithError(http.StatusConflict, entity.ErrTwoFactorAlreadyEnabled)
+			return
+		}
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// enable two-factor auth with the first code, returns entity.RecoveryCodes
+func (h *Handler) ConfirmTwoFactor(c *gin.Context) {
+	ctx := c.Request.Context()
+	var code entity.TwoFactorCode
+
+	// check input data
+	if err := c.ShouldBindJSON(&code); err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler ConfirmTwoFactor ShouldBindJSON", err))
+		c.AbortWithError(http.StatusBadRequest, entity.ErrStatusBadRequest)
+		return
+	}
+
+	userID, err := h.ctxinfo.GetUserIDFromCtx(c)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler ConfirmTwoFactor GetUserIDFromCtx", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+
+	recoveryCodes, err := h.usecase.ConfirmTwoFactor(ctx, entity.User{ID: userID}, code.Code)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler ConfirmTwoFactor usecase.ConfirmTwoFactor", err))
+
+		switch {
+		case errors.Is(err, entity.ErrTwoFactorAlreadyEnabled), errors.Is(err, entity.ErrTwoFactorNotEnrolled):
+			c.AbortWithError(http.StatusConflict, err)
+		case errors.Is(err, entity.ErrInvalidTOTPCode):
+			c.AbortWithError(http.StatusUnprocessableEntity, entity.ErrInvalidTOTPCode)
+		default:
+			c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, recoveryCodes)
+}
+
+// second login step with the challenge from Login
+func (h *Handler) LoginTwoFactor(c *gin.Context) {
+	ctx := c.Request.Context()
+	var answer entity.LoginChallengeAnswer
+
+	// check input data
+	if err := c.ShouldBindJSON(&answer); err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler LoginTwoFactor ShouldBindJSON", err))
+		c.AbortWithError(http.StatusBadRequest, entity.ErrStatusBadRequest)
+		return
+	}
+
+	// get user device info
+	device := entity.User{
+		IP:        c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	}
+
+	user, err := h.usecase.CompleteLogin(ctx, answer, device)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler LoginTwoFactor CompleteLogin", err))
+
+		if abortLoginBlocked(c, err) {
+			return
+		}
+		if errors.Is(err, entity.ErrLoginChallengeInvalid) || errors.Is(err, entity.ErrInvalidTOTPCode) {
+			c.AbortWithError(http.StatusUnauthorized, entity.ErrUserLoginUnauthorized)
+			return
+		}
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+	device.ID = user.ID
+	device.Role = user.Role
+
+	// open session and hand out tokens
+	token, refreshToken, err := h.startSession(c, device, entity.AuditLoginSucceeded)
+	if err != nil {
+		c.Error(fmt.Errorf("%s %w", "Handler LoginTwoFactor startSession", err))
+		c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+		return
+	}
+
+	h.respondTokens(c, token, refreshToken)
+}
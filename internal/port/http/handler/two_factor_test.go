@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/korovindenis/go-market/internal/domain/entity"
+	"github.com/korovindenis/go-market/internal/port/http/handler/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_AuthLoginChallenge(t *testing.T) {
+	usecase := mocks.NewUsecase(t)
+	handler, _ := New(mocks.NewConfig(t), usecase, mocks.NewAuth(t), mocks.NewCtxinfo(t))
+	router := gin.Default()
+	router.POST("/login", handler.Login)
+
+	// Arrange
+	user := entity.User{Login: "user10", Password: "root"}
+	expiresAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	usecase.On("UserLogin", mock.Anything, mock.Anything).Return(&entity.LoginChallenge{Token: "challenge", ExpiresAt: expiresAt}, nil).Once()
+
+	// Act
+	args, _ := json.Marshal(user)
+	req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewReader(args))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"challenge":"challenge","expires_at":"2026-10-18T12:00:00Z"}`, w.Body.String())
+	assert.Empty(t, w.Result().Cookies(), "no tokens before the second step")
+}
+
+func TestHandler_LoginTwoFactor(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "totp code",
+			body:       `{"challenge":"challenge","code":"123456"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "recovery code",
+			body:       `{"challenge":"challenge","recovery_code":"abcde-fghij"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "wrong code",
+			body:       `{"challenge":"challenge","code":"654321"}`,
+			err:        entity.ErrInvalidTOTPCode,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "expired challenge",
+			body:       `{"challenge":"challenge","code":"123456"}`,
+			err:        entity.ErrLoginChallengeInvalid,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "locked",
+			body:       `{"challenge":"challenge","code":"123456"}`,
+			err:        &entity.LoginBlockedError{Err: entity.ErrLoginLocked, RetryAfter: time.Minute},
+			statusCode: http.StatusLocked,
+		},
+		{
+			name:       "without code",
+			body:       `{"challenge":"challenge"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			config := mocks.NewConfig(t)
+			usecase := mocks.NewUsecase(t)
+			auth := mocks.NewAuth(t)
+			handler, _ := New(config, usecase, auth, mocks.NewCtxinfo(t))
+			router := gin.Default()
+			router.POST("/login/2fa", handler.LoginTwoFactor)
+
+			var answer entity.LoginChallengeAnswer
+			json.Unmarshal([]byte(tt.body), &answer)
+			if tt.statusCode != http.StatusBadRequest {
+				usecase.On("CompleteLogin", mock.Anything, answer, mock.Anything).Return(entity.User{ID: 7}, tt.err).Once()
+			}
+			if tt.statusCode == http.StatusOK {
+				config.On("GetTokenName").Return("gomarket_auth", nil)
+				config.On("GetTokenLifeTime").Return(time.Duration(6), nil)
+				config.On("GetAccessTokenLifeTime").Return(15*time.Minute, nil)
+				config.On("GetRefreshTokenName").Return("gomarket_refresh", nil)
+				auth.On("GenerateRefreshToken").Return("refreshToken", "refreshHash", nil).Once()
+				usecase.On("CreateSession", mock.Anything, mock.MatchedBy(func(user entity.User) bool { return user.ID == 7 }), "refreshHash", entity.AuditLoginSucceeded).Return(int64(1), nil).Once()
+				auth.On("GenerateToken", mock.Anything).Return("newToken", nil).Once()
+			}
+
+			// Act
+			req, _ := http.NewRequest(http.MethodPost, "/login/2fa", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+			if tt.statusCode == http.StatusOK {
+				assert.Len(t, w.Result().Cookies(), 2)
+			}
+		})
+	}
+}
+
+func TestHandler_EnrollTwoFactor(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "enroll positive",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "enroll already enabled",
+			err:        entity.ErrTwoFactorAlreadyEnabled,
+			statusCode: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			usecase := mocks.NewUsecase(t)
+			ctxInf := mocks.NewCtxinfo(t)
+			handler, _ := New(mocks.NewConfig(t), usecase, mocks.NewAuth(t), ctxInf)
+			router := gin.Default()
+			router.POST("/2fa/enroll", handler.EnrollTwoFactor)
+
+			enrollment := entity.TwoFactorEnrollment{Secret: "JBSWY3DPEHPK3PXP", URI: "otpauth://totp/gomarket:root?secret=JBSWY3DPEHPK3PXP"}
+			ctxInf.On("GetUserIDFromCtx", mock.Anything).Return(int64(7), nil)
+			usecase.On("EnrollTwoFactor", mock.Anything, entity.User{ID: 7}).Return(enrollment, tt.err)
+
+			// Act
+			req, _ := http.NewRequest(http.MethodPost, "/2fa/enroll", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+			if tt.err == nil {
+				var got entity.TwoFactorEnrollment
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+				assert.Equal(t, enrollment, got)
+			}
+		})
+	}
+}
+
+func TestHandler_ConfirmTwoFactor(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "confirm positive",
+			body:       `{"code":"123456"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "confirm wrong code",
+			body:       `{"code":"654321"}`,
+			err:        entity.ErrInvalidTOTPCode,
+			statusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "confirm not enrolled",
+			body:       `{"code":"123456"}`,
+			err:        entity.ErrTwoFactorNotEnrolled,
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "confirm wrong param",
+			body:       `{}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			usecase := mocks.NewUsecase(t)
+			ctxInf := mocks.NewCtxinfo(t)
+			handler, _ := New(mocks.NewConfig(t), usecase, mocks.NewAuth(t), ctxInf)
+			router := gin.Default()
+			router.POST("/2fa/confirm", handler.ConfirmTwoFactor)
+
+			var code entity.TwoFactorCode
+			json.Unmarshal([]byte(tt.body), &code)
+			if tt.statusCode != http.StatusBadRequest {
+				ctxInf.On("GetUserIDFromCtx", mock.Anything).Return(int64(7), nil)
+				usecase.On("ConfirmTwoFactor", mock.Anything, entity.User{ID: 7}, code.Code).Return(entity.RecoveryCodes{Codes: []string{"abcde-fghij"}}, tt.err)
+			}
+
+			// Act
+			req, _ := http.NewRequest(http.MethodPost, "/2fa/confirm", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.statusCode, w.Code)
+			if tt.err == nil && tt.statusCode == http.StatusOK {
+				assert.JSONEq(t, `{"recovery_codes":["abcde-fghij"]}`, w.Body.String())
+			}
+		})
+	}
+}
@@ -9,7 +9,8 @@ import (
 	"github.com/korovindenis/go-market/internal/domain/entity"
 )
 
-// handler for get Withdrawals
+// handler for get Withdrawals.
+// Supports limit, cursor, status (PROCESSED or REVERSED), from and to query parameters, without limit the whole list is returned
 func (h *Handler) Withdrawals(c *gin.Context) {
 	ctx := c.Request.Context()
 	userID, err := h.GetUserIDFromCtx(c)
@@ -22,7 +23,14 @@ func (h *Handler) Withdrawals(c *gin.Context) {
 		ID: userID,
 	}
 
-	withdrawals, err := h.usecase.Withdrawals(ctx, user)
+	// check input data
+	filter, err := parseWithdrawalFilter(c)
+	if err != nil {
+		abortBadQuery(c, "Handler Withdrawals parseWithdrawalFilter", err)
+		return
+	}
+
+	withdrawals, err := h.usecase.Withdrawals(ctx, user, filter)
 	if err != nil {
 		if errors.Is(err, entity.ErrNoContent) {
 			c.Error(fmt.Errorf("%s %w", "Handler Withdrawals usecase.Withdrawals", err))
@@ -34,5 +42,8 @@ func (h *Handler) Withdrawals(c *gin.Context) {
 		return
 	}
 
+	if len(withdrawals) > 0 {
+		setNextPage(c, filter.Page, len(withdrawals), withdrawals[len(withdrawals)-1].ID)
+	}
 	c.JSON(http.StatusOK, withdrawals)
 }
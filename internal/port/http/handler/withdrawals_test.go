@@ -29,14 +29,49 @@ func TestHandler_Withdrawals(t *testing.T) {
 	}
 	tests := []struct {
 		name       string
+		query      string
+		filter     entity.OrderFilter
 		orders     []entity.BalanceUpdate
 		statusCode int
+		nextCursor string
 		err        CustErr
 	}{
 		{
 			name:       "withdrawals positive",
 			statusCode: http.StatusOK,
 		},
+		{
+			name:       "withdrawals paginated",
+			query:      "?limit=1",
+			filter:     entity.OrderFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: 1}}},
+			orders:     []entity.BalanceUpdate{{ID: 4, Order: "2377225624", Sum: entity.NewMoney(100, 0)}},
+			statusCode: http.StatusOK,
+			nextCursor: entity.EncodeCursor(4),
+		},
+		{
+			name:       "withdrawals limit above the maximum",
+			query:      "?limit=5000",
+			filter:     entity.OrderFilter{ListFilter: entity.ListFilter{Page: entity.Page{Limit: entity.MaxPageLimit}}},
+			orders:     fullPageOfWithdrawals(),
+			statusCode: http.StatusOK,
+			nextCursor: entity.EncodeCursor(1),
+		},
+		{
+			name:       "withdrawals reversed",
+			query:      "?status=REVERSED",
+			filter:     entity.OrderFilter{Statuses: []string{entity.WithdrawalReversed}},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "withdrawals order status",
+			query:      "?status=NEW",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "withdrawals wrong limit",
+			query:      "?limit=-1",
+			statusCode: http.StatusBadRequest,
+		},
 		{
 			name:       "withdrawals err Get withdrawals",
 			statusCode: http.StatusInternalServerError,
@@ -55,11 +90,11 @@ func TestHandler_Withdrawals(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Arrange
-			Withdrawals := usecase.On("Withdrawals", mock.Anything, mock.Anything).Return(tt.orders, tt.err.withdrawals).Maybe()
+			Withdrawals := usecase.On("Withdrawals", mock.Anything, mock.Anything, tt.filter).Return(tt.orders, tt.err.withdrawals).Maybe()
 			GetUserIDFromCtx := ctxInf.On("GetUserIDFromCtx", mock.Anything).Return(int64(0), tt.err.getUserFromCtx)
 
 			// Act
-			req, err := http.NewRequest(http.MethodGet, "/withdrawals", nil)
+			req, err := http.NewRequest(http.MethodGet, "/withdrawals"+tt.query, nil)
 			if err != tt.err.request {
 				t.Fatal(err)
 			}
@@ -69,6 +104,7 @@ func TestHandler_Withdrawals(t *testing.T) {
 
 			// Assert
 			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Equal(t, tt.nextCursor, w.Header().Get(nextCursorHeader))
 
 			// Unset
 			GetUserIDFromCtx.Unset()
@@ -76,3 +112,12 @@ func TestHandler_Withdrawals(t *testing.T) {
 		})
 	}
 }
+
+// a page of the maximum size, newest first
+func fullPageOfWithdrawals() []entity.BalanceUpdate {
+	withdrawals := make([]entity.BalanceUpdate, 0, entity.MaxPageLimit)
+	for id := entity.MaxPageLimit; id > 0; id-- {
+		withdrawals = append(withdrawals, entity.BalanceUpdate{ID: int64(id), Order: "2377225624", Sum: entity.NewMoney(1, 0)})
+	}
+	return withdrawals
+}
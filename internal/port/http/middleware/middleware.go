@@ -1,15 +1,30 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/korovindenis/go-market/internal/domain/entity"
 )
 
+// set on responses replayed for a repeated Idempotency-Key
+const idempotentReplayedHeader = "Idempotent-Replayed"
+
+// where the access token can be taken from
+const (
+	TokenSourceCookie = "cookie"
+	TokenSourceHeader = "header"
+)
+
 type config interface {
 	GetTokenName() string
+	GetTokenSources() []string
 }
 
 type auth interface {
@@ -17,21 +32,47 @@ type auth interface {
 	GetUserFromToken(tokenString string) (entity.User, error)
 }
 
+type sessions interface {
+	CheckSession(ctx context.Context, user entity.User) error
+}
+
+type idempotency interface {
+	ReserveIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) (*entity.IdempotencyKey, error)
+	CompleteIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error
+}
+
 type Middleware struct {
 	config
 	auth
+	sessions
+	idempotency
+	tokenSources []string
 }
 
-func New(config config, auth auth) (*Middleware, error) {
+func New(config config, auth auth, sessions sessions, idempotency idempotency) (*Middleware, error) {
+	// cookie first keeps the old behaviour for browsers
+	tokenSources := config.GetTokenSources()
+	if len(tokenSources) == 0 {
+		tokenSources = []string{TokenSourceCookie, TokenSourceHeader}
+	}
+	for _, source := range tokenSources {
+		if source != TokenSourceCookie && source != TokenSourceHeader {
+			return nil, fmt.Errorf("unknown token source: %s", source)
+		}
+	}
+
 	return &Middleware{
 		config,
 		auth,
+		sessions,
+		idempotency,
+		tokenSources,
 	}, nil
 }
 
 func (m *Middleware) CheckMethod() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if c.Request.Method != http.MethodPost && c.Request.Method != http.MethodGet {
+		if c.Request.Method != http.MethodPost && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodDelete {
 			c.AbortWithError(http.StatusMethodNotAllowed, entity.ErrMethodNotAllowed)
 			return
 		}
@@ -71,9 +112,9 @@ func (m *Middleware) CheckContentTypeText() gin.HandlerFunc {
 
 func (m *Middleware) CheckAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token, err := c.Cookie(m.config.GetTokenName())
+		token, err := m.getToken(c)
 		if err != nil {
-			c.Error(fmt.Errorf("%s %w", "Middleware CheckAuth get Cookie", err))
+			c.Error(fmt.Errorf("%s %w", "Middleware CheckAuth getToken", err))
 			c.AbortWithError(http.StatusUnauthorized, entity.ErrUserLoginUnauthorized)
 			return
 		}
@@ -89,15 +130,28 @@ func (m *Middleware) CheckAuth() gin.HandlerFunc {
 			return
 		}
 
+		// a valid token is not enough, its session must be alive and belong to the user
+		user, err := m.auth.GetUserFromToken(token)
+		if err != nil {
+			c.Error(fmt.Errorf("error: %s %w", "Middleware CheckAuth GetUserFromToken", err))
+			c.AbortWithError(http.StatusUnauthorized, entity.ErrUserLoginUnauthorized)
+			return
+		}
+		if err := m.sessions.CheckSession(c.Request.Context(), user); err != nil {
+			c.Error(fmt.Errorf("error: %s %w", "Middleware CheckSession", err))
+			c.AbortWithError(http.StatusUnauthorized, entity.ErrUserLoginUnauthorized)
+			return
+		}
+
 		c.Next()
 	}
 }
 
 func (m *Middleware) AddUserInfoToCtx() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token, err := c.Cookie(m.config.GetTokenName())
+		token, err := m.getToken(c)
 		if err != nil {
-			c.Error(fmt.Errorf("%s %w", "Middleware AddUserInfoToCtx get Cookie", err))
+			c.Error(fmt.Errorf("%s %w", "Middleware AddUserInfoToCtx getToken", err))
 			c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
 			return
 		}
@@ -110,7 +164,128 @@ func (m *Middleware) AddUserInfoToCtx() gin.HandlerFunc {
 		}
 
 		c.Set("userId", user.ID)
+		c.Set("sessionId", user.SessionID)
+		setAuditActor(c, user.ID)
+
+		c.Next()
+	}
+}
+
+// requests are audited as anonymous until AddUserInfoToCtx knows the user
+func (m *Middleware) AddAuditActorToCtx() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setAuditActor(c, 0)
+		c.Next()
+	}
+}
+func setAuditActor(c *gin.Context, userID int64) {
+	actor := entity.AuditActor{
+		UserID:    userID,
+		IP:        c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	}
+	c.Request = c.Request.WithContext(entity.WithAuditActor(c.Request.Context(), actor))
+}
+
+// Replays the status of a request repeated with the same Idempotency-Key.
+// Must run after AddUserInfoToCtx, keys are kept per user
+func (m *Middleware) Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader(entity.IdempotencyKeyHeader)
+		if value == "" {
+			c.Next()
+			return
+		}
+
+		// the body is part of the fingerprint, handlers read it again
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(fmt.Errorf("%s %w", "Middleware Idempotency ReadAll", err))
+			c.AbortWithError(http.StatusBadRequest, entity.ErrStatusBadRequest)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := entity.IdempotencyKey{
+			UserID:      c.GetInt64("userId"),
+			Key:         value,
+			Fingerprint: entity.RequestFingerprint(c.Request.Method, c.Request.URL.Path, body),
+		}
+		ctx := c.Request.Context()
+		stored, err := m.idempotency.ReserveIdempotencyKey(ctx, key)
+		if err != nil {
+			c.Error(fmt.Errorf("%s %w", "Middleware Idempotency ReserveIdempotencyKey", err))
 
+			switch {
+			case errors.Is(err, entity.ErrInvalidIdempotencyKey):
+				c.AbortWithError(http.StatusBadRequest, entity.ErrInvalidIdempotencyKey)
+			case errors.Is(err, entity.ErrIdempotencyKeyReused):
+				c.AbortWithError(http.StatusUnprocessableEntity, entity.ErrIdempotencyKeyReused)
+			case errors.Is(err, entity.ErrIdempotencyKeyInProgress):
+				c.AbortWithError(http.StatusConflict, entity.ErrIdempotencyKeyInProgress)
+			default:
+				c.AbortWithError(http.StatusInternalServerError, entity.ErrInternalServerError)
+			}
+			return
+		}
+		if stored != nil {
+			c.Header(idempotentReplayedHeader, "true")
+			c.AbortWithStatus(stored.StatusCode)
+			return
+		}
+
+		// the storage completes the key with a successful change
+		c.Request = c.Request.WithContext(entity.WithIdempotencyKey(ctx, key))
 		c.Next()
+
+		key.StatusCode = c.Writer.Status()
+		if err := m.idempotency.CompleteIdempotencyKey(ctx, key); err != nil {
+			c.Error(fmt.Errorf("%s %w", "Middleware Idempotency CompleteIdempotencyKey", err))
+		}
 	}
 }
+
+// must run after CheckAuth, the role comes from the access token
+func (m *Middleware) RequireRole(role entity.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := m.getToken(c)
+		if err != nil {
+			c.Error(fmt.Errorf("%s %w", "Middleware RequireRole getToken", err))
+			c.AbortWithError(http.StatusUnauthorized, entity.ErrUserLoginUnauthorized)
+			return
+		}
+
+		user, err := m.auth.GetUserFromToken(token)
+		if err != nil {
+			c.Error(fmt.Errorf("error: %s %w", "Middleware RequireRole GetUserFromToken", err))
+			c.AbortWithError(http.StatusUnauthorized, entity.ErrUserLoginUnauthorized)
+			return
+		}
+		if user.Role != role {
+			c.Error(fmt.Errorf("%s %s", "Middleware RequireRole role", user.Role))
+			c.AbortWithError(http.StatusForbidden, entity.ErrForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// access token from the first configured source that has one
+func (m *Middleware) getToken(c *gin.Context) (string, error) {
+	for _, source := range m.tokenSources {
+		switch source {
+		case TokenSourceCookie:
+			if token, err := c.Cookie(m.config.GetTokenName()); err == nil && token != "" {
+				return token, nil
+			}
+		case TokenSourceHeader:
+			header := c.GetHeader("Authorization")
+			if token, found := strings.CutPrefix(header, entity.TokenTypeBearer+" "); found && token != "" {
+				return token, nil
+			}
+		}
+	}
+
+	return "", entity.ErrUserLoginUnauthorized
+}
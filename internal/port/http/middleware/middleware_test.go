@@ -1,8 +1,11 @@
 package middleware
 
 import (
+	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -10,20 +13,63 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-type mockConfig struct{}
+type mockConfig struct {
+	tokenSources []string
+}
 
 func (c *mockConfig) GetTokenName() string {
 	return "token"
 }
 
-type mockAuth struct{}
+func (c *mockConfig) GetTokenSources() []string {
+	return c.tokenSources
+}
+
+type mockAuth struct {
+	role entity.Role
+}
 
 func (a *mockAuth) CheckToken(user entity.User, tokenString string) error {
 	return nil
 }
 
 func (a *mockAuth) GetUserFromToken(tokenString string) (entity.User, error) {
-	return entity.User{}, nil
+	return entity.User{Role: a.role}, nil
+}
+
+type mockSessions struct {
+	err error
+}
+
+func (s *mockSessions) CheckSession(ctx context.Context, user entity.User) error {
+	return s.err
+}
+
+type mockIdempotency struct {
+	keys map[string]entity.IdempotencyKey
+}
+
+func (m *mockIdempotency) ReserveIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) (*entity.IdempotencyKey, error) {
+	stored, ok := m.keys[key.Key]
+	if !ok {
+		m.keys[key.Key] = key
+		return nil, nil
+	}
+	if stored.Fingerprint != key.Fingerprint {
+		return nil, entity.ErrIdempotencyKeyReused
+	}
+
+	return &stored, nil
+}
+
+// like the usecase, a retryable answer releases the key
+func (m *mockIdempotency) CompleteIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	if key.Retryable() {
+		delete(m.keys, key.Key)
+		return nil
+	}
+	m.keys[key.Key] = key
+	return nil
 }
 
 func setupGinTest() *gin.Context {
@@ -40,7 +86,7 @@ func TestCheckMethod(t *testing.T) {
 	ctx := setupGinTest()
 	ctx.Request.Method = http.MethodPost
 
-	middleware, _ := New(&mockConfig{}, &mockAuth{})
+	middleware, _ := New(&mockConfig{}, &mockAuth{}, &mockSessions{}, &mockIdempotency{})
 	handler := middleware.CheckMethod()
 
 	handler(ctx)
@@ -51,7 +97,7 @@ func TestCheckMethod(t *testing.T) {
 func TestCheckContentTypeJSON(t *testing.T) {
 	ctx := setupGinTest()
 
-	middleware, _ := New(&mockConfig{}, &mockAuth{})
+	middleware, _ := New(&mockConfig{}, &mockAuth{}, &mockSessions{}, &mockIdempotency{})
 	handler := middleware.CheckContentTypeJSON()
 
 	handler(ctx)
@@ -63,7 +109,7 @@ func TestCheckContentTypeText(t *testing.T) {
 	ctx := setupGinTest()
 	ctx.Request.Header.Set("Content-Type", "text/plain")
 
-	middleware, _ := New(&mockConfig{}, &mockAuth{})
+	middleware, _ := New(&mockConfig{}, &mockAuth{}, &mockSessions{}, &mockIdempotency{})
 	handler := middleware.CheckContentTypeText()
 
 	handler(ctx)
@@ -75,7 +121,7 @@ func TestCheckAuth(t *testing.T) {
 	ctx := setupGinTest()
 	ctx.Request.AddCookie(&http.Cookie{Name: "token", Value: "testToken"})
 
-	middleware, _ := New(&mockConfig{}, &mockAuth{})
+	middleware, _ := New(&mockConfig{}, &mockAuth{}, &mockSessions{}, &mockIdempotency{})
 	handler := middleware.CheckAuth()
 
 	handler(ctx)
@@ -83,11 +129,23 @@ func TestCheckAuth(t *testing.T) {
 	assert.Equal(t, http.StatusOK, ctx.Writer.Status())
 }
 
+func TestCheckAuthRevokedSession(t *testing.T) {
+	ctx := setupGinTest()
+	ctx.Request.AddCookie(&http.Cookie{Name: "token", Value: "testToken"})
+
+	middleware, _ := New(&mockConfig{}, &mockAuth{}, &mockSessions{err: entity.ErrSessionRevoked}, &mockIdempotency{})
+	handler := middleware.CheckAuth()
+
+	handler(ctx)
+
+	assert.Equal(t, http.StatusUnauthorized, ctx.Writer.Status())
+}
+
 func TestAddUserInfoToCtx(t *testing.T) {
 	ctx := setupGinTest()
 	ctx.Request.AddCookie(&http.Cookie{Name: "token", Value: "testToken"})
 
-	middleware, _ := New(&mockConfig{}, &mockAuth{})
+	middleware, _ := New(&mockConfig{}, &mockAuth{}, &mockSessions{}, &mockIdempotency{})
 	handler := middleware.AddUserInfoToCtx()
 
 	handler(ctx)
@@ -95,3 +153,238 @@ func TestAddUserInfoToCtx(t *testing.T) {
 	userID, _ := ctx.Get("userId")
 	assert.NotNil(t, userID)
 }
+
+func TestAddAuditActorToCtx(t *testing.T) {
+	ctx := setupGinTest()
+	ctx.Request.Header.Set("User-Agent", "curl")
+
+	middleware, _ := New(&mockConfig{}, &mockAuth{}, &mockSessions{}, &mockIdempotency{})
+	handler := middleware.AddAuditActorToCtx()
+
+	handler(ctx)
+
+	actor := entity.AuditActorFromContext(ctx.Request.Context())
+	assert.Equal(t, "curl", actor.UserAgent)
+	assert.Zero(t, actor.UserID)
+}
+
+func TestIdempotency(t *testing.T) {
+	middleware, _ := New(&mockConfig{}, &mockAuth{}, &mockSessions{}, &mockIdempotency{keys: make(map[string]entity.IdempotencyKey)})
+	router := gin.New()
+	calls := 0
+	router.POST("/withdraw", func(c *gin.Context) { c.Set("userId", int64(7)) }, middleware.Idempotency(), func(c *gin.Context) {
+		calls++
+		body, _ := io.ReadAll(c.Request.Body)
+		assert.NotEmpty(t, body, "the body is kept for the handler")
+		_, withKey := entity.IdempotencyKeyFromContext(c.Request.Context())
+		assert.Equal(t, c.GetHeader(entity.IdempotencyKeyHeader) != "", withKey, "the storage completes the key")
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		key        string
+		body       string
+		statusCode int
+		calls      int
+		replayed   string
+	}{
+		{
+			name:       "first request",
+			key:        "key1",
+			body:       `{"order":"2377225624","sum":751}`,
+			statusCode: http.StatusOK,
+			calls:      1,
+		},
+		{
+			name:       "repeated request",
+			key:        "key1",
+			body:       `{"order":"2377225624","sum":751}`,
+			statusCode: http.StatusOK,
+			calls:      1,
+			replayed:   "true",
+		},
+		{
+			name:       "reused key",
+			key:        "key1",
+			body:       `{"order":"2377225625","sum":751}`,
+			statusCode: http.StatusUnprocessableEntity,
+			calls:      1,
+		},
+		{
+			name:       "without key",
+			body:       `{"order":"2377225624","sum":751}`,
+			statusCode: http.StatusOK,
+			calls:      2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/withdraw", strings.NewReader(tt.body))
+			if tt.key != "" {
+				req.Header.Set(entity.IdempotencyKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Equal(t, tt.calls, calls)
+			assert.Equal(t, tt.replayed, w.Header().Get(idempotentReplayedHeader))
+		})
+	}
+}
+
+func TestIdempotencyRetryAfterTOTPChallenge(t *testing.T) {
+	middleware, _ := New(&mockConfig{}, &mockAuth{}, &mockSessions{}, &mockIdempotency{keys: make(map[string]entity.IdempotencyKey)})
+	router := gin.New()
+	calls := 0
+	router.POST("/withdraw", func(c *gin.Context) { c.Set("userId", int64(7)) }, middleware.Idempotency(), func(c *gin.Context) {
+		calls++
+		var withdrawal entity.BalanceUpdate
+		if err := c.ShouldBindJSON(&withdrawal); err != nil || withdrawal.TOTPCode == "" {
+			c.AbortWithError(http.StatusForbidden, entity.ErrTOTPRequired)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		statusCode int
+		calls      int
+		replayed   string
+	}{
+		{
+			name:       "totp code required",
+			body:       `{"order":"2377225624","sum":1500}`,
+			statusCode: http.StatusForbidden,
+			calls:      1,
+		},
+		{
+			name:       "retry with totp code",
+			body:       `{"order":"2377225624","sum":1500,"totp_code":"123456"}`,
+			statusCode: http.StatusOK,
+			calls:      2,
+		},
+		{
+			name:       "repeated request",
+			body:       `{"order":"2377225624","sum":1500,"totp_code":"123456"}`,
+			statusCode: http.StatusOK,
+			calls:      2,
+			replayed:   "true",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/withdraw", strings.NewReader(tt.body))
+			req.Header.Set(entity.IdempotencyKeyHeader, "key1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Equal(t, tt.calls, calls)
+			assert.Equal(t, tt.replayed, w.Header().Get(idempotentReplayedHeader))
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		role       entity.Role
+		statusCode int
+	}{
+		{
+			name:       "admin",
+			role:       entity.RoleAdmin,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "user",
+			role:       entity.RoleUser,
+			statusCode: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := setupGinTest()
+			ctx.Request.AddCookie(&http.Cookie{Name: "token", Value: "testToken"})
+
+			middleware, _ := New(&mockConfig{}, &mockAuth{role: tt.role}, &mockSessions{}, &mockIdempotency{})
+			handler := middleware.RequireRole(entity.RoleAdmin)
+
+			handler(ctx)
+
+			assert.Equal(t, tt.statusCode, ctx.Writer.Status())
+		})
+	}
+}
+
+func TestGetToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []string
+		cookie  string
+		header  string
+		want    string
+		err     error
+	}{
+		{
+			name:   "cookie by default",
+			cookie: "cookieToken",
+			header: "Bearer headerToken",
+			want:   "cookieToken",
+		},
+		{
+			name:    "header first",
+			sources: []string{TokenSourceHeader, TokenSourceCookie},
+			cookie:  "cookieToken",
+			header:  "Bearer headerToken",
+			want:    "headerToken",
+		},
+		{
+			name:   "fallback to header",
+			header: "Bearer headerToken",
+			want:   "headerToken",
+		},
+		{
+			name:    "header disabled",
+			sources: []string{TokenSourceCookie},
+			header:  "Bearer headerToken",
+			err:     entity.ErrUserLoginUnauthorized,
+		},
+		{
+			name:   "not a bearer token",
+			header: "Basic dXNlcjpyb290",
+			err:    entity.ErrUserLoginUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ctx := setupGinTest()
+			if tt.cookie != "" {
+				ctx.Request.AddCookie(&http.Cookie{Name: "token", Value: tt.cookie})
+			}
+			if tt.header != "" {
+				ctx.Request.Header.Set("Authorization", tt.header)
+			}
+			middleware, err := New(&mockConfig{tokenSources: tt.sources}, &mockAuth{}, &mockSessions{}, &mockIdempotency{})
+			assert.NoError(t, err)
+
+			// Act
+			token, err := middleware.getToken(ctx)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, token)
+		})
+	}
+}
+
+func TestNewUnknownTokenSource(t *testing.T) {
+	_, err := New(&mockConfig{tokenSources: []string{"query"}}, &mockAuth{}, &mockSessions{}, &mockIdempotency{})
+
+	assert.Error(t, err)
+}
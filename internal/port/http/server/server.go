@@ -3,6 +3,7 @@ package server
 
 import (
 	"context"
+	"expvar"
 	"log"
 	"net/http"
 	"os"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
+	"github.com/korovindenis/go-market/internal/domain/entity"
 
 	"github.com/gin-contrib/pprof"
 )
@@ -19,6 +21,18 @@ import (
 type handler interface {
 	Register(c *gin.Context)
 	Login(c *gin.Context)
+	RefreshToken(c *gin.Context)
+	Logout(c *gin.Context)
+	ChangePassword(c *gin.Context)
+	RequestPasswordReset(c *gin.Context)
+	ResetPassword(c *gin.Context)
+	EnrollTwoFactor(c *gin.Context)
+	ConfirmTwoFactor(c *gin.Context)
+	LoginTwoFactor(c *gin.Context)
+	JWKS(c *gin.Context)
+
+	GetSessions(c *gin.Context)
+	DeleteSession(c *gin.Context)
 
 	GetAllOrders(c *gin.Context)
 	SetOrder(c *gin.Context)
@@ -27,6 +41,18 @@ type handler interface {
 	WithdrawBalance(c *gin.Context)
 
 	Withdrawals(c *gin.Context)
+
+	GetTransactions(c *gin.Context)
+
+	AdminGetUser(c *gin.Context)
+	AdminAdjustBalance(c *gin.Context)
+	AdminBlockUser(c *gin.Context)
+	AdminUnblockUser(c *gin.Context)
+	AdminGetOrder(c *gin.Context)
+	AdminRequeueOrder(c *gin.Context)
+	AdminReverseWithdrawal(c *gin.Context)
+	AdminGetAuditEvents(c *gin.Context)
+	AdminVerifyAuditLog(c *gin.Context)
 }
 
 // middleware for http server
@@ -38,6 +64,9 @@ type middleware interface {
 
 	CheckAuth() gin.HandlerFunc
 	AddUserInfoToCtx() gin.HandlerFunc
+	AddAuditActorToCtx() gin.HandlerFunc
+	Idempotency() gin.HandlerFunc
+	RequireRole(role entity.Role) gin.HandlerFunc
 }
 
 // configuration
@@ -60,6 +89,10 @@ func Run(ctx context.Context, config config, handler handler, middleware middlew
 	router.Use(gin.Recovery())
 	router.Use(gzip.Gzip(gzip.DefaultCompression))
 	router.Use(middleware.CheckMethod())
+	router.Use(middleware.AddAuditActorToCtx())
+
+	// keys to verify our tokens
+	router.GET("/.well-known/jwks.json", handler.JWKS)
 
 	// api
 	user := router.Group("/api/user")
@@ -67,17 +100,49 @@ func Run(ctx context.Context, config config, handler handler, middleware middlew
 		// routes with auth
 		mainPath := user.Group("/", middleware.CheckContentTypeText(), middleware.CheckAuth(), middleware.AddUserInfoToCtx())
 		mainPath.GET("orders", handler.GetAllOrders)
-		mainPath.POST("orders", handler.SetOrder)
+		mainPath.POST("orders", middleware.Idempotency(), handler.SetOrder)
 		mainPath.GET("withdrawals", handler.Withdrawals)
+		mainPath.GET("transactions", handler.GetTransactions)
 
 		balancePath := user.Group("/balance", middleware.CheckAuth(), middleware.AddUserInfoToCtx())
 		balancePath.GET("/", handler.GetBalance)
-		balancePath.POST("withdraw", handler.WithdrawBalance)
+		balancePath.POST("withdraw", middleware.Idempotency(), handler.WithdrawBalance)
+
+		sessionPath := user.Group("/", middleware.CheckAuth(), middleware.AddUserInfoToCtx())
+		sessionPath.POST("logout", handler.Logout)
+		sessionPath.GET("sessions", handler.GetSessions)
+		sessionPath.DELETE("sessions/:id", handler.DeleteSession)
+
+		passwordPath := user.Group("/", middleware.CheckContentTypeJSON(), middleware.CheckAuth(), middleware.AddUserInfoToCtx())
+		passwordPath.POST("password", handler.ChangePassword)
+
+		twoFactorPath := user.Group("/2fa", middleware.CheckAuth(), middleware.AddUserInfoToCtx())
+		twoFactorPath.POST("enroll", handler.EnrollTwoFactor)
+		twoFactorPath.POST("confirm", middleware.CheckContentTypeJSON(), handler.ConfirmTwoFactor)
 
 		// routes without auth
+		user.POST("token/refresh", handler.RefreshToken)
 		nonAuth := user.Group("/", middleware.CheckContentTypeJSON())
 		nonAuth.POST("register", handler.Register)
 		nonAuth.POST("login", handler.Login)
+		nonAuth.POST("login/2fa", handler.LoginTwoFactor)
+		nonAuth.POST("password/reset-request", handler.RequestPasswordReset)
+		nonAuth.POST("password/reset", handler.ResetPassword)
+	}
+
+	// support tools, only for admins
+	admin := router.Group("/api/admin", middleware.CheckAuth(), middleware.AddUserInfoToCtx(), middleware.RequireRole(entity.RoleAdmin))
+	{
+		admin.GET("users/:login", handler.AdminGetUser)
+		admin.POST("users/:login/balance/adjust", middleware.CheckContentTypeJSON(), handler.AdminAdjustBalance)
+		admin.POST("users/:login/block", handler.AdminBlockUser)
+		admin.POST("users/:login/unblock", handler.AdminUnblockUser)
+		admin.GET("orders/:number", handler.AdminGetOrder)
+		admin.POST("orders/:number/requeue", handler.AdminRequeueOrder)
+		admin.POST("withdrawals/:order/reverse", handler.AdminReverseWithdrawal)
+		admin.GET("audit", handler.AdminGetAuditEvents)
+		admin.GET("audit/verify", handler.AdminVerifyAuditLog)
+		admin.GET("debug/vars", gin.WrapH(expvar.Handler()))
 	}
 
 	// add pprof